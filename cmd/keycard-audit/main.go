@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/status-im/status-keycard-go/pkg/audit"
+)
+
+var (
+	verify  = flag.String("verify", "", "audit log file to verify, recorded with `auditFilePath`")
+	keyFile = flag.String("key-file", "", "file holding the hex-encoded `auditKey` the log was recorded with")
+)
+
+func main() {
+	flag.Parse()
+
+	if *verify == "" || *keyFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	key, err := readKey(*keyFile)
+	if err != nil {
+		fmt.Printf("failed to read the audit key: %v\n", err)
+		os.Exit(2)
+	}
+
+	count, err := audit.VerifyFile(*verify, key)
+	if err != nil {
+		fmt.Printf("audit log is not valid after %d entries: %v\n", count, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("audit log is valid: %d entries\n", count)
+}
+
+func readKey(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+}
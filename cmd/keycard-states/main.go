@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/status-im/status-keycard-go/internal"
+)
+
+var (
+	output = flag.String("o", "", "path of the Graphviz file to write, stdout when empty")
+)
+
+// Prints the session state machine as a Graphviz digraph, e.g.:
+//
+//	go run ./cmd/keycard-states | dot -Tsvg > states.svg
+func main() {
+	flag.Parse()
+
+	dot := internal.TransitionsDot()
+
+	if *output == "" {
+		fmt.Print(dot)
+		return
+	}
+
+	err := os.WriteFile(*output, []byte(dot), 0644)
+	if err != nil {
+		fmt.Printf("failed to write states: %v\n", err)
+		os.Exit(1)
+	}
+}
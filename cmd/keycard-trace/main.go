@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/status-im/status-keycard-go/pkg/trace"
+)
+
+var (
+	input  = flag.String("input", "", "APDU trace file, recorded with `traceFilePath`")
+	pcapng = flag.String("pcapng", "", "path of the pcapng file to write")
+)
+
+func main() {
+	flag.Parse()
+
+	if *input == "" || *pcapng == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := export(*input, *pcapng)
+	if err != nil {
+		fmt.Printf("failed to export trace: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func export(inputPath, outputPath string) error {
+	entries, err := trace.Load(inputPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+
+	err = trace.WritePcapNG(file, entries)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
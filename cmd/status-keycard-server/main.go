@@ -1,21 +1,35 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/status-im/status-keycard-go/cmd/status-keycard-server/server"
 	"github.com/status-im/status-keycard-go/internal/logging"
+	"github.com/status-im/status-keycard-go/pkg/session"
 )
 
 var (
-	address    = flag.String("address", "127.0.0.1:0", "host:port to listen")
-	rootLogger = zap.NewNop()
+	address        = flag.String("address", "127.0.0.1:0", "address to listen: host:port, unix:/path/to/socket, or systemd for socket activation")
+	stopTimeout    = flag.Duration("shutdown-timeout", 10*time.Second, "time to let a card command in progress finish on shutdown")
+	socketMode     = flag.String("socket-mode", "0600", "file permissions of the unix socket, octal")
+	noAuth         = flag.Bool("no-auth", false, "disable authentication, anyone who can reach the server can use the keycard")
+	tokenFile      = flag.String("token-file", "", "file with access tokens, one per line, optionally followed by scope (read|full). When empty, a full-control token is generated and printed")
+	allowedOrigins = flag.String("allowed-origins", "", "comma-separated list of origins allowed to connect from browsers")
+	tlsCert        = flag.String("tls-cert", "", "TLS certificate file, enables TLS")
+	tlsKey         = flag.String("tls-key", "", "TLS key file")
+	tlsClientCA    = flag.String("tls-client-ca", "", "CA file to verify TLS client certificates, enables mutual TLS authentication")
+	rootLogger     = zap.NewNop()
 )
 
 func init() {
@@ -30,28 +44,99 @@ func main() {
 	logger := rootLogger.Named("main")
 
 	flag.Parse()
-	go handleInterrupts()
 
-	srv := server.NewServer(rootLogger)
-	srv.Setup()
+	options, err := serverOptions()
+	if err != nil {
+		logger.Error("invalid configuration", zap.Error(err))
+		return
+	}
 
-	err := srv.Listen(*address)
+	srv := server.NewServer(rootLogger, options...)
+
+	err = srv.Listen(*address)
 	if err != nil {
 		logger.Error("failed to start server", zap.Error(err))
 		return
 	}
 
 	logger.Info("keycard-server started", zap.String("address", srv.Address()))
-	srv.Serve()
+
+	served := make(chan struct{})
+	go func() {
+		srv.Serve()
+		close(served)
+	}()
+
+	select {
+	case <-served:
+		return
+	case <-interrupted():
+	}
+
+	logger.Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), *stopTimeout)
+	defer cancel()
+
+	srv.Stop(ctx)
+	<-served
+	logger.Info("keycard-server stopped")
+}
+
+func serverOptions() ([]server.Option, error) {
+	var options []server.Option
+
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil {
+		return nil, errors.New("invalid -socket-mode, expected octal permissions, e.g. 0660")
+	}
+	options = append(options, server.WithUnixSocketMode(os.FileMode(mode)))
+
+	if *allowedOrigins != "" {
+		options = append(options, server.WithAllowedOrigins(strings.Split(*allowedOrigins, ",")))
+	}
+
+	if *tlsCert != "" || *tlsKey != "" {
+		if *tlsCert == "" || *tlsKey == "" {
+			return nil, errors.New("both -tls-cert and -tls-key must be set")
+		}
+		options = append(options, server.WithTLS(*tlsCert, *tlsKey, *tlsClientCA))
+	} else if *tlsClientCA != "" {
+		return nil, errors.New("-tls-client-ca requires -tls-cert and -tls-key")
+	}
+
+	if *noAuth {
+		return options, nil
+	}
+
+	authenticator := server.NewAuthenticator()
+	if *tokenFile != "" {
+		err := authenticator.LoadTokens(*tokenFile)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		token, err := server.GenerateToken()
+		if err != nil {
+			return nil, err
+		}
+		authenticator.AddToken(token, session.ScopeFull)
+		// Printed to stdout only, to keep it out of the logs
+		fmt.Printf("access token: %s\n", token)
+	}
+
+	return append(options, server.WithAuthenticator(authenticator)), nil
 }
 
-// handleInterrupts catches interrupt signal (SIGTERM/SIGINT) and
-// gracefully logouts and stops the node.
-func handleInterrupts() {
+// interrupted returns a channel closed on interrupt signal (SIGTERM/SIGINT)
+func interrupted() <-chan struct{} {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(ch)
 
-	<-ch
-	os.Exit(0)
+	done := make(chan struct{})
+	go func() {
+		<-ch
+		signal.Stop(ch)
+		close(done)
+	}()
+	return done
 }
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+)
+
+const tokenLength = 32
+
+// Authenticator verifies bearer tokens and client certificates, and resolves the client scope
+type Authenticator struct {
+	tokens [][]byte
+	scopes []session.Scope
+
+	// clientCertScope is granted to clients presenting a certificate verified against the TLS client CA
+	clientCertScope session.Scope
+}
+
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{
+		clientCertScope: session.ScopeFull,
+	}
+}
+
+// AddToken allows the given bearer token with the given scope
+func (a *Authenticator) AddToken(token string, scope session.Scope) {
+	a.tokens = append(a.tokens, []byte(token))
+	a.scopes = append(a.scopes, scope)
+}
+
+// SetClientCertScope sets the scope granted to clients authenticated with a TLS client certificate
+func (a *Authenticator) SetClientCertScope(scope session.Scope) {
+	a.clientCertScope = scope
+}
+
+// GenerateToken returns a new random token
+func GenerateToken() (string, error) {
+	token := make([]byte, tokenLength)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// LoadTokens reads tokens from a file. Each line is a token, optionally followed by its scope.
+// Tokens without scope get the full scope. Empty lines and lines starting with `#` are ignored.
+func (a *Authenticator) LoadTokens(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to open tokens file")
+	}
+	defer file.Close()
+
+	lineNumber := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return errors.Errorf("invalid tokens file line %d", lineNumber)
+		}
+
+		scope := session.ScopeFull
+		if len(fields) == 2 {
+			var ok bool
+			scope, ok = session.ParseScope(fields[1])
+			if !ok {
+				return errors.Errorf("unknown scope '%s' at tokens file line %d", fields[1], lineNumber)
+			}
+		}
+
+		a.AddToken(fields[0], scope)
+	}
+
+	return scanner.Err()
+}
+
+// authenticate returns the scope of the client, or false if the client is not authenticated.
+// The token is taken from the `Authorization: Bearer` header. As browsers can't set headers
+// on websocket connections, it's also accepted in the `access_token` query parameter of websocket upgrades.
+func (a *Authenticator) authenticate(r *http.Request) (session.Scope, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return a.clientCertScope, true
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		return "", false
+	}
+
+	// Check all tokens, so that the time doesn't reveal which one matched
+	matched := -1
+	for i, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t, []byte(token)) == 1 {
+			matched = i
+		}
+	}
+
+	if matched < 0 {
+		return "", false
+	}
+	return a.scopes[matched], true
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	// Query parameters end up in logs and browser history, so they are only accepted where headers can't be set
+	if !websocket.IsWebSocketUpgrade(r) {
+		return ""
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// checkOrigin allows requests without `Origin` header (non-browser clients),
+// from the explicitly allowed origins, and from loopback origins.
+// The `Host` header is not trusted: a DNS rebinding page would pass a same-host check.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range s.allowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+
+	return isLoopbackOrigin(origin)
+}
+
+// isLoopbackOrigin returns true for pages served from this machine by IP or as `localhost`, on any port
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authenticated wraps the handler with origin and authentication checks.
+// The client scope is passed to the handler in the request context.
+func (s *Server) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if s.authenticator == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope, ok := s.authenticator.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="keycard"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(session.WithScope(r.Context(), scope)))
+	})
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+)
+
+const (
+	testFullToken = "full-token"
+	testReadToken = "read-token"
+)
+
+func TestLoadTokens(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		scopes  map[string]session.Scope
+		err     bool
+	}{
+		{
+			name:    "tokens with and without scope",
+			content: "# token scope\n\nfirst\nsecond read\n  third   full  \n",
+			scopes: map[string]session.Scope{
+				"first":  session.ScopeFull,
+				"second": session.ScopeRead,
+				"third":  session.ScopeFull,
+			},
+		},
+		{
+			name:    "unknown scope",
+			content: "token admin\n",
+			err:     true,
+		},
+		{
+			name:    "too many fields",
+			content: "token read full\n",
+			err:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "tokens")
+			if err := os.WriteFile(filePath, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			a := NewAuthenticator()
+			err := a.LoadTokens(filePath)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(a.tokens) != len(tc.scopes) {
+				t.Fatalf("expected %d tokens, got %d", len(tc.scopes), len(a.tokens))
+			}
+			for token, expected := range tc.scopes {
+				r, _ := http.NewRequest(http.MethodPost, "/rpc", nil)
+				r.Header.Set("Authorization", "Bearer "+token)
+				scope, ok := a.authenticate(r)
+				if !ok || scope != expected {
+					t.Fatalf("token %s: expected scope %s, got %s (%v)", token, expected, scope, ok)
+				}
+			}
+		})
+	}
+
+	err := NewAuthenticator().LoadTokens(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	s := NewServer(zap.NewNop(), WithAllowedOrigins([]string{"https://admin.example.com"}))
+
+	testCases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"https://admin.example.com", true},
+		{"HTTPS://ADMIN.EXAMPLE.COM", true},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1", true},
+		{"https://127.1.2.3:8443", true},
+		{"http://[::1]:8080", true},
+		{"https://admin.example.com:8443", false},
+		{"https://evil.example.com", false},
+		{"http://localhost.evil.example.com", false},
+		// The same host as the request is not enough, see checkOrigin
+		{"http://keycard.example.com", false},
+		{"file://localhost", false},
+		{"null", false},
+	}
+
+	for _, tc := range testCases {
+		r, _ := http.NewRequest(http.MethodGet, "http://keycard.example.com/signals", nil)
+		if tc.origin != "" {
+			r.Header.Set("Origin", tc.origin)
+		}
+		if allowed := s.checkOrigin(r); allowed != tc.allowed {
+			t.Errorf("origin %q: expected allowed %v, got %v", tc.origin, tc.allowed, allowed)
+		}
+	}
+}
+
+// postRPC calls the method over HTTP, it returns the HTTP status code and the JSON-RPC error if any
+func postRPC(t *testing.T, s *Server, query string, header http.Header, method string) (int, *session.Error) {
+	t.Helper()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"keycard.` + method + `","params":[{}]}`
+	r, err := http.NewRequest(http.MethodPost, "http://"+s.Address()+"/rpc"+query, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header = header
+	r.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return response.StatusCode, nil
+	}
+
+	var message testMessage
+	if err = json.NewDecoder(response.Body).Decode(&message); err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, message.Error
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": []string{"Bearer " + token}}
+}
+
+func TestAuthentication(t *testing.T) {
+	authenticator := NewAuthenticator()
+	authenticator.AddToken(testFullToken, session.ScopeFull)
+	authenticator.AddToken(testReadToken, session.ScopeRead)
+	s := startTestServer(t, WithAuthenticator(authenticator))
+
+	forbidden := func(rpcErr *session.Error) bool {
+		return rpcErr != nil && rpcErr.Code == session.ErrorCodeForbidden
+	}
+
+	testCases := []struct {
+		name      string
+		query     string
+		header    http.Header
+		method    string
+		status    int
+		forbidden bool
+	}{
+		{name: "no token", header: http.Header{}, method: "GetStatus", status: http.StatusUnauthorized},
+		{name: "wrong token", header: bearer("wrong"), method: "GetStatus", status: http.StatusUnauthorized},
+		{name: "token in query", query: "?access_token=" + testFullToken, header: http.Header{}, method: "GetStatus", status: http.StatusUnauthorized},
+		{
+			name:   "foreign origin",
+			header: http.Header{"Authorization": []string{"Bearer " + testFullToken}, "Origin": []string{"https://evil.example.com"}},
+			method: "GetStatus",
+			status: http.StatusForbidden,
+		},
+		{name: "read scope, read method", header: bearer(testReadToken), method: "GetStatus", status: http.StatusOK},
+		{name: "read scope, full method", header: bearer(testReadToken), method: "FactoryReset", status: http.StatusOK, forbidden: true},
+		{name: "full scope", header: bearer(testFullToken), method: "FactoryReset", status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, rpcErr := postRPC(t, s, tc.query, tc.header, tc.method)
+			if status != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, status)
+			}
+			if forbidden(rpcErr) != tc.forbidden {
+				t.Fatalf("expected forbidden %v, got %+v", tc.forbidden, rpcErr)
+			}
+		})
+	}
+}
+
+func TestWebSocketAuthentication(t *testing.T) {
+	authenticator := NewAuthenticator()
+	authenticator.AddToken(testReadToken, session.ScopeRead)
+	s := startTestServer(t, WithAuthenticator(authenticator))
+	url := "ws://" + s.Address() + "/signals"
+
+	// Browsers can't set headers on websockets, the token is accepted in the query
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?access_token="+testReadToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The scope of the token applies to requests sent over the websocket
+	sendRequest(t, conn, 1, "FactoryReset", struct{}{})
+	response := waitResponse(t, conn, 1)
+	if response.Error == nil || response.Error.Code != session.ErrorCodeForbidden {
+		t.Fatalf("expected the forbidden error, got %+v", response.Error)
+	}
+
+	_, rejected, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"https://evil.example.com"}})
+	if err == nil {
+		t.Fatal("expected a foreign origin to be rejected")
+	}
+	if rejected == nil || rejected.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %+v", http.StatusForbidden, rejected)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	unixAddressPrefix     = "unix:"
+	systemdAddress        = "systemd"
+	defaultUnixSocketMode = os.FileMode(0600)
+
+	// systemdListenFDsStart is the first file descriptor passed by systemd, see sd_listen_fds(3)
+	systemdListenFDsStart = 3
+)
+
+func (s *Server) listen(address string) (net.Listener, error) {
+	switch {
+	case address == systemdAddress:
+		return systemdListener(systemdListenFDsStart)
+	case strings.HasPrefix(address, unixAddressPrefix):
+		return s.unixListener(strings.TrimPrefix(address, unixAddressPrefix))
+	}
+
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid address")
+	}
+
+	return net.Listen("tcp", address)
+}
+
+func (s *Server) unixListener(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("empty unix socket path")
+	}
+
+	// Remove a stale socket left by a previous run, but never any other kind of file
+	info, err := os.Lstat(path)
+	if err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Errorf("'%s' exists and is not a socket", path)
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to remove stale socket")
+		}
+	}
+
+	// The socket is reachable as soon as it's created, so it's created with the final permissions,
+	// the chmod only adds the bits masked by the umask, if any
+	var listener net.Listener
+	withUmask(0777&^s.unixSocketMode, func() {
+		listener, err = net.Listen("unix", path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(path, s.unixSocketMode)
+	if err != nil {
+		_ = listener.Close()
+		return nil, errors.Wrap(err, "failed to set socket permissions")
+	}
+
+	return listener, nil
+}
+
+// systemdListener returns the socket passed with systemd socket activation, starting at file descriptor firstFD.
+// Exactly one socket is expected, see sd_listen_fds(3).
+func systemdListener(firstFD int) (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+	if count > 1 {
+		return nil, errors.Errorf("expected 1 socket from systemd, got %d", count)
+	}
+
+	// Don't pass the sockets to child processes
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(uintptr(firstFD), "systemd-socket")
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to use socket passed by systemd")
+	}
+
+	return listener, nil
+}
+
+func listenerAddress(listener net.Listener) string {
+	address := listener.Addr()
+	if address.Network() == "unix" {
+		return unixAddressPrefix + address.String()
+	}
+	return address.String()
+}
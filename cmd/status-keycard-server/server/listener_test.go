@@ -0,0 +1,195 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestUnixListener(t *testing.T) {
+	testCases := []struct {
+		name string
+		mode os.FileMode
+	}{
+		{name: "default mode", mode: defaultUnixSocketMode},
+		{name: "group access", mode: 0660},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keycard.sock")
+
+			// A stale socket is replaced
+			stale, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stale.(*net.UnixListener).SetUnlinkOnClose(false)
+			_ = stale.Close()
+
+			s := NewServer(zap.NewNop(), WithUnixSocketMode(tc.mode))
+			err = s.Listen(unixAddressPrefix + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			go s.Serve()
+			defer s.Stop(context.Background())
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tc.mode {
+				t.Fatalf("expected mode %v, got %v", tc.mode, info.Mode().Perm())
+			}
+			if s.Address() != unixAddressPrefix+path {
+				t.Fatalf("unexpected address %s", s.Address())
+			}
+
+			client := http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", path)
+				},
+			}}
+			response, err := client.Get("http://localhost/healthz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestUnixListenerKeepsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keycard.sock")
+	err := os.WriteFile(path, []byte("data"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(zap.NewNop())
+	if err = s.Listen(unixAddressPrefix + path); err == nil {
+		t.Fatal("expected an error for an existing file")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "data" {
+		t.Fatalf("the file should be left intact, got %q (%v)", data, err)
+	}
+}
+
+// passSocket returns a duplicate file descriptor of a new listening socket, as systemd would pass it
+func passSocket(t *testing.T) (int, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	file, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd, listener.Addr().String()
+}
+
+func TestSystemdListener(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	testCases := []struct {
+		name string
+		pid  string
+		fds  string
+	}{
+		{name: "not activated"},
+		{name: "other process", pid: strconv.Itoa(os.Getpid() + 1), fds: "1"},
+		{name: "no sockets", pid: pid, fds: "0"},
+		{name: "several sockets", pid: pid, fds: "2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tc.pid)
+			t.Setenv("LISTEN_FDS", tc.fds)
+
+			_, err := systemdListener(-1)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	t.Run("activated", func(t *testing.T) {
+		fd, address := passSocket(t)
+		t.Setenv("LISTEN_PID", pid)
+		t.Setenv("LISTEN_FDS", "1")
+		t.Setenv("LISTEN_FDNAMES", "keycard")
+
+		listener, err := systemdListener(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		if listener.Addr().String() != address {
+			t.Fatalf("expected the passed socket %s, got %s", address, listener.Addr())
+		}
+		for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+			if _, ok := os.LookupEnv(name); ok {
+				t.Fatalf("%s should be unset", name)
+			}
+		}
+
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				_ = conn.Close()
+			}
+		}()
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.Close()
+	})
+}
+
+func TestWithUmask(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+
+	var err error
+	withUmask(0777&^defaultUnixSocketMode, func() {
+		err = os.WriteFile(path, nil, 0666)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != defaultUnixSocketMode {
+		t.Fatalf("expected mode %v, got %v", defaultUnixSocketMode, info.Mode().Perm())
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"os"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+)
+
+type Option func(*Server)
+
+// WithAuthenticator requires all clients to authenticate. Without it, the server is open to anyone who can reach it.
+func WithAuthenticator(authenticator *Authenticator) Option {
+	return func(s *Server) {
+		s.authenticator = authenticator
+	}
+}
+
+// WithAllowedOrigins allows browser clients from the given origins, e.g. `https://admin.example.com`.
+// Requests from loopback origins, e.g. `http://localhost:3000`, are always allowed.
+func WithAllowedOrigins(origins []string) Option {
+	return func(s *Server) {
+		s.allowedOrigins = origins
+	}
+}
+
+// WithTLS serves over TLS with the given certificate and key.
+// When clientCAFile is not empty, clients may authenticate with a certificate signed by this CA.
+func WithTLS(certFile, keyFile, clientCAFile string) Option {
+	return func(s *Server) {
+		s.tlsCertFile = certFile
+		s.tlsKeyFile = keyFile
+		s.tlsClientCAFile = clientCAFile
+	}
+}
+
+// WithUnixSocketMode sets the file permissions of the Unix domain socket, 0600 by default
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(s *Server) {
+		s.unixSocketMode = mode
+	}
+}
+
+// WithKeycardService serves the given service, e.g. to run several servers with separate services
+// in one process. A new service publishing to signal.Default is created by default.
+func WithKeycardService(service *session.KeycardService) Option {
+	return func(s *Server) {
+		s.service = service
+	}
+}
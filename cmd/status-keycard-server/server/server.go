@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net"
 	"net/http"
 	"os"
@@ -16,24 +18,89 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/pkg/utils"
 	"github.com/status-im/status-keycard-go/signal"
 )
 
+const (
+	writeTimeout        = 5 * time.Second
+	maxRPCMessageLength = 1 << 20
+
+	// signalQueueSize is the number of signals queued for a client before it's disconnected
+	signalQueueSize = 64
+
+	// requestQueueSize is the number of requests queued for a client before reading is paused
+	requestQueueSize = 16
+)
+
 type Server struct {
 	logger          *zap.Logger
 	server          *http.Server
 	listener        net.Listener
 	mux             *http.ServeMux
+	service         *session.KeycardService
+	rpcServer       *session.RPCServer
 	connectionsLock sync.Mutex
-	connections     map[*websocket.Conn]struct{}
+	connections     map[*connection]struct{}
+	sseHub          *sseHub
 	address         string
+
+	authenticator   *Authenticator
+	allowedOrigins  []string
+	tlsCertFile     string
+	tlsKeyFile      string
+	tlsClientCAFile string
+	unixSocketMode  os.FileMode
+}
+
+// connection is a websocket client. It receives signals and can send JSON-RPC requests.
+// Signals and responses are written by a single goroutine, see writeMessages.
+// Writes are serialized, as websocket connections support only one concurrent writer.
+type connection struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+	signals   *signal.Subscription
+	responses chan []byte
+	closed    chan struct{}
+}
+
+func (c *connection) write(data []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err != nil {
+		return errors.Wrap(err, "failed to set write deadline")
+	}
+
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// close sends a close frame. The connection must be closed afterwards.
+func (c *connection) close(code int, text string) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	message := websocket.FormatCloseMessage(code, text)
+	_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
 }
 
-func NewServer(logger *zap.Logger) *Server {
-	return &Server{
-		logger:      logger.Named("server"),
-		connections: make(map[*websocket.Conn]struct{}, 1),
+func NewServer(logger *zap.Logger, options ...Option) *Server {
+	s := &Server{
+		logger:         logger.Named("server"),
+		connections:    make(map[*connection]struct{}, 1),
+		unixSocketMode: defaultUnixSocketMode,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.service == nil {
+		s.service = session.NewKeycardService()
 	}
+
+	return s
 }
 
 func (s *Server) Address() string {
@@ -48,69 +115,69 @@ func (s *Server) Port() (int, error) {
 	return strconv.Atoi(portString)
 }
 
-func (s *Server) Setup() {
-	signal.SetKeycardSignalHandler(s.signalHandler)
-}
-
-func (s *Server) signalHandler(data []byte) {
-	s.connectionsLock.Lock()
-	defer s.connectionsLock.Unlock()
-
-	deleteConnection := func(connection *websocket.Conn) {
-		delete(s.connections, connection)
-		err := connection.Close()
-		if err != nil {
-			s.logger.Error("failed to close connection", zap.Error(err))
-		}
+// deleteConnection must be called with connectionsLock held
+func (s *Server) deleteConnection(c *connection) {
+	if _, ok := s.connections[c]; !ok {
+		return
 	}
 
-	for connection := range s.connections {
-		err := connection.SetWriteDeadline(time.Now().Add(5 * time.Second))
-		if err != nil {
-			s.logger.Error("failed to set write deadline", zap.Error(err))
-			deleteConnection(connection)
-			continue
-		}
-
-		err = connection.WriteMessage(websocket.TextMessage, data)
-		if err != nil {
-			deleteConnection(connection)
-		}
+	delete(s.connections, c)
+	s.service.Metrics().WebSocketClients.Add(-1)
+	c.signals.Close()
+	close(c.closed)
+	err := c.conn.Close()
+	if err != nil {
+		s.logger.Error("failed to close connection", zap.Error(err))
 	}
 }
 
+// Listen starts listening on the given address:
+//   - `host:port` for TCP
+//   - `unix:/path/to/socket` for a Unix domain socket, see WithUnixSocketMode
+//   - `systemd` for a socket passed by systemd socket activation
+//
+// TLS is applied on top of any of them when configured with WithTLS.
 func (s *Server) Listen(address string) error {
 	if s.server != nil {
 		return errors.New("server already started")
 	}
 
-	_, _, err := net.SplitHostPort(address)
+	listener, err := s.listen(address)
 	if err != nil {
-		return errors.Wrap(err, "invalid address")
+		return err
 	}
 
-	s.server = &http.Server{
-		Addr:              address,
-		ReadHeaderTimeout: 5 * time.Second,
+	if s.tlsCertFile != "" {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
 	}
 
-	rpcServer, err := session.CreateRPCServer()
+	s.rpcServer, err = session.NewRPCServer(s.service)
 	if err != nil {
-		s.logger.Error("failed to create PRC server", zap.Error(err))
-		os.Exit(1)
+		_ = listener.Close()
+		return errors.Wrap(err, "failed to create RPC server")
 	}
 
+	s.sseHub = newSSEHub(s.service.Bus(), s.service.Metrics())
+
 	s.mux = http.NewServeMux()
-	s.mux.HandleFunc("/signals", s.signals)
-	s.mux.Handle("/rpc", rpcServer)
-	s.server.Handler = s.mux
+	s.mux.Handle("/signals", s.authenticated(http.HandlerFunc(s.signals)))
+	s.mux.Handle("/events", s.authenticated(http.HandlerFunc(s.events)))
+	s.mux.Handle("/rpc", s.authenticated(s.rpcServer))
+	s.mux.Handle("/metrics", s.authenticated(s.service.Metrics().Handler()))
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/readyz", s.readyz)
 
-	s.listener, err = net.Listen("tcp", address)
-	if err != nil {
-		return err
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
 	}
-
-	s.address = s.listener.Addr().String()
+	s.listener = listener
+	s.address = listenerAddress(listener)
 
 	return nil
 }
@@ -122,16 +189,27 @@ func (s *Server) Serve() {
 	}
 }
 
+// Stop shuts the server down gracefully:
+//  1. new RPC calls are rejected
+//  2. the card command in progress finishes, or is cancelled when ctx is done
+//  3. the keycard service is stopped, the final status is sent to the clients
+//  4. websocket connections are closed with a close frame, event streams are ended
+//  5. the HTTP server is stopped
 func (s *Server) Stop(ctx context.Context) {
-	for connection := range s.connections {
-		err := connection.Close()
-		if err != nil {
-			s.logger.Error("failed to close connection", zap.Error(err))
-		}
-		delete(s.connections, connection)
+	err := s.rpcServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error("failed to stop keycard service gracefully", zap.Error(err))
 	}
 
-	err := s.server.Shutdown(ctx)
+	s.connectionsLock.Lock()
+	for c := range s.connections {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+		s.deleteConnection(c)
+	}
+	s.connectionsLock.Unlock()
+	s.sseHub.close()
+
+	err = s.server.Shutdown(ctx)
 	if err != nil {
 		s.logger.Error("failed to shutdown signals server", zap.Error(err))
 	}
@@ -140,22 +218,227 @@ func (s *Server) Stop(ctx context.Context) {
 	s.address = ""
 }
 
+// signals upgrades the request to a websocket, which pushes signals to the client.
+// The client can also send JSON-RPC requests over the same connection. Requests are
+// executed one by one, responses are interleaved with signals in the order they happen.
+// Approve and Reject requests are executed as soon as they're received, so that a call
+// waiting for confirmation can be resolved over the same connection.
 func (s *Server) signals(w http.ResponseWriter, r *http.Request) {
-	s.connectionsLock.Lock()
-	defer s.connectionsLock.Unlock()
-
 	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true // Accepting all requests
-		},
+		CheckOrigin: s.checkOrigin,
 	}
 
-	connection, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Error("failed to upgrade connection", zap.Error(err))
 		return
 	}
 	s.logger.Debug("new websocket connection")
 
-	s.connections[connection] = struct{}{}
+	// New clients start with the current status.
+	// A client which doesn't keep up with signals is disconnected, and gets the current status on reconnect.
+	c := &connection{
+		conn:      conn,
+		signals:   s.service.Bus().Subscribe(signalQueueSize, signal.CloseOnOverflow),
+		responses: make(chan []byte),
+		closed:    make(chan struct{}),
+	}
+
+	s.connectionsLock.Lock()
+	s.connections[c] = struct{}{}
+	s.service.Metrics().WebSocketClients.Add(1)
+	s.connectionsLock.Unlock()
+
+	// Scope is not set when authentication is disabled
+	ctx := context.Background()
+	if scope, ok := session.ScopeFromContext(r.Context()); ok {
+		ctx = session.WithScope(ctx, scope)
+	}
+
+	go s.writeMessages(c)
+	go s.readRequests(ctx, c)
+}
+
+// writeMessages writes signals and RPC responses to the websocket until the connection is closed
+func (s *Server) writeMessages(c *connection) {
+	defer func() {
+		s.connectionsLock.Lock()
+		s.deleteConnection(c)
+		s.connectionsLock.Unlock()
+	}()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case envelope, ok := <-c.signals.Events():
+			if !ok {
+				s.logger.Warn("websocket client is too slow, disconnecting")
+				return
+			}
+			err := c.write(envelope.JSON())
+			envelope.Release()
+			if err != nil {
+				s.logger.Error("failed to send signal", zap.Error(err))
+				return
+			}
+		case response := <-c.responses:
+			// Signals caused by the request were queued before its response was
+			err := s.writeQueuedSignals(c)
+			if err == nil {
+				err = c.write(response)
+			}
+			utils.Wipe(response)
+			if err != nil {
+				s.logger.Error("failed to send rpc response", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) writeQueuedSignals(c *connection) error {
+	for {
+		select {
+		case envelope, ok := <-c.signals.Events():
+			if !ok {
+				return errors.New("signals subscription closed")
+			}
+			err := c.write(envelope.JSON())
+			envelope.Release()
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// readRequests serves JSON-RPC requests received from the websocket until the connection is closed
+func (s *Server) readRequests(ctx context.Context, c *connection) {
+	defer func() {
+		s.connectionsLock.Lock()
+		s.deleteConnection(c)
+		s.connectionsLock.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	requests := make(chan []byte, requestQueueSize)
+	defer close(requests)
+	go s.executeRequests(ctx, c, requests)
+
+	c.conn.SetReadLimit(maxRPCMessageLength)
+
+	for {
+		messageType, payload, err := c.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				s.logger.Debug("websocket connection closed", zap.Error(err))
+			}
+			return
+		}
+
+		if messageType != websocket.TextMessage {
+			utils.Wipe(payload)
+			continue
+		}
+
+		if session.ResolvesConfirmation(payload) {
+			if !s.execute(ctx, c, payload) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case requests <- payload:
+		case <-c.closed:
+			utils.Wipe(payload)
+			return
+		}
+	}
+}
+
+// executeRequests executes the queued requests one by one, until the queue is closed
+func (s *Server) executeRequests(ctx context.Context, c *connection, requests <-chan []byte) {
+	for payload := range requests {
+		if !s.execute(ctx, c, payload) {
+			break
+		}
+	}
+
+	// Wipe the requests left behind when the connection was closed
+	for payload := range requests {
+		utils.Wipe(payload)
+	}
+}
+
+// execute executes the request and queues the response. Returns false when the connection is closed.
+func (s *Server) execute(ctx context.Context, c *connection, payload []byte) bool {
+	response := s.rpcServer.Call(ctx, payload)
+	utils.Wipe(payload)
+
+	if response == nil {
+		return true
+	}
+
+	select {
+	case c.responses <- response:
+		return true
+	case <-c.closed:
+		utils.Wipe(response)
+		return false
+	}
+}
+
+// healthz responds with 200 as long as the server is serving.
+// Health and readiness checks don't require authentication.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// readyz responds with 200 when the keycard session is started and PC/SC is available, 503 otherwise
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	err := s.rpcServer.Ready()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load TLS certificate")
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if s.tlsClientCAFile != "" {
+		caData, err := os.ReadFile(s.tlsClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read TLS client CA")
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caData) {
+			return nil, errors.New("no certificates found in TLS client CA file")
+		}
+
+		// Clients without certificate can still authenticate with a token
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
 }
@@ -0,0 +1,481 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-keycard-go/pkg/metrics"
+	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// startTestServer serves a new service on a random local port, without authentication unless given in options
+func startTestServer(t *testing.T, options ...Option) *Server {
+	t.Helper()
+
+	service := session.NewKeycardService(session.WithSignalBus(signal.NewBus()))
+	s := NewServer(zap.NewNop(), append([]Option{WithKeycardService(service)}, options...)...)
+	err := s.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	t.Cleanup(func() { s.Stop(context.Background()) })
+	return s
+}
+
+// testMessage is either a signal or an RPC response
+type testMessage struct {
+	Type  string          `json:"type"`
+	Seq   uint64          `json:"seq"`
+	Event json.RawMessage `json:"event"`
+	ID    json.RawMessage `json:"id"`
+	Error *session.Error  `json:"error"`
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) testMessage {
+	t.Helper()
+
+	var message testMessage
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	err := conn.ReadJSON(&message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func sendRequest(t *testing.T, conn *websocket.Conn, id int, method string, params interface{}) {
+	t.Helper()
+
+	err := conn.WriteJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "keycard." + method,
+		"params":  []interface{}{params},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitResponse reads messages until the response with the given id
+func waitResponse(t *testing.T, conn *websocket.Conn, id int) testMessage {
+	t.Helper()
+
+	for {
+		message := readMessage(t, conn)
+		if string(message.ID) == strconv.Itoa(id) {
+			return message
+		}
+	}
+}
+
+func TestApproveOverSameWebSocket(t *testing.T) {
+	for _, method := range []string{"Approve", "Reject"} {
+		t.Run(method, func(t *testing.T) {
+			s := startTestServer(t)
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws://"+s.Address()+"/signals", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			sendRequest(t, conn, 1, "Start", session.StartRequest{
+				StorageFilePath:       filepath.Join(t.TempDir(), "pairings.json"),
+				ConfirmationTimeoutMs: 60000,
+			})
+			waitResponse(t, conn, 1)
+
+			sendRequest(t, conn, 2, "FactoryReset", struct{}{})
+
+			var request session.ConfirmationRequiredEvent
+			for {
+				message := readMessage(t, conn)
+				if message.Type == session.ConfirmationRequired {
+					err = json.Unmarshal(message.Event, &request)
+					if err != nil {
+						t.Fatal(err)
+					}
+					break
+				}
+			}
+
+			// The factory reset is still waiting, Approve and Reject are executed right away
+			sendRequest(t, conn, 3, method, session.ResolveConfirmationRequest{ID: request.ID})
+			response := waitResponse(t, conn, 3)
+			if response.Error != nil {
+				t.Fatal(response.Error)
+			}
+
+			// Approved, the factory reset fails without a keycard
+			response = waitResponse(t, conn, 2)
+			if response.Error == nil {
+				t.Fatal("expected an error")
+			}
+			rejected := response.Error.Code == session.ErrorCodeRejected
+			if rejected != (method == "Reject") {
+				t.Fatalf("unexpected error %+v", response.Error)
+			}
+		})
+	}
+}
+
+func TestWebSocketOrdering(t *testing.T) {
+	s := startTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+s.Address()+"/signals", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Requests are sent without waiting for the responses
+	sendRequest(t, conn, 1, "Start", session.StartRequest{StorageFilePath: filepath.Join(t.TempDir(), "pairings.json")})
+	sendRequest(t, conn, 2, "Stop", struct{}{})
+	sendRequest(t, conn, 3, "GetStatus", struct{}{})
+
+	var lastSeq uint64
+	statusBeforeStart := false
+	for expectedID := 1; expectedID <= 3; {
+		message := readMessage(t, conn)
+		if message.Type == signal.StatusChanged {
+			if message.Seq <= lastSeq {
+				t.Fatalf("signal %d received after %d", message.Seq, lastSeq)
+			}
+			lastSeq = message.Seq
+			statusBeforeStart = statusBeforeStart || expectedID == 1
+			continue
+		}
+
+		// Responses come in request order, the status published by Start before its response
+		if string(message.ID) != strconv.Itoa(expectedID) {
+			t.Fatalf("expected the response %d, got %s", expectedID, message.ID)
+		}
+		if expectedID == 1 && !statusBeforeStart {
+			t.Fatal("the Start response was sent before the status it published")
+		}
+		if expectedID == 3 && (message.Error == nil || message.Error.Code != session.ErrorCodeNotStarted) {
+			t.Fatalf("expected GetStatus to run after Stop, got %+v", message.Error)
+		}
+		expectedID++
+	}
+}
+
+// TestWriteMessagesFlushesSignalsFirst starts the writer with both a signal and a response waiting,
+// the signal published before the response must be written first.
+func TestWriteMessagesFlushesSignalsFirst(t *testing.T) {
+	s := NewServer(zap.NewNop())
+
+	for i := 0; i < 20; i++ {
+		serverConns := make(chan *websocket.Conn, 1)
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			serverConns <- conn
+		}))
+
+		client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bus := signal.NewBus()
+		c := &connection{
+			conn:      <-serverConns,
+			signals:   bus.Subscribe(signalQueueSize, signal.CloseOnOverflow),
+			responses: make(chan []byte),
+			closed:    make(chan struct{}),
+		}
+
+		bus.Send(signal.StatusChanged, map[string]string{"state": "ready"})
+		go func() { c.responses <- []byte(`{"jsonrpc":"2.0","id":1,"result":null}`) }()
+		time.Sleep(time.Millisecond)
+		go s.writeMessages(c)
+
+		if message := readMessage(t, client); message.Type != signal.StatusChanged {
+			t.Fatalf("expected the signal first, got the response %s", message.ID)
+		}
+		if message := readMessage(t, client); string(message.ID) != "1" {
+			t.Fatalf("expected the response, got %+v", message)
+		}
+
+		close(c.closed)
+		c.signals.Close()
+		_ = c.conn.Close()
+		_ = client.Close()
+		httpServer.Close()
+	}
+}
+
+// readSSE reads the stream until the given number of events, and returns their fields
+func readSSE(t *testing.T, body *bufio.Reader, count int) []map[string]string {
+	t.Helper()
+
+	var events []map[string]string
+	event := map[string]string{}
+	for len(events) < count {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if _, ok := event["data"]; ok {
+				events = append(events, event)
+			}
+			event = map[string]string{}
+			continue
+		}
+		if field, value, ok := strings.Cut(line, ": "); ok {
+			event[field] = value
+		}
+	}
+	return events
+}
+
+// waitSSEHub waits until the SSE hub received the signals sent up to seq
+func waitSSEHub(t *testing.T, h *sseHub, seq uint64) {
+	t.Helper()
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		h.lock.Lock()
+		lastSeq := h.lastSeq
+		h.lock.Unlock()
+		if lastSeq >= seq {
+			return
+		}
+	}
+	t.Fatalf("signal %d not received", seq)
+}
+
+func TestSSEResumeAfterLostEvents(t *testing.T) {
+	s := startTestServer(t)
+	bus := s.service.Bus()
+	bus.Send(signal.StatusChanged, map[string]string{"state": "test"})
+	bus.Send("flow-result", map[string]string{})
+	bus.Send("flow-result", map[string]string{})
+	waitSSEHub(t, s.sseHub, 3)
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		expected    []map[string]string
+	}{
+		{
+			name:        "within history",
+			lastEventID: "1",
+			expected:    []map[string]string{{"id": "2"}, {"id": "3"}},
+		},
+		{
+			name:        "unknown id",
+			lastEventID: "100",
+			expected:    []map[string]string{{"event": "reset"}, {"id": "1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+s.Address()+"/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set(lastEventIDHeader, tc.lastEventID)
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			events := readSSE(t, bufio.NewReader(response.Body), len(tc.expected))
+			for i, expected := range tc.expected {
+				for field, value := range expected {
+					if events[i][field] != value {
+						t.Fatalf("event %d: expected %s %q, got %v", i, field, value, events[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewClientsGetCurrentStatus(t *testing.T) {
+	s := startTestServer(t)
+	bus := s.service.Bus()
+	bus.Send(signal.StatusChanged, map[string]string{"state": "first"})
+	bus.Send(signal.StatusChanged, map[string]string{"state": "current"})
+	bus.Send("flow-result", map[string]string{})
+	waitSSEHub(t, s.sseHub, 3)
+
+	t.Run("websocket", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://"+s.Address()+"/signals", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		message := readMessage(t, conn)
+		if message.Type != signal.StatusChanged || message.Seq != 2 || !strings.Contains(string(message.Event), "current") {
+			t.Fatalf("expected the current status, got %+v", message)
+		}
+	})
+
+	t.Run("server-sent events", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+s.Address()+"/events", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		event := readSSE(t, bufio.NewReader(response.Body), 1)[0]
+		if event["id"] != "2" || !strings.Contains(event["data"], `"type":"status-changed"`) || !strings.Contains(event["data"], "current") {
+			t.Fatalf("expected the current status, got %v", event)
+		}
+	})
+}
+
+type keyEvent struct {
+	PrivateKey utils.SecretHexString `json:"privateKey"`
+}
+
+func TestSSEHubSubscribe(t *testing.T) {
+	bus := signal.NewBus()
+	hub := newSSEHub(bus, metrics.New())
+	defer hub.close()
+
+	bus.Send(signal.StatusChanged, map[string]int{"n": 0})
+	bus.Send("key", keyEvent{PrivateKey: utils.SecretHexString{0xca, 0xfe}})
+	for i := 2; i < sseHistorySize+10; i++ {
+		bus.Send("number", map[string]int{"n": i})
+	}
+	lastSeq := uint64(sseHistorySize + 10)
+	waitSSEHub(t, hub, lastSeq)
+
+	testCases := []struct {
+		name    string
+		resume  bool
+		lastID  uint64
+		resumed bool
+		first   uint64
+		count   int
+	}{
+		{name: "new client", first: 1, count: 1},
+		{name: "up to date", resume: true, lastID: lastSeq, resumed: true},
+		{name: "within history", resume: true, lastID: lastSeq - 5, resumed: true, first: lastSeq - 4, count: 5},
+		{name: "whole history", resume: true, lastID: lastSeq - sseHistorySize, resumed: true, first: lastSeq - sseHistorySize + 1, count: sseHistorySize},
+		{name: "older than history", resume: true, lastID: lastSeq - sseHistorySize - 1, first: 1, count: 1},
+		{name: "from another process", resume: true, lastID: lastSeq + 1, first: 1, count: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, resumed := hub.subscribe(nil, tc.resume, tc.lastID)
+			defer hub.unsubscribe(c)
+
+			if resumed != tc.resumed {
+				t.Fatalf("expected resumed %v, got %v", tc.resumed, resumed)
+			}
+			for i := 0; i < tc.count; i++ {
+				envelope := <-c.queue
+				if envelope.Seq != tc.first+uint64(i) {
+					t.Fatalf("expected seq %d, got %d", tc.first+uint64(i), envelope.Seq)
+				}
+				envelope.Release()
+			}
+			select {
+			case envelope := <-c.queue:
+				t.Fatalf("unexpected signal %d", envelope.Seq)
+			default:
+			}
+		})
+	}
+}
+
+func TestSSEHubWipesSecretsOnceDelivered(t *testing.T) {
+	bus := signal.NewBus()
+	hub := newSSEHub(bus, metrics.New())
+	defer hub.close()
+
+	bus.Send(signal.StatusChanged, map[string]int{"n": 0})
+	c, _ := hub.subscribe(nil, false, 0)
+	bus.Send("key", keyEvent{PrivateKey: utils.SecretHexString{0xca, 0xfe}})
+	waitSSEHub(t, hub, 2)
+
+	// The key signal isn't kept, clients can't resume before it
+	for lastID, expected := range map[uint64]bool{1: false, 2: true} {
+		client, resumed := hub.subscribe(nil, true, lastID)
+		hub.unsubscribe(client)
+		if resumed != expected {
+			t.Fatalf("resuming after %d: expected %v, got %v", lastID, expected, resumed)
+		}
+	}
+
+	if status := <-c.queue; status.Seq != 1 {
+		t.Fatalf("expected the status first, got %d", status.Seq)
+	}
+
+	envelope := <-c.queue
+	data := envelope.JSON()
+	if !bytes.Contains(data, []byte("cafe")) {
+		t.Fatalf("expected the key to be revealed, got %s", data)
+	}
+	envelope.Release()
+	hub.unsubscribe(c)
+
+	if !bytes.Equal(data, make([]byte, len(data))) {
+		t.Fatalf("signal not wiped once delivered: %s", data)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	s := startTestServer(t)
+
+	get := func(path string) int {
+		response, err := http.Get("http://" + s.Address() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		return response.StatusCode
+	}
+
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected healthz to respond %d, got %d", http.StatusOK, code)
+	}
+	// The session is not started
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected readyz to respond %d, got %d", http.StatusServiceUnavailable, code)
+	}
+	if code := get("/metrics"); code != http.StatusOK {
+		t.Fatalf("expected metrics to respond %d, got %d", http.StatusOK, code)
+	}
+}
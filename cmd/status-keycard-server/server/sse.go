@@ -0,0 +1,243 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-keycard-go/pkg/metrics"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+const (
+	sseKeepAlive      = 15 * time.Second
+	lastEventIDHeader = "Last-Event-ID"
+
+	// sseHistorySize is the number of signals kept for SSE clients to resume
+	sseHistorySize = 256
+
+	// sseResetEvent tells the client that events were lost since its Last-Event-ID.
+	// The current status follows, if any.
+	sseResetEvent = "reset"
+)
+
+// sseHub delivers signals to the SSE clients, and keeps the last ones for clients to resume
+// after a reconnection. Signals carrying secrets, e.g. exported keys, are never kept: they're
+// delivered to the clients connected at the time only, and resuming across one is refused.
+type sseHub struct {
+	lock         sync.Mutex
+	subscription *signal.Subscription
+	metrics      *metrics.Metrics
+	lastSeq      uint64
+	lastStatus   *signal.Envelope
+	history      []*signal.Envelope
+	clients      map[*sseClient]struct{}
+}
+
+// sseClient receives signals through its own bounded queue, closed when the
+// client falls behind or the server stops
+type sseClient struct {
+	queue  chan *signal.Envelope
+	types  map[string]bool
+	closed bool
+}
+
+func (c *sseClient) accepts(typ string) bool {
+	return len(c.types) == 0 || c.types[typ]
+}
+
+// newSSEHub starts receiving signals from the bus. Close it to stop.
+// Signals dropped for clients which don't keep up are recorded to m.
+func newSSEHub(bus *signal.Bus, m *metrics.Metrics) *sseHub {
+	h := &sseHub{clients: make(map[*sseClient]struct{}), metrics: m}
+	h.subscription = bus.SubscribeFunc(h.send)
+	return h
+}
+
+// send keeps the signal and queues it for the clients
+func (h *sseHub) send(envelope *signal.Envelope) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// history only holds consecutive signals without secrets, so that a client
+	// resuming after any of them receives every signal sent since
+	secret := envelope.CarriesSecrets()
+	if secret || envelope.Seq != h.lastSeq+1 {
+		h.history = nil
+	}
+	h.lastSeq = envelope.Seq
+
+	if !secret {
+		if envelope.Type == signal.StatusChanged {
+			h.lastStatus = envelope
+		}
+		if len(h.history) == sseHistorySize {
+			h.history = h.history[1:]
+		}
+		h.history = append(h.history, envelope)
+	}
+
+	for c := range h.clients {
+		h.push(c, envelope)
+	}
+}
+
+// subscribe adds a client. It starts with the signals sent after lastID when resuming,
+// and with the last status otherwise. resumed is false when signals after lastID were lost,
+// or when lastID wasn't sent yet, e.g. it was given by a previous process.
+func (h *sseHub) subscribe(types map[string]bool, resume bool, lastID uint64) (c *sseClient, resumed bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var backlog []*signal.Envelope
+	missed := h.lastSeq - lastID
+	if resume && lastID <= h.lastSeq && missed <= uint64(len(h.history)) {
+		resumed = true
+		backlog = h.history[uint64(len(h.history))-missed:]
+	} else if h.lastStatus != nil {
+		backlog = []*signal.Envelope{h.lastStatus}
+	}
+
+	c = &sseClient{
+		queue: make(chan *signal.Envelope, signalQueueSize+len(backlog)),
+		types: types,
+	}
+	h.clients[c] = struct{}{}
+	for _, envelope := range backlog {
+		h.push(c, envelope)
+	}
+	return c, resumed
+}
+
+// push must be called with lock held. A client which doesn't keep up is closed.
+func (h *sseHub) push(c *sseClient, envelope *signal.Envelope) {
+	if c.closed || !c.accepts(envelope.Type) {
+		return
+	}
+
+	envelope.Retain()
+	select {
+	case c.queue <- envelope:
+	default:
+		envelope.Release()
+		h.closeClient(c)
+		h.metrics.SignalsDropped.Inc()
+	}
+}
+
+// closeClient must be called with lock held. Queued signals can still be read.
+func (h *sseHub) closeClient(c *sseClient) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(h.clients, c)
+	close(c.queue)
+}
+
+// unsubscribe removes the client, and releases the signals still queued for it
+func (h *sseHub) unsubscribe(c *sseClient) {
+	h.lock.Lock()
+	h.closeClient(c)
+	h.lock.Unlock()
+
+	for envelope := range c.queue {
+		envelope.Release()
+	}
+}
+
+// close stops receiving signals, and ends the event streams
+func (h *sseHub) close() {
+	h.subscription.Close()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for c := range h.clients {
+		h.closeClient(c)
+	}
+}
+
+// events streams signals as Server-Sent Events. Each event carries the same JSON envelope
+// as websocket signals, with a numeric id.
+//   - `?type=status-changed,flow-result` only streams signals of the given types
+//   - `Last-Event-ID` header, or `lastEventId` query parameter, resumes after the given event.
+//     Without it, the stream starts with the current status. When events after it were lost,
+//     the stream starts with a `reset` event, followed by the current status.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	var lastID uint64
+	resume := r.Header.Get(lastEventIDHeader)
+	if resume == "" {
+		resume = r.URL.Query().Get("lastEventId")
+	}
+	if resume != "" {
+		var err error
+		lastID, err = strconv.ParseUint(resume, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var types map[string]bool
+	if typeList := r.URL.Query().Get("type"); typeList != "" {
+		types = make(map[string]bool)
+		for _, typ := range strings.Split(typeList, ",") {
+			types[strings.TrimSpace(typ)] = true
+		}
+	}
+
+	c, resumed := s.sseHub.subscribe(types, resume != "", lastID)
+	defer s.sseHub.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	write := func(format string, args ...interface{}) error {
+		err := controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		_, err = fmt.Fprintf(w, format, args...)
+		if err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	// Tells the client to reconnect quickly after the connection is lost
+	err := write("retry: 1000\n\n")
+	if err == nil && resume != "" && !resumed {
+		err = write("event: %s\ndata: {}\n\n", sseResetEvent)
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			err = write(": keep-alive\n\n")
+		case envelope, ok := <-c.queue:
+			if !ok {
+				// Server is stopping, or the client didn't keep up
+				return
+			}
+			err = write("id: %d\ndata: %s\n\n", envelope.Seq, envelope.JSON())
+			envelope.Release()
+		}
+	}
+
+	s.logger.Debug("sse connection closed", zap.Error(err))
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package server
+
+import (
+	"os"
+)
+
+// withUmask runs fn, there is no umask on this platform
+func withUmask(mask os.FileMode, fn func()) {
+	fn()
+}
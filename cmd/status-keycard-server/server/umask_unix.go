@@ -0,0 +1,16 @@
+//go:build unix
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// withUmask runs fn with the given umask. The umask is process-wide: files created concurrently
+// by other goroutines get the same, more restrictive, permissions.
+func withUmask(mask os.FileMode, fn func()) {
+	previous := syscall.Umask(int(mask.Perm()))
+	defer syscall.Umask(previous)
+	fn()
+}
@@ -3,7 +3,6 @@ package main
 import "C"
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -19,10 +18,8 @@ var correctPIN = "123456"
 var correctPUK = "123456123456"
 var keyUID = "136cbfc087cf7df6cf3248bce7563d4253b302b2f9e2b5eef8713fa5091409bc"
 
-func signalHandler(j []byte) {
-	var sig signal.Envelope
-	json.Unmarshal(j, &sig)
-	fmt.Printf("Received signal: %+v\n", sig)
+func signalHandler(sig *signal.Envelope) {
+	fmt.Printf("Received signal: %s\n", sig.Type)
 
 	go func() {
 		switch sig.Type {
@@ -34,25 +31,27 @@ func signalHandler(j []byte) {
 			fmt.Printf("Swap card. Changing constraint\n")
 			currentFlow.Resume(flow.FlowParams{flow.KeyUID: keyUID})
 		case flow.EnterPairing:
-			fmt.Printf("Entering pass: %+v\n", correctPairing)
+			fmt.Printf("Entering pass\n")
 			currentFlow.Resume(flow.FlowParams{flow.PairingPass: correctPairing})
 		case flow.EnterPIN:
-			fmt.Printf("Entering PIN: %+v\n", correctPIN)
+			fmt.Printf("Entering PIN\n")
 			currentFlow.Resume(flow.FlowParams{flow.PIN: correctPIN})
 		case flow.EnterNewPIN:
-			fmt.Printf("Creating PIN: %+v\n", correctPIN)
+			fmt.Printf("Creating PIN\n")
 			currentFlow.Resume(flow.FlowParams{flow.NewPIN: correctPIN})
 		case flow.EnterNewPUK:
-			fmt.Printf("Creating PUK: %+v\n", correctPUK)
+			fmt.Printf("Creating PUK\n")
 			currentFlow.Resume(flow.FlowParams{flow.NewPUK: correctPUK})
 		case flow.EnterNewPair:
-			fmt.Printf("Creating pairing: %+v\n", correctPairing)
+			fmt.Printf("Creating pairing\n")
 			currentFlow.Resume(flow.FlowParams{flow.NewPairing: correctPairing})
 		case flow.EnterMnemonic:
 			fmt.Printf("Loading mnemonic\n")
 			currentFlow.Resume(flow.FlowParams{flow.Mnemonic: "receive fan copper bracket end train again sustain wet siren throw cigar"})
 		case flow.FlowResult:
-			fmt.Printf("Flow result: %+v\n", sig.Event)
+			if result, ok := sig.Event.(flow.ResultEvent); ok {
+				fmt.Printf("Flow result: %s\n", result.Status)
+			}
 			close(finished)
 		}
 	}()
@@ -98,7 +97,7 @@ func main() {
 		return
 	}
 
-	signal.SetKeycardSignalHandler(signalHandler)
+	signal.SubscribeFunc(signalHandler)
 
 	testFlow(flow.GetAppInfo, flow.FlowParams{flow.FactoryReset: true})
 	testFlow(flow.LoadAccount, flow.FlowParams{flow.MnemonicLen: 12})
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/status-im/keycard-go"
+
+	"github.com/status-im/status-keycard-go/pkg/audit"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// SetAuditLog records security-relevant card operations to the given audit log
+func (kc *KeycardContext) SetAuditLog(log *audit.Log) {
+	kc.auditLog = log
+}
+
+// audit records the event. InstanceUID and KeyUID are taken from the selected applet, unless set.
+func (kc *KeycardContext) audit(entry audit.Entry) {
+	if kc.auditLog == nil {
+		return
+	}
+
+	if kc.cmdSet != nil && kc.cmdSet.ApplicationInfo != nil {
+		if entry.InstanceUID == "" {
+			entry.InstanceUID = utils.Btox(kc.cmdSet.ApplicationInfo.InstanceUID)
+		}
+		if entry.KeyUID == "" {
+			entry.KeyUID = utils.Btox(kc.cmdSet.ApplicationInfo.KeyUID)
+		}
+	}
+
+	err := kc.auditLog.Record(entry)
+	if err != nil {
+		Printf("failed to record audit event %s: %+v", entry.Event, err)
+	}
+}
+
+func (kc *KeycardContext) auditPINVerification(err error) {
+	var wrongPIN *keycard.WrongPINError
+	switch {
+	case err == nil:
+		kc.audit(audit.Entry{Event: audit.PINVerified})
+	case errors.As(err, &wrongPIN):
+		kc.audit(audit.Entry{Event: audit.PINFailed, Details: map[string]string{
+			"remainingAttempts": strconv.Itoa(wrongPIN.RemainingAttempts),
+		}})
+	}
+}
+
+func (kc *KeycardContext) auditPINUnblock(err error) {
+	var wrongPUK *keycard.WrongPUKError
+	switch {
+	case err == nil:
+		kc.audit(audit.Entry{Event: audit.PINUnblocked})
+	case errors.As(err, &wrongPUK):
+		kc.audit(audit.Entry{Event: audit.PUKFailed, Details: map[string]string{
+			"remainingAttempts": strconv.Itoa(wrongPUK.RemainingAttempts),
+		}})
+	}
+}
+
+// auditExport records private key exports and exports denied by the export policy
+func (kc *KeycardContext) auditExport(path string, p2 uint8, denied bool) {
+	kind := exportKindOf(p2)
+	details := map[string]string{"path": path, "kind": string(kind)}
+
+	if denied {
+		kc.audit(audit.Entry{Event: audit.ExportDenied, Details: details})
+	} else if kind == ExportPrivate {
+		kc.audit(audit.Entry{Event: audit.KeyExported, Details: details})
+	}
+}
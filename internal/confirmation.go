@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// ConfirmationRequest describes an operation waiting for the user approval
+type ConfirmationRequest struct {
+	ID          string `json:"id"`
+	Method      string `json:"method"`
+	Description string `json:"description"`
+	InstanceUID string `json:"instanceUID"`
+	KeyUID      string `json:"keyUID"`
+}
+
+// NewConfirmationRequest returns a request with a random ID
+func NewConfirmationRequest(method string, description string) (ConfirmationRequest, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return ConfirmationRequest{}, err
+	}
+
+	return ConfirmationRequest{
+		ID:          hex.EncodeToString(id),
+		Method:      method,
+		Description: description,
+	}, nil
+}
+
+// Confirmer approves operations before they run: signing, private key exports and factory reset.
+// Embedders can implement it to show their own UI.
+type Confirmer interface {
+	// Confirm returns nil when the operation is approved, an error otherwise.
+	// It must return when ctx is done.
+	Confirm(ctx context.Context, request ConfirmationRequest) error
+}
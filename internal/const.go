@@ -8,6 +8,9 @@ const (
 	DefPINLen     = 6
 	DefPUKLen     = 12
 	DefPairing    = "KeycardDefaultPairing"
+
+	// TransportReader is the reader name of a card connected through a transport instead of PC/SC
+	TransportReader = "transport"
 )
 
 const (
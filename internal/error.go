@@ -21,4 +21,6 @@ const (
 	ErrorPCSC        = "no-pcsc"
 	ErrorReaderList  = "no-reader-list"
 	ErrorNoReader    = "no-reader-found"
+	ErrorRejected    = "rejected"
+	ErrorTimeout     = "timeout"
 )
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/derivationpath"
+)
+
+// ExportKind is what is exported for a key
+type ExportKind string
+
+const (
+	ExportPrivate        ExportKind = "private"
+	ExportPublic         ExportKind = "public"
+	ExportExtendedPublic ExportKind = "extended-public"
+)
+
+// hardenedIndex is added to the index of hardened path components
+const hardenedIndex = 0x80000000
+
+func exportKindOf(p2 uint8) ExportKind {
+	switch p2 {
+	case keycard.P2ExportKeyPrivateAndPublic:
+		return ExportPrivate
+	case keycard.P2ExportKeyExtendedPublic:
+		return ExportExtendedPublic
+	default:
+		return ExportPublic
+	}
+}
+
+// ExportPolicy decides which derivation paths can be exported, on top of the applet restrictions.
+// Each list holds absolute paths. A path ending with `/*` matches all its descendants, e.g. `m/43'/60'/1581'/*`.
+// A nil list allows all paths, an empty list denies all of them.
+type ExportPolicy struct {
+	Private        []string `json:"private"`
+	Public         []string `json:"public"`
+	ExtendedPublic []string `json:"extendedPublic"`
+}
+
+// Allows reports whether the key at path can be exported with the given kind.
+// A nil policy allows everything.
+func (p *ExportPolicy) Allows(path string, kind ExportKind) bool {
+	if p == nil {
+		return true
+	}
+
+	var patterns []string
+	switch kind {
+	case ExportPrivate:
+		patterns = p.Private
+	case ExportExtendedPublic:
+		patterns = p.ExtendedPublic
+	default:
+		patterns = p.Public
+	}
+
+	if patterns == nil {
+		return true
+	}
+
+	// The path is checked as keycard-go sends it to the card, since that's the key which is exported
+	start, components, err := derivationpath.Decode(path)
+	if err != nil || start != derivationpath.StartingPointMaster {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if matchPath(pattern, components) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns a `forbidden` error when the export is not allowed
+func (p *ExportPolicy) check(path string, p2 uint8) error {
+	kind := exportKindOf(p2)
+	if p.Allows(path, kind) {
+		return nil
+	}
+	return NewError(ErrorCodeForbidden, fmt.Sprintf("export of %s key not allowed for path %s", kind, path))
+}
+
+// Validate reports patterns which are not valid absolute paths
+func (p *ExportPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	for _, patterns := range [][]string{p.Private, p.Public, p.ExtendedPublic} {
+		for _, pattern := range patterns {
+			_, err := decodeAbsolutePath(strings.TrimSuffix(pattern, "/*"))
+			if err != nil {
+				return fmt.Errorf("invalid export path pattern '%s'", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func matchPath(pattern string, components []uint32) bool {
+	subtree := strings.HasSuffix(pattern, "/*")
+
+	prefix, err := decodeAbsolutePath(strings.TrimSuffix(pattern, "/*"))
+	if err != nil {
+		return false
+	}
+
+	if subtree && len(components) <= len(prefix) || !subtree && len(components) != len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if components[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeAbsolutePath parses a path starting at the master key, e.g. `m/44'/60'/0'/0/0`.
+// derivationpath.Decode drops a trailing hardened component, e.g. `m/43'/60'/1581'` is decoded
+// as `m/43'/60'`, which would widen the patterns.
+func decodeAbsolutePath(path string) ([]uint32, error) {
+	segments := strings.Split(path, "/")
+	if segments[0] != "m" {
+		return nil, fmt.Errorf("path must start with 'm'")
+	}
+
+	components := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		hardened := strings.HasSuffix(segment, "'")
+		index, err := strconv.ParseUint(strings.TrimSuffix(segment, "'"), 10, 31)
+		if err != nil {
+			return nil, err
+		}
+		if hardened {
+			index |= hardenedIndex
+		}
+		components = append(components, uint32(index))
+	}
+	return components, nil
+}
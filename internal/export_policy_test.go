@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go"
+)
+
+func TestExportPolicyAllows(t *testing.T) {
+	policy := &ExportPolicy{
+		Private:        []string{"m/43'/60'/1581'/*", "m/44'/60'/0'/0/0"},
+		Public:         []string{},
+		ExtendedPublic: nil,
+	}
+
+	testCases := []struct {
+		name    string
+		policy  *ExportPolicy
+		path    string
+		kind    ExportKind
+		allowed bool
+	}{
+		{name: "nil policy", policy: nil, path: "m/44'/60'/0'/0/1", kind: ExportPrivate, allowed: true},
+		{name: "nil list allows all", policy: policy, path: "m/44'/60'/0'/0/1", kind: ExportExtendedPublic, allowed: true},
+		{name: "empty list denies all", policy: policy, path: "m/44'/60'/0'/0/0", kind: ExportPublic, allowed: false},
+
+		{name: "exact path", policy: policy, path: "m/44'/60'/0'/0/0", kind: ExportPrivate, allowed: true},
+		{name: "sibling of exact path", policy: policy, path: "m/44'/60'/0'/0/1", kind: ExportPrivate, allowed: false},
+		{name: "child of exact path", policy: policy, path: "m/44'/60'/0'/0/0/0", kind: ExportPrivate, allowed: false},
+		{name: "parent of exact path", policy: policy, path: "m/44'/60'/0'/0", kind: ExportPrivate, allowed: false},
+
+		{name: "child of subtree", policy: policy, path: "m/43'/60'/1581'/0", kind: ExportPrivate, allowed: true},
+		{name: "descendant of subtree", policy: policy, path: "m/43'/60'/1581'/1'/0", kind: ExportPrivate, allowed: true},
+		{name: "subtree root", policy: policy, path: "m/43'/60'/1581'", kind: ExportPrivate, allowed: false},
+		{name: "outside subtree", policy: policy, path: "m/43'/60'/1582'/0", kind: ExportPrivate, allowed: false},
+		{name: "sibling of subtree root", policy: policy, path: "m/43'/60'/1582'/0'/0", kind: ExportPrivate, allowed: false},
+		// keycard-go drops the trailing hardened component, the card would export the subtree root
+		{name: "trailing hardened component", policy: policy, path: "m/43'/60'/1581'/0'", kind: ExportPrivate, allowed: false},
+
+		{name: "non-hardened instead of hardened", policy: policy, path: "m/43'/60'/1581/0", kind: ExportPrivate, allowed: false},
+		{name: "hardened instead of non-hardened", policy: policy, path: "m/44'/60'/0'/0/0'", kind: ExportPrivate, allowed: false},
+
+		{name: "relative to current", policy: policy, path: "43'/60'/1581'/0'", kind: ExportPrivate, allowed: false},
+		{name: "relative to parent", policy: policy, path: "../60'/1581'/0'", kind: ExportPrivate, allowed: false},
+		{name: "invalid path", policy: policy, path: "m/43'/sixty/1581'/0'", kind: ExportPrivate, allowed: false},
+		{name: "empty path", policy: policy, path: "", kind: ExportPrivate, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if allowed := tc.policy.Allows(tc.path, tc.kind); allowed != tc.allowed {
+				t.Fatalf("expected %v, got %v", tc.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestExportPolicyCheck(t *testing.T) {
+	policy := &ExportPolicy{Private: []string{}}
+
+	err := policy.check("m/44'/60'/0'/0/0", keycard.P2ExportKeyPrivateAndPublic)
+	if AsError(err).Code != ErrorCodeForbidden {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+
+	err = policy.check("m/44'/60'/0'/0/0", keycard.P2ExportKeyPublicOnly)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestExportPolicyValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy *ExportPolicy
+		valid  bool
+	}{
+		{name: "nil policy", policy: nil, valid: true},
+		{name: "nil and empty lists", policy: &ExportPolicy{Public: []string{}}, valid: true},
+		{name: "exact and subtree paths", policy: &ExportPolicy{Private: []string{"m/44'/60'/0'/0/0", "m/43'/60'/1581'/*"}}, valid: true},
+		{name: "master key", policy: &ExportPolicy{Public: []string{"m"}}, valid: true},
+		{name: "index too large", policy: &ExportPolicy{Private: []string{"m/2147483648"}}, valid: false},
+		{name: "negative index", policy: &ExportPolicy{Private: []string{"m/-1"}}, valid: false},
+		{name: "empty component", policy: &ExportPolicy{Private: []string{"m//0"}}, valid: false},
+		{name: "relative path", policy: &ExportPolicy{Private: []string{"44'/60'/0'/0/0"}}, valid: false},
+		{name: "invalid path", policy: &ExportPolicy{ExtendedPublic: []string{"m/44'/x"}}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.Validate(); (err == nil) != tc.valid {
+				t.Fatalf("expected valid %v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestDecodeAbsolutePath(t *testing.T) {
+	components, err := decodeAbsolutePath("m/43'/60'/1581'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint32{0x8000002b, 0x8000003c, 0x8000062d}
+	if len(components) != len(expected) {
+		t.Fatalf("expected %x, got %x", expected, components)
+	}
+	for i := range expected {
+		if components[i] != expected[i] {
+			t.Fatalf("expected %x, got %x", expected, components)
+		}
+	}
+}
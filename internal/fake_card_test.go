@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"sync"
+	"testing"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/io"
+
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// swInsNotSupported is returned by fakeCard for any command without a queued response
+var swInsNotSupported = []byte{0x6D, 0x00}
+
+// fakeCard is a transport which answers commands with queued responses
+type fakeCard struct {
+	lock      sync.Mutex
+	responses [][]byte
+	commands  [][]byte
+}
+
+func (c *fakeCard) Transmit(command []byte) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.commands = append(c.commands, command)
+	if len(c.responses) == 0 {
+		return swInsNotSupported, nil
+	}
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	return response, nil
+}
+
+func (c *fakeCard) sent() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.commands)
+}
+
+// newTestContext returns a context with a keycard connected through the fake card, in the given state.
+// Nothing is started, commands are sent straight to the fake card.
+func newTestContext(t *testing.T, state State, options ...Option) (*KeycardContextV2, *fakeCard, *signal.Bus) {
+	t.Helper()
+
+	bus := signal.NewBus()
+	kc, err := NewKeycardContextV2(append([]Option{WithSignalBus(bus)}, options...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := &fakeCard{}
+	kc.setChannel(io.NewNormalChannel(card))
+	kc.connected.Store(true)
+	kc.status.status.State = state
+	return kc, card, bus
+}
+
+// waitForState waits for a `status-changed` signal with the given state
+func waitForState(t *testing.T, subscription *signal.Subscription, state State) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case envelope, ok := <-subscription.Events():
+			if !ok {
+				t.Fatalf("subscription closed before %s", state)
+			}
+			if event, ok := envelope.Event.(StatusChangedEvent); ok && event.State == state {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %s status", state)
+		}
+	}
+}
+
+// newTransportContext returns a context exchanging APDUs with the transport through the card communication
+// routine, as started with WithTransport. The card is not connected and there is no detection.
+func newTransportContext(t *testing.T, transport io.Transmitter, options ...Option) (*KeycardContextV2, *signal.Bus) {
+	t.Helper()
+
+	bus := signal.NewBus()
+	kc, err := NewKeycardContextV2(append([]Option{WithSignalBus(bus), WithTransport(transport)}, options...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	kc.transmitContext = ctx
+	kc.routines.Add(1)
+	go kc.cardCommunicationRoutine(ctx)
+	t.Cleanup(func() {
+		cancel()
+		kc.routines.Wait()
+	})
+	return kc, bus
+}
+
+// selectResponse is the response of a keycard to the applet selection.
+// The keycard is initialized when instanceUID is set.
+func selectResponse(instanceUID []byte, publicKey *ecdsa.PublicKey) []byte {
+	tlv := func(tag byte, value ...byte) []byte {
+		return append([]byte{tag, byte(len(value))}, value...)
+	}
+
+	pubKey := ethcrypto.FromECDSAPub(publicKey)
+	if instanceUID == nil {
+		return append(tlv(0x80, pubKey...), 0x90, 0x00)
+	}
+
+	var info []byte
+	info = append(info, tlv(0x8F, instanceUID...)...)
+	info = append(info, tlv(0x80, pubKey...)...)
+	info = append(info, tlv(0x02, 0x03, 0x01)...)
+	info = append(info, tlv(0x02, 0x05)...)
+	info = append(info, tlv(0x8E)...)
+	info = append(info, tlv(0x8D, 0xFF)...)
+	return append(tlv(0xA4, info...), 0x90, 0x00)
+}
+
+// statusResponse is the response to GET STATUS with the given PIN and PUK retries
+func statusResponse(pinRetries, pukRetries byte) []byte {
+	return []byte{0xA3, 0x09, 0x02, 0x01, pinRetries, 0x02, 0x01, pukRetries, 0x01, 0x01, 0x00, 0x90, 0x00}
+}
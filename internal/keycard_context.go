@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/ebfe/scard"
@@ -17,6 +18,10 @@ import (
 	"github.com/status-im/keycard-go/types"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/text/unicode/norm"
+
+	"github.com/status-im/status-keycard-go/pkg/audit"
+	"github.com/status-im/status-keycard-go/pkg/trace"
+	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
 const bip39Salt = "mnemonic"
@@ -33,6 +38,9 @@ type KeycardContext struct {
 	cardCtx   *scard.Context
 	card      *scard.Card
 	readers   []string
+	reader    string
+	tracer    *trace.Recorder
+	transport io.Transmitter
 	c         types.Channel
 	cmdSet    *keycard.CommandSet
 	connected chan (bool)
@@ -40,10 +48,21 @@ type KeycardContext struct {
 	apdu      []byte
 	rpdu      []byte
 	runErr    error
+
+	// secureChannel is the channel of cmdSet, it knows the session keys to decrypt the trace
+	secureChannel *secureChannel
+	scDecoder     trace.SecureChannelDecoder
+
+	// exportPolicy restricts the exported keys, all keys allowed by the applet are exported when nil
+	exportPolicy *ExportPolicy
+
+	// auditLog records security-relevant operations, see SetAuditLog
+	auditLog *audit.Log
 }
 
 // Transmit implements the Channel and Transmitter interfaces
 func (kc *KeycardContext) Transmit(apdu []byte) ([]byte, error) {
+	started := time.Now()
 	kc.apdu = apdu
 	kc.command <- Transmit
 	<-kc.command
@@ -51,13 +70,19 @@ func (kc *KeycardContext) Transmit(apdu []byte) ([]byte, error) {
 	rpdu, err := kc.rpdu, kc.runErr
 	kc.rpdu = nil
 	kc.runErr = nil
+	kc.traceAPDU(started, apdu, rpdu, err)
 	return rpdu, err
 }
 
-func StartKeycardContext() (*KeycardContext, error) {
+// StartKeycardContext connects to the first card found. When tracer is not nil,
+// all APDUs exchanged with the card are recorded.
+// When transport is not nil, it's used instead of PC/SC, e.g. to replay a trace with trace.Replayer.
+func StartKeycardContext(tracer *trace.Recorder, transport io.Transmitter) (*KeycardContext, error) {
 	kctx := &KeycardContext{
 		connected: make(chan (bool)),
 		command:   make(chan (commandType)),
+		tracer:    tracer,
+		transport: transport,
 	}
 
 	go kctx.run()
@@ -110,7 +135,7 @@ func (kc *KeycardContext) run() {
 	for cmd := range kc.command {
 		switch cmd {
 		case Transmit:
-			kc.rpdu, kc.runErr = kc.card.Transmit(kc.apdu)
+			kc.rpdu, kc.runErr = kc.transmitter().Transmit(kc.apdu)
 			kc.command <- Ack
 		case Close:
 			return
@@ -119,6 +144,10 @@ func (kc *KeycardContext) run() {
 }
 
 func (kc *KeycardContext) start() error {
+	if kc.transport != nil {
+		return nil
+	}
+
 	cardCtx, err := scard.EstablishContext()
 	if err != nil {
 		return errors.New(ErrorPCSC)
@@ -145,6 +174,12 @@ func (kc *KeycardContext) Stop() {
 }
 
 func (kc *KeycardContext) connect() error {
+	if kc.transport != nil {
+		kc.reader = TransportReader
+		kc.setChannel(io.NewNormalChannel(kc))
+		return nil
+	}
+
 	Printf("waiting for card")
 	index, err := kc.waitForCard(kc.cardCtx, kc.readers)
 	if err != nil {
@@ -179,12 +214,31 @@ func (kc *KeycardContext) connect() error {
 	}
 
 	kc.card = card
-	kc.c = io.NewNormalChannel(kc)
-	kc.cmdSet = keycard.NewCommandSet(kc.c)
+	kc.reader = reader
+	kc.setChannel(io.NewNormalChannel(kc))
 
 	return nil
 }
 
+// transmitter returns the transport given to the context, or the connected card
+func (kc *KeycardContext) transmitter() io.Transmitter {
+	if kc.transport != nil {
+		return kc.transport
+	}
+	if kc.card == nil {
+		return nil
+	}
+	return kc.card
+}
+
+// setChannel creates the command set for a new card connection
+func (kc *KeycardContext) setChannel(c types.Channel) {
+	kc.c = c
+	kc.secureChannel = newSecureChannel(c)
+	kc.cmdSet = keycard.NewCommandSet(kc.secureChannel)
+	kc.scDecoder = trace.SecureChannelDecoder{}
+}
+
 func (kc *KeycardContext) waitForCard(ctx *scard.Context, readers []string) (int, error) {
 	rs := make([]scard.ReaderState, len(readers))
 
@@ -240,12 +294,16 @@ func (kc *KeycardContext) Pair(pairingPassword string) (*types.PairingInfo, erro
 		return nil, err
 	}
 
+	kc.audit(audit.Entry{Event: audit.Paired, Details: map[string]string{
+		"index": strconv.Itoa(kc.cmdSet.PairingInfo.Index),
+	}})
+
 	return kc.cmdSet.PairingInfo, nil
 }
 
 func (kc *KeycardContext) OpenSecureChannel(index int, key []byte) error {
 	kc.cmdSet.SetPairingInfo(key, index)
-	err := kc.cmdSet.OpenSecureChannel()
+	err := kc.secureChannel.open(kc.cmdSet.PairingInfo)
 	if err != nil {
 		Printf("OpenSecureChannel failed %+v", err)
 		return err
@@ -256,6 +314,7 @@ func (kc *KeycardContext) OpenSecureChannel(index int, key []byte) error {
 
 func (kc *KeycardContext) VerifyPin(pin string) error {
 	err := kc.cmdSet.VerifyPIN(pin)
+	kc.auditPINVerification(err)
 	if err != nil {
 		Printf("VerifyPin failed %+v", err)
 		return err
@@ -266,6 +325,7 @@ func (kc *KeycardContext) VerifyPin(pin string) error {
 
 func (kc *KeycardContext) UnblockPIN(puk string, newPIN string) error {
 	err := kc.cmdSet.UnblockPIN(puk, newPIN)
+	kc.auditPINUnblock(err)
 	if err != nil {
 		Printf("UnblockPIN failed %+v", err)
 		return err
@@ -293,6 +353,9 @@ func (kc *KeycardContext) GenerateKey() ([]byte, error) {
 		return nil, err
 	}
 
+	kc.setKeyUID(keyUID)
+	kc.audit(audit.Entry{Event: audit.KeyGenerated})
+
 	return keyUID, nil
 }
 
@@ -313,6 +376,9 @@ func (kc *KeycardContext) RemoveKey() error {
 		return err
 	}
 
+	kc.audit(audit.Entry{Event: audit.KeyRemoved})
+	kc.setKeyUID(nil)
+
 	return nil
 }
 
@@ -334,10 +400,26 @@ func (kc *KeycardContext) SignWithPath(data []byte, path string) (*types.Signatu
 		return nil, err
 	}
 
+	kc.audit(audit.Entry{Event: audit.Signed, Details: map[string]string{
+		"path": path,
+		"hash": utils.Btox(data),
+	}})
+
 	return sig, nil
 }
 
+// SetExportPolicy restricts the keys exported with ExportKey
+func (kc *KeycardContext) SetExportPolicy(policy *ExportPolicy) {
+	kc.exportPolicy = policy
+}
+
 func (kc *KeycardContext) ExportKey(derive bool, makeCurrent bool, p2 uint8, path string) (*KeyPair, error) {
+	if err := kc.exportPolicy.check(path, p2); err != nil {
+		Printf("export denied: %v", err)
+		kc.auditExport(path, p2, true)
+		return nil, err
+	}
+
 	address := ""
 	exportedKey, err := kc.cmdSet.ExportKeyExtended(derive, makeCurrent, p2, path)
 	if err != nil {
@@ -345,6 +427,8 @@ func (kc *KeycardContext) ExportKey(derive bool, makeCurrent bool, p2 uint8, pat
 		return nil, err
 	}
 
+	kc.auditExport(path, p2, false)
+
 	if exportedKey.PubKey() != nil {
 		ecdsaPubKey, err := crypto.UnmarshalPubkey(exportedKey.PubKey())
 		if err != nil {
@@ -364,22 +448,47 @@ func (kc *KeycardContext) loadSeed(seed []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	kc.setKeyUID(pubKey)
+	kc.audit(audit.Entry{Event: audit.KeyLoaded})
+
 	return pubKey, nil
 }
 
-func (kc *KeycardContext) mnemonicToBinarySeed(mnemonic string, password string) []byte {
-	return pbkdf2.Key(
-		norm.NFKD.Bytes([]byte(mnemonic)),
-		norm.NFKD.Bytes([]byte(bip39Salt+password)),
-		2048, 64, sha512.New,
-	)
+// mnemonicToBinarySeed derives the BIP39 seed. The caller owns the returned buffer and must wipe it.
+// All intermediate buffers are wiped before returning.
+func (kc *KeycardContext) mnemonicToBinarySeed(mnemonic []byte, password []byte) []byte {
+	salt := make([]byte, 0, len(bip39Salt)+len(password))
+	salt = append(salt, bip39Salt...)
+	salt = append(salt, password...)
+	defer utils.Wipe(salt)
+
+	normalizedMnemonic := norm.NFKD.Bytes(mnemonic)
+	defer utils.Wipe(normalizedMnemonic)
+
+	normalizedSalt := norm.NFKD.Bytes(salt)
+	defer utils.Wipe(normalizedSalt)
+
+	return pbkdf2.Key(normalizedMnemonic, normalizedSalt, 2048, 64, sha512.New)
 }
 
-func (kc *KeycardContext) LoadMnemonic(mnemonic string, password string) ([]byte, error) {
+// loadMnemonic derives the seed and loads it to the card. The seed is wiped once LoadSeed returns.
+func (kc *KeycardContext) loadMnemonic(mnemonic []byte, password []byte) ([]byte, error) {
 	seed := kc.mnemonicToBinarySeed(mnemonic, password)
+	defer utils.Wipe(seed)
+
 	return kc.loadSeed(seed)
 }
 
+func (kc *KeycardContext) LoadMnemonic(mnemonic string, password string) ([]byte, error) {
+	mnemonicBytes := []byte(mnemonic)
+	defer utils.Wipe(mnemonicBytes)
+
+	passwordBytes := []byte(password)
+	defer utils.Wipe(passwordBytes)
+
+	return kc.loadMnemonic(mnemonicBytes, passwordBytes)
+}
+
 func (kc *KeycardContext) Init(pin, puk, pairingPassword string) error {
 	secrets := keycard.NewSecrets(pin, puk, pairingPassword)
 	err := kc.cmdSet.Init(secrets)
@@ -398,9 +507,20 @@ func (kc *KeycardContext) Unpair(index uint8) error {
 		return err
 	}
 
+	kc.audit(audit.Entry{Event: audit.Unpaired, Details: map[string]string{
+		"index": strconv.Itoa(int(index)),
+	}})
+
 	return nil
 }
 
+// setKeyUID keeps the selected applet info up to date after the key is changed
+func (kc *KeycardContext) setKeyUID(keyUID []byte) {
+	if kc.cmdSet.ApplicationInfo != nil {
+		kc.cmdSet.ApplicationInfo.KeyUID = keyUID
+	}
+}
+
 func (kc *KeycardContext) UnpairCurrent() error {
 	return kc.Unpair(uint8(kc.cmdSet.PairingInfo.Index))
 }
@@ -422,6 +542,8 @@ func (kc *KeycardContext) ChangePin(pin string) error {
 		return err
 	}
 
+	kc.audit(audit.Entry{Event: audit.PINChanged})
+
 	return nil
 }
 
@@ -432,6 +554,8 @@ func (kc *KeycardContext) ChangePuk(puk string) error {
 		return err
 	}
 
+	kc.audit(audit.Entry{Event: audit.PUKChanged})
+
 	return nil
 }
 
@@ -468,7 +592,7 @@ func (kc *KeycardContext) factoryResetFallback(retry bool) error {
 		Printf("error deleting keycard aid %+v", err)
 
 		if retry {
-			return kc.FactoryReset(false)
+			return kc.factoryReset(false)
 		} else {
 			return err
 		}
@@ -483,6 +607,18 @@ func (kc *KeycardContext) factoryResetFallback(retry bool) error {
 }
 
 func (kc *KeycardContext) FactoryReset(retry bool) error {
+	err := kc.factoryReset(retry)
+
+	entry := audit.Entry{Event: audit.FactoryReset, Details: map[string]string{"result": "ok"}}
+	if err != nil {
+		entry.Details["result"] = "failed"
+	}
+	kc.audit(entry)
+
+	return err
+}
+
+func (kc *KeycardContext) factoryReset(retry bool) error {
 	appInfo, err := kc.SelectApplet()
 
 	if err != nil || !appInfo.HasFactoryResetCapability() {
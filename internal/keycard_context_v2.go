@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ebfe/scard"
@@ -17,7 +18,10 @@ import (
 	"github.com/status-im/keycard-go/types"
 	"go.uber.org/zap"
 
+	"github.com/status-im/status-keycard-go/pkg/audit"
+	"github.com/status-im/status-keycard-go/pkg/metrics"
 	"github.com/status-im/status-keycard-go/pkg/pairing"
+	"github.com/status-im/status-keycard-go/pkg/utils"
 	"github.com/status-im/status-keycard-go/signal"
 )
 
@@ -25,20 +29,26 @@ const (
 	infiniteTimeout = -1
 	zeroTimeout     = 0
 	monitoringTick  = 500 * time.Millisecond
+
+	// routinesGracePeriod bounds the wait for routines when the shutdown context is already done
+	routinesGracePeriod = time.Second
 )
 
 var (
-	errKeycardNotConnected   = errors.New("keycard not connected")
-	errKeycardNotInitialized = errors.New("keycard not initialized")
-	errKeycardNotReady       = errors.New("keycard not ready")
-	errKeycardNotAuthorized  = errors.New("keycard not authorized")
-	errKeycardNotBlocked     = errors.New("keycard not blocked")
-	errKeycardNoKeys         = errors.New("keycard has not keys")
+	errKeycardNotConnected   = NewError(ErrorCodeNotConnected, "keycard not connected")
+	errKeycardNotInitialized = NewError(ErrorCodeNotInitialized, "keycard not initialized")
+	errKeycardNoKeys         = NewError(ErrorCodeNoKeys, "keycard has not keys")
 )
 
 type transmitRequest struct {
+	ctx             context.Context
+	transmitter     io.Transmitter
 	data            []byte
 	responseChannel chan *transmitResponse
+
+	// disconnect asks to disconnect card instead of transmitting data
+	disconnect bool
+	card       *scard.Card
 }
 
 type transmitResponse struct {
@@ -49,27 +59,61 @@ type transmitResponse struct {
 type KeycardContextV2 struct {
 	KeycardContext
 
-	shutdown   func()
+	shutdown func()
+
+	// forceScanC is created once and never reassigned, as it's used from any goroutine, see forceScan
 	forceScanC chan struct{}
 	logger     *zap.Logger
+	bus        *signal.Bus
+	metrics    *metrics.Metrics
 	pairings   *pairing.Store
-	status     *Status
+	status     *statusOwner
+
+	// readerFilter restricts the used readers, see WithReader
+	readerFilter string
+
+	// authTimer deauthorizes the keycard, see WithAuthorizationTimeouts
+	authTimer *authorizationTimer
 
 	transmitContext context.Context
 	transmitChannel chan *transmitRequest
 
 	// cmdSetMutex is needed to ensure that the last response in secure channel
 	// is parsed before attempting to send a new request.
-	cmdSetMutex *sync.Mutex
+	cmdSetMutex commandLock
+
+	// commandContext is the context of the card command in progress, see lockCommand
+	commandContext context.Context
+
+	// connected mirrors cmdSet != nil, so that it can be checked without waiting for the command in progress
+	connected atomic.Bool
 
-	// simulation options
-	simulatedError error
+	// routines tracks the card communication and monitoring goroutines
+	routines *sync.WaitGroup
+
+	// simulation is set by SimulateError from RPC calls and read by the routines
+	simulation atomic.Value // simulation
+}
+
+type simulation struct {
+	err error
 }
 
-// Transmit implements the Channel and Transmitter interfaces
+// Transmit implements the Channel and Transmitter interfaces.
+// All exchanges are recorded when tracing is enabled with WithTrace.
+// The exchange is abandoned when the context of the card command is done, see lockCommand.
 func (kc *KeycardContextV2) Transmit(apdu []byte) ([]byte, error) {
+	started := time.Now()
+
+	ctx := kc.commandContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	responseChannel := make(chan *transmitResponse, 1)
 	kc.transmitChannel <- &transmitRequest{
+		ctx:             ctx,
+		transmitter:     kc.transmitter(),
 		data:            apdu,
 		responseChannel: responseChannel,
 	}
@@ -77,7 +121,14 @@ func (kc *KeycardContextV2) Transmit(apdu []byte) ([]byte, error) {
 	select {
 	case <-kc.transmitContext.Done():
 		return nil, errors.New("transmit context done")
+	case <-ctx.Done():
+		err := contextError(ctx.Err())
+		kc.abandonCardConnection()
+		kc.observeAPDU(started, err)
+		return nil, err
 	case rpdu := <-responseChannel:
+		kc.traceAPDU(started, apdu, rpdu.data, rpdu.err)
+		kc.observeAPDU(started, rpdu.err)
 		return rpdu.data, rpdu.err
 	}
 }
@@ -86,37 +137,57 @@ func NewKeycardContextV2(options []Option) (*KeycardContextV2, error) {
 
 	kc := &KeycardContextV2{
 		transmitChannel: make(chan *transmitRequest, 10),
-		status:          NewStatus(),
-		cmdSetMutex:     &sync.Mutex{},
+		forceScanC:      make(chan struct{}, 1),
+		logger:          zap.NewNop(),
+		bus:             signal.Default,
+		metrics:         metrics.Default,
+		cmdSetMutex:     newCommandLock(),
+		authTimer:       &authorizationTimer{},
+		routines:        &sync.WaitGroup{},
 	}
 
 	for _, option := range options {
 		option(kc)
 	}
 
+	kc.status = newStatusOwner(kc.logger.Named("status"))
+
 	return kc, nil
 }
 
 func (kc *KeycardContextV2) Start() error {
-	err := kc.establishContext()
-	err = kc.simulateError(err, simulatedNoPCSC)
-	if err != nil {
-		kc.logger.Error("failed to establish context", zap.Error(err))
-		kc.status.State = NoPCSC
-		kc.publishStatus()
-		return err
+	if kc.transport == nil {
+		err := kc.establishContext()
+		err = kc.simulateError(err, simulatedNoPCSC)
+		if err != nil {
+			kc.logger.Error("failed to establish context", zap.Error(err))
+			kc.status.setState(NoPCSC)
+			kc.publishStatus()
+			return err
+		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	kc.shutdown = cancel
-	kc.forceScanC = nil
 
 	// NOTE: It is not correct to store Context, but there was no better way
 	// to pass it to `Transmit` function, which is called from the `keycard-go` package.
 	kc.transmitContext = ctx
 
+	kc.routines.Add(1)
 	go kc.cardCommunicationRoutine(ctx)
-	kc.startDetectionLoop(ctx)
+
+	if kc.transport != nil {
+		kc.routines.Add(1)
+		go kc.transportRoutine(ctx)
+	} else {
+		kc.startDetectionLoop(ctx)
+	}
+
+	if kc.authTimer.enabled() {
+		kc.routines.Add(1)
+		go kc.authorizationRoutine(ctx)
+	}
 
 	return nil
 }
@@ -140,22 +211,35 @@ func (kc *KeycardContextV2) cardCommunicationRoutine(ctx context.Context) {
 
 	defer func() {
 		kc.logger.Debug("card communication routine stopped")
-		err := kc.cardCtx.Release()
-		if err != nil {
-			kc.logger.Error("failed to release context", zap.Error(err))
-		}
+		kc.routines.Done()
 	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case apdu, ok := <-kc.transmitChannel:
+		case request, ok := <-kc.transmitChannel:
 			if !ok {
 				return
 			}
-			rpdu, err := kc.card.Transmit(apdu.data)
-			apdu.responseChannel <- &transmitResponse{
+			if request.disconnect {
+				err := request.card.Disconnect(scard.LeaveCard)
+				if err != nil {
+					kc.logger.Error("failed to disconnect card", zap.Error(err))
+				}
+				continue
+			}
+			// Skip exchanges abandoned while queued
+			if err := request.ctx.Err(); err != nil {
+				request.responseChannel <- &transmitResponse{err: err}
+				continue
+			}
+			if request.transmitter == nil {
+				request.responseChannel <- &transmitResponse{err: errKeycardNotConnected}
+				continue
+			}
+			rpdu, err := request.transmitter.Transmit(request.data)
+			request.responseChannel <- &transmitResponse{
 				data: rpdu,
 				err:  err,
 			}
@@ -170,9 +254,11 @@ func (kc *KeycardContextV2) startDetectionLoop(ctx context.Context) {
 
 	logger := kc.logger.Named("detect")
 
+	kc.routines.Add(1)
 	go func() {
 		logger.Debug("detect started")
 		defer logger.Debug("detect stopped")
+		defer kc.routines.Done()
 		// This goroutine will be stopped by cardCtx.Cancel()
 		for {
 			ok := kc.detectionRoutine(ctx, logger)
@@ -191,7 +277,7 @@ func (kc *KeycardContextV2) detectionRoutine(ctx context.Context, logger *zap.Lo
 	readers, err := kc.getCurrentReadersState()
 	if err != nil {
 		logger.Error("failed to get readers state", zap.Error(err))
-		kc.status.Reset(InternalError)
+		kc.status.reset(InternalError)
 		kc.publishStatus()
 		return false
 	}
@@ -202,6 +288,7 @@ func (kc *KeycardContextV2) detectionRoutine(ctx context.Context, logger *zap.Lo
 		if err != nil {
 			logger.Error("failed to connect keycard", zap.Error(err))
 		}
+		kc.routines.Add(1)
 		go kc.watchActiveReader(ctx, card.readerState)
 		return false
 	}
@@ -218,6 +305,9 @@ func (kc *KeycardContextV2) detectionRoutine(ctx context.Context, logger *zap.Lo
 		CurrentState: scard.StateUnaware,
 	}
 	rs := append(readers, pnpReader)
+	if ctx.Err() != nil {
+		return false
+	}
 	err = kc.cardCtx.GetStatusChange(rs, infiniteTimeout)
 	if err == scard.ErrCancelled {
 		// Shutdown requested
@@ -231,6 +321,28 @@ func (kc *KeycardContextV2) detectionRoutine(ctx context.Context, logger *zap.Lo
 	return true
 }
 
+// transportRoutine connects the keycard through the transport given with WithTransport,
+// as if it was inserted in a reader named TransportReader.
+// There is no detection: the keycard is connected once and is never considered removed.
+func (kc *KeycardContextV2) transportRoutine(ctx context.Context) {
+	logger := kc.logger.Named("transport")
+	defer kc.routines.Done()
+
+	readers := ReadersStates{{Reader: TransportReader, EventState: scard.StatePresent}}
+	card, err := kc.connectCard(ctx, readers)
+	if err != nil {
+		logger.Error("failed to connect card", zap.Error(err))
+	}
+	if card == nil {
+		return
+	}
+
+	err = kc.connectKeycard()
+	if err != nil {
+		logger.Error("failed to connect keycard", zap.Error(err))
+	}
+}
+
 type connectedCard struct {
 	readerState scard.ReaderState
 }
@@ -239,53 +351,54 @@ func (kc *KeycardContextV2) connectCard(ctx context.Context, readers ReadersStat
 	defer kc.publishStatus()
 
 	if readers.Empty() {
-		kc.status.Reset(WaitingForReader)
+		kc.status.reset(WaitingForReader)
 		return nil, nil
 	}
 
-	kc.forceScanC = make(chan struct{})
-	kc.resetCardConnection()
+	// Drop a scan forced for the previous connection
+	select {
+	case <-kc.forceScanC:
+	default:
+	}
+	kc.disconnectCard()
 
 	readerWithCardIndex, ok := readers.ReaderWithCardIndex()
 	if !ok {
 		kc.logger.Debug("no card found on any readers")
-		kc.status.Reset(WaitingForCard)
+		kc.status.reset(WaitingForCard)
 		return nil, nil
 	}
 
 	kc.logger.Debug("card found", zap.Int("index", readerWithCardIndex))
 	activeReader := readers[readerWithCardIndex]
 
-	var err error
-	kc.card, err = kc.cardCtx.Connect(activeReader.Reader, scard.ShareExclusive, scard.ProtocolAny)
-	err = kc.simulateError(err, simulatedCardConnectError)
+	err := kc.connect(activeReader.Reader)
 	if err != nil {
-		kc.status.State = ConnectionError
+		kc.status.setState(ConnectionError)
 		return nil, errors.Wrap(err, "failed to connect to card")
 	}
 
-	kc.c = io.NewNormalChannel(kc)
-	kc.cmdSet = keycard.NewCommandSet(kc.c)
-
 	// Card connected, now check if this is a keycard
 	appInfo, err := kc.selectApplet()
 	err = kc.simulateError(err, simulatedSelectAppletError)
 	if err != nil {
-		kc.resetCardConnection()
-		kc.status.State = ConnectionError
+		kc.disconnectCard()
+		kc.status.setState(ConnectionError)
 		return nil, errors.Wrap(err, "failed to select applet")
 	}
 
 	// Save AppInfo
-	kc.status.AppInfo = appInfo
+	kc.status.update(func(status *Status) {
+		status.AppInfo = appInfo
+	})
 
 	if !appInfo.Installed {
-		kc.resetCardConnection()
-		kc.status.State = NotKeycard
+		kc.disconnectCard()
+		kc.status.setState(NotKeycard)
 		return nil, nil
 	}
 
-	kc.status.State = ConnectingCard
+	kc.status.setState(ConnectingCard)
 
 	return &connectedCard{
 		readerState: activeReader,
@@ -296,6 +409,7 @@ func (kc *KeycardContextV2) watchActiveReader(ctx context.Context, activeReader
 	logger := kc.logger.Named("watch")
 	logger.Debug("watch started", zap.String("reader", activeReader.Reader))
 	defer logger.Debug("watch stopped")
+	defer kc.routines.Done()
 
 	readersStates := ReadersStates{
 		activeReader,
@@ -324,11 +438,10 @@ func (kc *KeycardContextV2) watchActiveReader(ctx context.Context, activeReader
 		// 		 This worked perfectly on MacOS, but not on Linux. So we poll the reader state instead.
 		select {
 		case <-ctx.Done():
+			return
 		case <-time.After(monitoringTick): // Pause for a while to avoid a busy loop
-		case _, ok := <-kc.forceScanC:
-			if ok {
-				kc.startDetectionLoop(ctx)
-			}
+		case <-kc.forceScanC:
+			kc.startDetectionLoop(ctx)
 			return
 		}
 	}
@@ -343,10 +456,15 @@ func (kc *KeycardContextV2) getCurrentReadersState() (ReadersStates, error) {
 		return nil, err
 	}
 
-	rs := make(ReadersStates, len(readers))
-	for i, name := range readers {
-		rs[i].Reader = name
-		rs[i].CurrentState = scard.StateUnaware
+	rs := make(ReadersStates, 0, len(readers))
+	for _, name := range readers {
+		if !strings.Contains(name, kc.readerFilter) {
+			continue
+		}
+		rs = append(rs, scard.ReaderState{
+			Reader:       name,
+			CurrentState: scard.StateUnaware,
+		})
 	}
 
 	if rs.Empty() {
@@ -375,12 +493,12 @@ func (kc *KeycardContextV2) getCurrentReadersState() (ReadersStates, error) {
 
 func (kc *KeycardContextV2) connectKeycard() error {
 	var err error
-	appInfo := kc.status.AppInfo
+	appInfo := kc.status.snapshot().AppInfo
 
 	defer kc.publishStatus()
 
 	if !appInfo.Initialized {
-		kc.status.State = EmptyKeycard
+		kc.status.setState(EmptyKeycard)
 		return nil
 	}
 
@@ -393,34 +511,36 @@ func (kc *KeycardContextV2) connectKeycard() error {
 		pairingPassword := DefPairing
 		pairingInfo, err = kc.Pair(pairingPassword)
 		if errors.Is(err, keycard.ErrNoAvailablePairingSlots) {
-			kc.status.State = NoAvailablePairingSlots
+			kc.status.setState(NoAvailablePairingSlots)
 			return err
 		}
 		if err != nil {
-			kc.status.State = PairingError
+			kc.status.setState(PairingError)
 			return errors.Wrap(err, "failed to pair keycard")
 		}
 
 		pair = pairing.ToPairInfo(pairingInfo)
 		err = kc.pairings.Store(appInfo.InstanceUID.String(), pair)
 		if err != nil {
-			kc.status.State = InternalError
+			kc.status.setState(InternalError)
 			return errors.Wrap(err, "failed to store pairing")
 		}
 
 		// After successful pairing, we should `SelectApplet` again to update the ApplicationInfo
 		appInfo, err = kc.selectApplet()
 		if err != nil {
-			kc.status.State = ConnectionError
+			kc.status.setState(ConnectionError)
 			return errors.Wrap(err, "failed to select applet")
 		}
-		kc.status.AppInfo = appInfo
+		kc.status.update(func(status *Status) {
+			status.AppInfo = appInfo
+		})
 	}
 
 	err = kc.OpenSecureChannel(pair.Index, pair.Key)
 	err = kc.simulateError(err, simulatedOpenSecureChannelError)
 	if err != nil {
-		kc.status.State = ConnectionError
+		kc.status.setState(ConnectionError)
 		return errors.Wrap(err, "failed to open secure channel")
 	}
 
@@ -429,7 +549,7 @@ func (kc *KeycardContextV2) connectKeycard() error {
 		return errors.Wrap(err, "failed to get application status")
 	}
 
-	err = kc.updateMetadata()
+	err = kc.updateMetadata(context.Background())
 	if err != nil {
 		return errors.Wrap(err, "failed to get metadata")
 	}
@@ -437,6 +557,33 @@ func (kc *KeycardContextV2) connectKeycard() error {
 	return nil
 }
 
+// connect connects the card in the given reader, or the transport given with WithTransport
+func (kc *KeycardContextV2) connect(reader string) error {
+	kc.cmdSetMutex.Lock()
+	defer kc.cmdSetMutex.Unlock()
+
+	var err error
+	if kc.transport == nil {
+		kc.card, err = kc.cardCtx.Connect(reader, scard.ShareExclusive, scard.ProtocolAny)
+	}
+	err = kc.simulateError(err, simulatedCardConnectError)
+	if err != nil {
+		return err
+	}
+
+	kc.reader = reader
+	kc.setChannel(io.NewNormalChannel(kc))
+	kc.connected.Store(true)
+	return nil
+}
+
+func (kc *KeycardContextV2) disconnectCard() {
+	kc.cmdSetMutex.Lock()
+	defer kc.cmdSetMutex.Unlock()
+	kc.resetCardConnection()
+}
+
+// resetCardConnection must be called with cmdSetMutex held
 func (kc *KeycardContextV2) resetCardConnection() {
 	if kc.card != nil {
 		err := kc.card.Disconnect(scard.LeaveCard)
@@ -447,45 +594,131 @@ func (kc *KeycardContextV2) resetCardConnection() {
 	}
 
 	kc.card = nil
+	kc.clearCardConnection()
+}
+
+// clearCardConnection forgets the connected keycard, except for the card itself
+func (kc *KeycardContextV2) clearCardConnection() {
+	kc.reader = ""
 	kc.c = nil
 	kc.cmdSet = nil
+	kc.secureChannel = nil
+	kc.connected.Store(false)
 }
 
+// forceScan makes the watch routine go back to detection. It doesn't wait for the watch routine,
+// which can be waiting for cmdSetMutex held by the caller.
 func (kc *KeycardContextV2) forceScan() {
-	kc.forceScanC <- struct{}{}
+	select {
+	case kc.forceScanC <- struct{}{}:
+	default:
+	}
 }
 
 func (kc *KeycardContextV2) publishStatus() {
-	kc.logger.Info("status changed", zap.Any("status", kc.status))
-	signal.Send("status-changed", kc.status)
+	kc.status.publish(func(status Status, transition bool) {
+		if transition {
+			kc.metrics.StateTransitions.Inc(string(status.State))
+		}
+		kc.logger.Info("status changed", zap.Object("status", &status))
+		kc.bus.Publish(StatusChangedEvent{Status: status})
+	})
 }
 
 func (kc *KeycardContextV2) Stop() {
-	if kc.forceScanC != nil {
-		close(kc.forceScanC)
+	_ = kc.Shutdown(context.Background())
+}
+
+// Shutdown stops monitoring, disconnects the card and releases the PC/SC context.
+// A card command in progress is allowed to finish until ctx is done, then it's cancelled.
+// The final `unknown` status is published once everything is stopped.
+func (kc *KeycardContextV2) Shutdown(ctx context.Context) error {
+	if kc.shutdown == nil {
+		return nil
+	}
+
+	err := kc.cmdSetMutex.LockContext(ctx)
+	if err != nil {
+		kc.logger.Warn("cancelling card command in progress")
+		err = errors.Wrap(err, "card command cancelled")
+		kc.shutdown()
+		kc.cmdSetMutex.Lock()
 	}
+	defer kc.cmdSetMutex.Unlock()
+
+	kc.resetCardConnection()
+	kc.shutdown()
+	kc.shutdown = nil
 
+	waitCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(context.Background(), routinesGracePeriod)
+		defer cancel()
+	}
+	waitErr := kc.waitRoutines(waitCtx)
+
+	// Releasing the context also fails PC/SC calls of routines that didn't stop
 	if kc.cardCtx != nil {
-		err := kc.cardCtx.Cancel()
-		if err != nil {
-			kc.logger.Error("failed to cancel context", zap.Error(err))
+		releaseErr := kc.cardCtx.Release()
+		if releaseErr != nil {
+			kc.logger.Error("failed to release context", zap.Error(releaseErr))
 		}
 	}
+	if waitErr != nil {
+		// The PC/SC context is kept, routines left behind still use it
+		kc.logger.Error("routines didn't stop", zap.Error(waitErr))
+		if err == nil {
+			err = waitErr
+		}
+	} else {
+		kc.cardCtx = nil
+	}
 
-	if kc.shutdown != nil {
-		kc.shutdown()
+	kc.status.reset(UnknownReaderState)
+	kc.publishStatus()
+
+	return err
+}
+
+// waitRoutines cancels blocking PC/SC calls until all routines are stopped or ctx is done.
+// Cancelling repeatedly covers calls started right after a cancellation.
+// A card transmission can't be cancelled, a routine stuck in it is left behind.
+func (kc *KeycardContextV2) waitRoutines(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		kc.routines.Wait()
+		close(done)
+	}()
+
+	for {
+		if kc.cardCtx != nil {
+			err := kc.cardCtx.Cancel()
+			if err != nil {
+				kc.logger.Error("failed to cancel context", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for routines")
+		case <-time.After(monitoringTick / 5):
+		}
 	}
 }
 
+// keycardConnected doesn't wait for the command in progress, lockCommand checks the connection again
 func (kc *KeycardContextV2) keycardConnected() bool {
-	return kc.cmdSet != nil
+	return kc.connected.Load()
 }
 
 func (kc *KeycardContextV2) keycardInitialized() error {
 	if !kc.keycardConnected() {
 		return errKeycardNotConnected
 	}
-	if kc.status.State == EmptyKeycard {
+	if kc.status.state() == EmptyKeycard {
 		return errKeycardNotInitialized
 	}
 	return nil
@@ -495,8 +728,12 @@ func (kc *KeycardContextV2) keycardReady() error {
 	if err := kc.keycardInitialized(); err != nil {
 		return err
 	}
-	if kc.status.State != Ready && kc.status.State != Authorized {
-		return errKeycardNotReady
+	state := kc.status.state()
+	if state == BlockedPIN || state == BlockedPUK {
+		return blockedError(state)
+	}
+	if state != Ready && state != Authorized {
+		return NewError(ErrorCodeNotReady, "keycard not ready").withState(state)
 	}
 	return nil
 }
@@ -505,14 +742,19 @@ func (kc *KeycardContextV2) keycardAuthorized() error {
 	if err := kc.keycardInitialized(); err != nil {
 		return err
 	}
-	if kc.status.State != Authorized {
-		return errKeycardNotAuthorized
+	state := kc.status.state()
+	if state == BlockedPIN || state == BlockedPUK {
+		return blockedError(state)
+	}
+	if state != Authorized {
+		return NewError(ErrorCodeNotAuthorized, "keycard not authorized").withState(state)
 	}
 	return nil
 }
 
 func (kc *KeycardContextV2) keycardHasKeys() error {
-	if !kc.status.AppStatus.KeyInitialized {
+	appStatus := kc.status.snapshot().AppStatus
+	if appStatus == nil || !appStatus.KeyInitialized {
 		return errKeycardNoKeys
 	}
 	return nil
@@ -531,7 +773,7 @@ func (kc *KeycardContextV2) checkSCardError(err error, context string) error {
 		kc.forceScan()
 	}
 
-	return err
+	return AsError(err)
 }
 
 func (kc *KeycardContextV2) selectApplet() (*ApplicationInfoV2, error) {
@@ -555,51 +797,58 @@ func (kc *KeycardContextV2) updateApplicationStatus() error {
 	defer kc.cmdSetMutex.Unlock()
 
 	appStatus, err := kc.cmdSet.GetStatusApplication()
-	kc.status.AppStatus = ToAppStatus(appStatus)
 
+	state := Ready
 	if err != nil {
-		kc.status.State = ConnectionError
-		return err
-	}
-
-	kc.status.State = Ready
-
-	if appStatus != nil {
+		state = ConnectionError
+	} else if appStatus != nil {
 		if appStatus.PinRetryCount == 0 {
-			kc.status.State = BlockedPIN
+			state = BlockedPIN
 		}
 		if appStatus.PUKRetryCount == 0 {
-			kc.status.State = BlockedPUK
+			state = BlockedPUK
 		}
 	}
 
-	return nil
+	kc.status.update(func(status *Status) {
+		status.AppStatus = ToAppStatus(appStatus)
+		status.State = state
+	})
+
+	return err
 }
 
-func (kc *KeycardContextV2) updateMetadata() error {
-	metadata, err := kc.GetMetadata()
+func (kc *KeycardContextV2) updateMetadata(ctx context.Context) error {
+	metadata, err := kc.GetMetadata(ctx)
 	if err != nil {
-		kc.status.State = ConnectionError
+		kc.status.setState(ConnectionError)
 		return err
 	}
 
-	kc.status.Metadata = metadata
+	kc.status.update(func(status *Status) {
+		status.Metadata = metadata
+	})
 	return nil
 }
 
+// GetStatus returns a snapshot of the status
 func (kc *KeycardContextV2) GetStatus() Status {
-	return *kc.status
+	return kc.status.snapshot()
 }
 
-func (kc *KeycardContextV2) Initialize(pin, puk, pairingPassword string) error {
+func (kc *KeycardContextV2) Initialize(ctx context.Context, pin, puk, pairingPassword utils.Secret) error {
 	if !kc.keycardConnected() {
 		return errKeycardNotConnected
 	}
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if err := kc.lockCommand(ctx); err != nil {
+		return err
+	}
+	defer kc.unlockCommand()
+
+	secrets := keycard.NewSecrets(string(pin.Value()), string(puk.Value()), string(pairingPassword.Value()))
+	defer utils.Wipe(secrets.PairingToken())
 
-	secrets := keycard.NewSecrets(pin, puk, pairingPassword)
 	err := kc.cmdSet.Init(secrets)
 	if err != nil {
 		return kc.checkSCardError(err, "Init")
@@ -611,108 +860,142 @@ func (kc *KeycardContextV2) Initialize(pin, puk, pairingPassword string) error {
 	return nil
 }
 
+// onAuthorizeInteractions refreshes the status after a PIN or PUK command was sent to the card.
+// It waits for the command lock, so it must be called after the command released it.
 func (kc *KeycardContextV2) onAuthorizeInteractions(authorized bool) {
 	err := kc.updateApplicationStatus()
 	if err != nil {
 		kc.logger.Error("failed to update app status", zap.Error(err))
 	}
-	if kc.status.State == Ready && authorized {
-		kc.status.State = Authorized
+	if authorized {
+		kc.authTimer.pinVerified()
+		becameAuthorized := false
+		updated := kc.status.update(func(status *Status) {
+			if status.State == Ready {
+				status.State = Authorized
+				becameAuthorized = true
+			}
+		})
+		if updated && becameAuthorized {
+			kc.authTimer.authorized()
+		}
 	}
 	kc.publishStatus()
 }
 
-func (kc *KeycardContextV2) VerifyPIN(pin string) (err error, authorized bool) {
+func (kc *KeycardContextV2) VerifyPIN(ctx context.Context, pin utils.Secret) (err error, authorized bool) {
 	if err := kc.keycardReady(); err != nil {
 		return err, false
 	}
 
+	if err := kc.lockCommand(ctx); err != nil {
+		return err, false
+	}
+
 	defer func() {
 		kc.onAuthorizeInteractions(authorized)
 	}()
+	defer kc.unlockCommand()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
-
-	err = kc.cmdSet.VerifyPIN(pin)
+	err = kc.cmdSet.VerifyPIN(string(pin.Value()))
+	kc.auditPINVerification(err)
 
 	if err == nil {
 		return nil, true
 	}
 
 	if _, ok := err.(*keycard.WrongPINError); ok {
-		return nil, false
+		kc.metrics.PINFailures.Inc()
+		return AsError(err), false
 	}
 
 	return kc.checkSCardError(err, "VerifyPIN"), false
 }
 
-func (kc *KeycardContextV2) ChangePIN(pin string) error {
+func (kc *KeycardContextV2) ChangePIN(ctx context.Context, pin utils.Secret) error {
 	if err := kc.keycardAuthorized(); err != nil {
 		return err
 	}
 
+	if err := kc.lockCommand(ctx); err != nil {
+		return err
+	}
+
 	defer func() {
 		kc.onAuthorizeInteractions(false)
 	}()
+	defer kc.unlockCommand()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
-
-	err := kc.cmdSet.ChangePIN(pin)
+	err := kc.cmdSet.ChangePIN(string(pin.Value()))
+	if err == nil {
+		kc.audit(audit.Entry{Event: audit.PINChanged})
+	}
 	return kc.checkSCardError(err, "ChangePIN")
 }
 
-func (kc *KeycardContextV2) UnblockPIN(puk string, newPIN string) (err error) {
+func (kc *KeycardContextV2) UnblockPIN(ctx context.Context, puk utils.Secret, newPIN utils.Secret) (err error) {
 	if err = kc.keycardInitialized(); err != nil {
 		return err
 	}
 
-	if kc.status.State != BlockedPIN {
-		return errKeycardNotBlocked
+	if state := kc.status.state(); state != BlockedPIN {
+		return NewError(ErrorCodeNotBlocked, "keycard not blocked").withState(state)
+	}
+
+	if err = kc.lockCommand(ctx); err != nil {
+		return err
 	}
 
 	defer func() {
 		authorized := err == nil
 		kc.onAuthorizeInteractions(authorized)
 	}()
+	defer kc.unlockCommand()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
-
-	err = kc.cmdSet.UnblockPIN(puk, newPIN)
+	err = kc.cmdSet.UnblockPIN(string(puk.Value()), string(newPIN.Value()))
+	kc.auditPINUnblock(err)
+	if _, ok := err.(*keycard.WrongPUKError); ok {
+		kc.metrics.PUKFailures.Inc()
+	}
 	return kc.checkSCardError(err, "UnblockPIN")
 }
 
-func (kc *KeycardContextV2) ChangePUK(puk string) error {
+func (kc *KeycardContextV2) ChangePUK(ctx context.Context, puk utils.Secret) error {
 	if err := kc.keycardAuthorized(); err != nil {
 		return err
 	}
 
+	if err := kc.lockCommand(ctx); err != nil {
+		return err
+	}
+
 	defer func() {
 		kc.onAuthorizeInteractions(false)
 	}()
+	defer kc.unlockCommand()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
-
-	err := kc.cmdSet.ChangePUK(puk)
+	err := kc.cmdSet.ChangePUK(string(puk.Value()))
+	if err == nil {
+		kc.audit(audit.Entry{Event: audit.PUKChanged})
+	}
 	return kc.checkSCardError(err, "ChangePUK")
 }
 
-func (kc *KeycardContextV2) GenerateMnemonic(mnemonicLength int) ([]int, error) {
+func (kc *KeycardContextV2) GenerateMnemonic(ctx context.Context, mnemonicLength int) ([]int, error) {
 	if err := kc.keycardReady(); err != nil {
 		return nil, err
 	}
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if err := kc.lockCommand(ctx); err != nil {
+		return nil, err
+	}
+	defer kc.unlockCommand()
 
 	indexes, err := kc.cmdSet.GenerateMnemonic(mnemonicLength / 3)
 	return indexes, kc.checkSCardError(err, "GenerateMnemonic")
 }
 
-func (kc *KeycardContextV2) LoadMnemonic(mnemonic string, password string) ([]byte, error) {
+func (kc *KeycardContextV2) LoadMnemonic(ctx context.Context, mnemonic utils.Secret, password utils.Secret) ([]byte, error) {
 	if err := kc.keycardAuthorized(); err != nil {
 		return nil, err
 	}
@@ -724,47 +1007,64 @@ func (kc *KeycardContextV2) LoadMnemonic(mnemonic string, password string) ([]by
 		if err != nil {
 			return
 		}
-		kc.status.AppInfo.KeyUID = keyUID
-		kc.status.AppStatus.KeyInitialized = true
+		kc.status.update(func(status *Status) {
+			if status.AppInfo != nil {
+				status.AppInfo.KeyUID = keyUID
+			}
+			if status.AppStatus != nil {
+				status.AppStatus.KeyInitialized = true
+			}
+		})
 		kc.publishStatus()
 	}()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if err = kc.lockCommand(ctx); err != nil {
+		return nil, err
+	}
+	defer kc.unlockCommand()
 
-	seed := kc.mnemonicToBinarySeed(mnemonic, password)
-	keyUID, err = kc.loadSeed(seed)
+	keyUID, err = kc.loadMnemonic(mnemonic, password)
 	return keyUID, kc.checkSCardError(err, "LoadMnemonic")
 }
 
-func (kc *KeycardContextV2) FactoryReset() error {
+func (kc *KeycardContextV2) FactoryReset(ctx context.Context) error {
 	if !kc.keycardConnected() {
 		return errKeycardNotConnected
 	}
 
-	kc.status.Reset(FactoryResetting)
-	kc.publishStatus()
+	if err := kc.lockCommand(ctx); err != nil {
+		return err
+	}
+	defer kc.unlockCommand()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if !kc.status.reset(FactoryResetting) {
+		state := kc.status.state()
+		return NewError(ErrorCodeNotReady, "keycard can't be factory reset").withState(state)
+	}
+	kc.publishStatus()
 
 	err := kc.KeycardContext.FactoryReset(true)
 
-	// Reset card connection to read the card data
-	kc.resetCardConnection()
-	kc.forceScan()
+	// Reset card connection to read the card data.
+	// An abandoned exchange has reset the connection already.
+	if kc.cmdSet != nil {
+		kc.resetCardConnection()
+		kc.forceScan()
+	}
 	return err
 }
 
-func (kc *KeycardContextV2) GetMetadata() (*Metadata, error) {
+func (kc *KeycardContextV2) GetMetadata(ctx context.Context) (*Metadata, error) {
 	if err := kc.keycardInitialized(); err != nil {
 		return nil, err
 	}
 
 	kc.logger.Debug("acquiring mutex - GetMetadata")
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if err := kc.lockCommand(ctx); err != nil {
+		return nil, err
+	}
+	defer kc.unlockCommand()
 
 	kc.logger.Debug("acquired mutex - GetMetadata")
 	defer kc.logger.Debug("finished - GetMetadata")
@@ -786,7 +1086,7 @@ func (kc *KeycardContextV2) GetMetadata() (*Metadata, error) {
 	return ToMetadata(metadata), nil
 }
 
-func (kc KeycardContextV2) parsePaths(paths []string) ([]uint32, error) {
+func (kc *KeycardContextV2) parsePaths(paths []string) ([]uint32, error) {
 	parsedPaths := make([]uint32, len(paths))
 	for i, path := range paths {
 		if !strings.HasPrefix(path, WalletRoothPath) {
@@ -804,7 +1104,7 @@ func (kc KeycardContextV2) parsePaths(paths []string) ([]uint32, error) {
 	return parsedPaths, nil
 }
 
-func (kc *KeycardContextV2) StoreMetadata(name string, paths []string) (err error) {
+func (kc *KeycardContextV2) StoreMetadata(ctx context.Context, name string, paths []string) (err error) {
 	if err = kc.keycardAuthorized(); err != nil {
 		return err
 	}
@@ -824,7 +1124,7 @@ func (kc *KeycardContextV2) StoreMetadata(name string, paths []string) (err erro
 			return
 		}
 
-		err = kc.updateMetadata()
+		err = kc.updateMetadata(context.Background())
 		if err != nil {
 			return
 		}
@@ -832,8 +1132,10 @@ func (kc *KeycardContextV2) StoreMetadata(name string, paths []string) (err erro
 		kc.publishStatus()
 	}()
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if err = kc.lockCommand(ctx); err != nil {
+		return err
+	}
+	defer kc.unlockCommand()
 
 	err = kc.cmdSet.StoreData(keycard.P1StoreDataPublic, metadata.Serialize())
 	return kc.checkSCardError(err, "StoreMetadata")
@@ -852,21 +1154,33 @@ func (kc *KeycardContextV2) exportedKeyToAddress(key *types.ExportedKey) (string
 	return crypto.PubkeyToAddress(*ecdsaPubKey).Hex(), nil
 }
 
-func (kc *KeycardContextV2) exportKey(path string, exportOption uint8) (*KeyPair, error) {
+func (kc *KeycardContextV2) exportKey(ctx context.Context, path string, exportOption uint8) (*KeyPair, error) {
 	// 1. As for today, it's pointless to use the 'current path' feature. So we always derive.
 	// 2. We keep this workaround for `makeCurrent` to mitigate a bug in an older version of the Keycard applet
 	//    that doesn't correctly export the public key for the master path unless it is also the current path.
 	const derive = true
 	makeCurrent := path == MasterPath
 
-	kc.cmdSetMutex.Lock()
-	defer kc.cmdSetMutex.Unlock()
+	if err := kc.exportPolicy.check(path, exportOption); err != nil {
+		kc.logger.Named("audit").Warn("export denied",
+			zap.String("path", path),
+			zap.String("kind", string(exportKindOf(exportOption))))
+		kc.auditExport(path, exportOption, true)
+		return nil, err
+	}
+
+	if err := kc.lockCommand(ctx); err != nil {
+		return nil, err
+	}
+	defer kc.unlockCommand()
 
 	exportedKey, err := kc.cmdSet.ExportKeyExtended(derive, makeCurrent, exportOption, path)
 	if err != nil {
 		return nil, kc.checkSCardError(err, "ExportKeyExtended")
 	}
 
+	kc.auditExport(path, exportOption, false)
+
 	address, err := kc.exportedKeyToAddress(exportedKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to convert key to address")
@@ -880,7 +1194,7 @@ func (kc *KeycardContextV2) exportKey(path string, exportOption uint8) (*KeyPair
 	}, nil
 }
 
-func (kc *KeycardContextV2) ExportLoginKeys() (*LoginKeys, error) {
+func (kc *KeycardContextV2) ExportLoginKeys(ctx context.Context) (*LoginKeys, error) {
 	if err := kc.keycardAuthorized(); err != nil {
 		return nil, err
 	}
@@ -896,12 +1210,12 @@ func (kc *KeycardContextV2) ExportLoginKeys() (*LoginKeys, error) {
 	kc.logger.Debug("acquired mutex - ExportLoginKeys")
 	defer kc.logger.Debug("finished - ExportLoginKeys")
 
-	keys.EncryptionPrivateKey, err = kc.exportKey(EncryptionPath, keycard.P2ExportKeyPrivateAndPublic)
+	keys.EncryptionPrivateKey, err = kc.exportKey(ctx, EncryptionPath, keycard.P2ExportKeyPrivateAndPublic)
 	if err != nil {
 		return nil, err
 	}
 
-	keys.WhisperPrivateKey, err = kc.exportKey(WhisperPath, keycard.P2ExportKeyPrivateAndPublic)
+	keys.WhisperPrivateKey, err = kc.exportKey(ctx, WhisperPath, keycard.P2ExportKeyPrivateAndPublic)
 	if err != nil {
 		return nil, err
 	}
@@ -909,12 +1223,12 @@ func (kc *KeycardContextV2) ExportLoginKeys() (*LoginKeys, error) {
 	return keys, err
 }
 
-func (kc *KeycardContextV2) ExportRecoverKeys() (*RecoverKeys, error) {
+func (kc *KeycardContextV2) ExportRecoverKeys(ctx context.Context) (*RecoverKeys, error) {
 	if err := kc.keycardAuthorized(); err != nil {
 		return nil, err
 	}
 
-	loginKeys, err := kc.ExportLoginKeys()
+	loginKeys, err := kc.ExportLoginKeys(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -923,7 +1237,7 @@ func (kc *KeycardContextV2) ExportRecoverKeys() (*RecoverKeys, error) {
 		LoginKeys: *loginKeys,
 	}
 
-	keys.EIP1581key, err = kc.exportKey(Eip1581Path, keycard.P2ExportKeyPublicOnly)
+	keys.EIP1581key, err = kc.exportKey(ctx, Eip1581Path, keycard.P2ExportKeyPublicOnly)
 	if err != nil {
 		return nil, err
 	}
@@ -932,18 +1246,19 @@ func (kc *KeycardContextV2) ExportRecoverKeys() (*RecoverKeys, error) {
 		true:  keycard.P2ExportKeyExtendedPublic,
 		false: keycard.P2ExportKeyPublicOnly,
 	}
-	keys.WalletRootKey, err = kc.exportKey(WalletRoothPath, rootExportOptions[kc.status.KeycardSupportsExtendedKeys()])
+	status := kc.status.snapshot()
+	keys.WalletRootKey, err = kc.exportKey(ctx, WalletRoothPath, rootExportOptions[status.KeycardSupportsExtendedKeys()])
 	if err != nil {
 		return nil, err
 	}
 
 	// NOTE: In theory, if P2ExportKeyExtendedPublic is used, then we don't need to export the wallet key separately.
-	keys.WalletKey, err = kc.exportKey(WalletPath, keycard.P2ExportKeyPublicOnly)
+	keys.WalletKey, err = kc.exportKey(ctx, WalletPath, keycard.P2ExportKeyPublicOnly)
 	if err != nil {
 		return nil, err
 	}
 
-	keys.MasterKey, err = kc.exportKey(MasterPath, keycard.P2ExportKeyPublicOnly)
+	keys.MasterKey, err = kc.exportKey(ctx, MasterPath, keycard.P2ExportKeyPublicOnly)
 	if err != nil {
 		return nil, err
 	}
@@ -959,12 +1274,13 @@ func (kc *KeycardContextV2) SimulateError(err error) error {
 		}
 	}
 
-	kc.simulatedError = err
+	kc.simulation.Store(simulation{err: err})
 	return nil
 }
 
 func (kc *KeycardContextV2) simulateError(currentError, errorToSimulate error) error {
-	if !errors.Is(kc.simulatedError, errorToSimulate) {
+	simulated, _ := kc.simulation.Load().(simulation)
+	if !errors.Is(simulated.err, errorToSimulate) {
 		return currentError
 	}
 	switch errorToSimulate {
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// authorizationTimer tracks how long the keycard has been authorized, see WithAuthorizationTimeouts
+type authorizationTimer struct {
+	lock         sync.Mutex
+	idleTimeout  time.Duration
+	maxDuration  time.Duration
+	authorizedAt time.Time
+	lastActivity time.Time
+
+	// pinVerifiedAt is the time of the last PIN verification, zero when the PIN is not verified
+	pinVerifiedAt time.Time
+}
+
+func (t *authorizationTimer) enabled() bool {
+	return t.idleTimeout > 0 || t.maxDuration > 0
+}
+
+func (t *authorizationTimer) authorized() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.authorizedAt = time.Now()
+	t.lastActivity = t.authorizedAt
+}
+
+func (t *authorizationTimer) pinVerified() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pinVerifiedAt = time.Now()
+}
+
+func (t *authorizationTimer) deauthorized() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pinVerifiedAt = time.Time{}
+}
+
+func (t *authorizationTimer) lastPINVerification() time.Time {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.pinVerifiedAt
+}
+
+// touch records a card command, which restarts the idle timeout
+func (t *authorizationTimer) touch() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lastActivity = time.Now()
+}
+
+// expired returns the reason when the authorization has expired
+func (t *authorizationTimer) expired(now time.Time) (string, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.maxDuration > 0 && now.Sub(t.authorizedAt) >= t.maxDuration {
+		return "max duration", true
+	}
+	if t.idleTimeout > 0 && now.Sub(t.lastActivity) >= t.idleTimeout {
+		return "idle", true
+	}
+	return "", false
+}
+
+// authorizationRoutine deauthorizes the keycard when the authorization expires
+func (kc *KeycardContextV2) authorizationRoutine(ctx context.Context) {
+	defer kc.routines.Done()
+
+	ticker := time.NewTicker(monitoringTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if kc.status.state() != Authorized {
+			continue
+		}
+		if _, expired := kc.authTimer.expired(time.Now()); !expired {
+			continue
+		}
+
+		err := kc.lockCommand(ctx)
+		if err != nil {
+			continue
+		}
+
+		// A command could have been executed while waiting for the lock
+		reason, expired := kc.authTimer.expired(time.Now())
+		if expired && kc.status.state() == Authorized {
+			kc.logger.Info("authorization expired", zap.String("reason", reason))
+			kc.deauthorize()
+		}
+		kc.unlockCommand()
+	}
+}
+
+// PINVerifiedAt returns the time of the last successful PIN verification, with VerifyPIN or UnblockPIN.
+// Returns zero time when the PIN is not verified in the current secure channel.
+func (kc *KeycardContextV2) PINVerifiedAt() time.Time {
+	if kc.status.state() != Authorized {
+		return time.Time{}
+	}
+	return kc.authTimer.lastPINVerification()
+}
+
+// Deauthorize resets the secure channel, so that the PIN has to be verified again.
+// Does nothing when the keycard is not authorized.
+func (kc *KeycardContextV2) Deauthorize(ctx context.Context) error {
+	if err := kc.keycardInitialized(); err != nil {
+		return err
+	}
+
+	if err := kc.lockCommand(ctx); err != nil {
+		return err
+	}
+	defer kc.unlockCommand()
+
+	if kc.status.state() != Authorized {
+		return nil
+	}
+
+	return kc.deauthorize()
+}
+
+// deauthorize selects the applet and opens a new secure channel, which resets the PIN verification.
+// When it fails, the card is connected again by the detection. Must be called with cmdSetMutex held.
+func (kc *KeycardContextV2) deauthorize() error {
+	// The PIN verification is lost either way
+	kc.authTimer.deauthorized()
+
+	err := kc.cmdSet.Select()
+	if err == nil {
+		err = kc.secureChannel.open(kc.cmdSet.PairingInfo)
+	}
+	if err != nil {
+		kc.logger.Error("failed to reset secure channel, resetting connection", zap.Error(err))
+		kc.resetCardConnection()
+		kc.forceScan()
+		kc.status.setState(ConnectionError)
+		kc.publishStatus()
+		return AsError(err)
+	}
+
+	kc.status.update(func(status *Status) {
+		if status.State == Authorized {
+			status.State = Ready
+		}
+	})
+	kc.publishStatus()
+	return nil
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+func TestAuthorizationTimerExpired(t *testing.T) {
+	authorizedAt := time.Now()
+
+	testCases := []struct {
+		name         string
+		idleTimeout  time.Duration
+		maxDuration  time.Duration
+		lastActivity time.Duration
+		now          time.Duration
+		reason       string
+	}{
+		{name: "disabled", now: time.Hour},
+		{name: "active", idleTimeout: time.Minute, maxDuration: time.Hour, lastActivity: 30 * time.Minute, now: 30*time.Minute + 59*time.Second},
+		{name: "idle", idleTimeout: time.Minute, maxDuration: time.Hour, lastActivity: 30 * time.Minute, now: 31 * time.Minute, reason: "idle"},
+		{name: "idle without max duration", idleTimeout: time.Minute, now: time.Minute, reason: "idle"},
+		{name: "max duration despite activity", idleTimeout: time.Minute, maxDuration: time.Hour, lastActivity: 59 * time.Minute, now: time.Hour, reason: "max duration"},
+		{name: "max duration without idle timeout", maxDuration: time.Hour, lastActivity: time.Hour, now: time.Hour, reason: "max duration"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			timer := &authorizationTimer{idleTimeout: tc.idleTimeout, maxDuration: tc.maxDuration}
+			timer.authorizedAt = authorizedAt
+			timer.lastActivity = authorizedAt.Add(tc.lastActivity)
+
+			reason, expired := timer.expired(authorizedAt.Add(tc.now))
+			if expired != (tc.reason != "") || reason != tc.reason {
+				t.Fatalf("expected %q, got %q (expired %v)", tc.reason, reason, expired)
+			}
+		})
+	}
+}
+
+func TestAuthorizationTimerTouch(t *testing.T) {
+	timer := &authorizationTimer{idleTimeout: time.Minute}
+	timer.authorized()
+	timer.lastActivity = timer.lastActivity.Add(-time.Hour)
+
+	if _, expired := timer.expired(time.Now()); !expired {
+		t.Fatal("expected the authorization to be expired")
+	}
+	timer.touch()
+	if _, expired := timer.expired(time.Now()); expired {
+		t.Fatal("expected a command to restart the idle timeout")
+	}
+}
+
+func TestDeauthorizeWhenNotAuthorized(t *testing.T) {
+	kc, card, _ := newTestContext(t, Ready)
+
+	err := kc.Deauthorize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.sent() != 0 {
+		t.Fatal("no command should have been sent")
+	}
+	if state := kc.GetStatus().State; state != Ready {
+		t.Fatalf("expected %s, got %s", Ready, state)
+	}
+}
+
+// TestIdleAuthorizationExpires deauthorizes through the routine. The fake card can't open
+// a new secure channel, so the connection is reset for the detection to connect the card again.
+func TestIdleAuthorizationExpires(t *testing.T) {
+	kc, card, bus := newTestContext(t, Authorized, WithAuthorizationTimeouts(10*time.Millisecond, 0))
+	kc.authTimer.authorized()
+	kc.authTimer.pinVerified()
+	subscription := bus.Subscribe(16, signal.DropOnOverflow)
+	defer subscription.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kc.routines.Add(1)
+	go kc.authorizationRoutine(ctx)
+
+	waitForState(t, subscription, ConnectionError)
+
+	if card.sent() == 0 {
+		t.Fatal("expected the applet to be selected again")
+	}
+	if kc.keycardConnected() {
+		t.Fatal("expected the card connection to be reset")
+	}
+	if !kc.authTimer.lastPINVerification().IsZero() {
+		t.Fatal("expected the PIN verification to be forgotten")
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+)
+
+// commandLock serializes card commands. Unlike sync.Mutex, it can be acquired with a context.
+type commandLock chan struct{}
+
+func newCommandLock() commandLock {
+	return make(commandLock, 1)
+}
+
+func (l commandLock) Lock() {
+	l <- struct{}{}
+}
+
+func (l commandLock) Unlock() {
+	<-l
+}
+
+// LockContext acquires the lock, or returns the context error when ctx is done first
+func (l commandLock) LockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lockCommand acquires the command set for a card command. When ctx is done, waiting for the
+// lock stops with a `timeout` error, and so does a pending exchange with the card, see Transmit.
+func (kc *KeycardContextV2) lockCommand(ctx context.Context) error {
+	err := kc.cmdSetMutex.LockContext(ctx)
+	if err != nil {
+		return contextError(err)
+	}
+
+	// The card could have been disconnected while waiting
+	if kc.cmdSet == nil {
+		kc.cmdSetMutex.Unlock()
+		return errKeycardNotConnected
+	}
+
+	kc.commandContext = ctx
+	return nil
+}
+
+func (kc *KeycardContextV2) unlockCommand() {
+	kc.authTimer.touch()
+	kc.commandContext = nil
+	kc.cmdSetMutex.Unlock()
+}
+
+// abandonCardConnection forgets the card after an exchange was abandoned halfway, as the secure
+// channel can't be used anymore. The card is disconnected by the communication routine once the
+// pending exchange is over, then connected again by the detection.
+// Must be called with cmdSetMutex held.
+func (kc *KeycardContextV2) abandonCardConnection() {
+	kc.logger.Warn("card command abandoned, resetting connection")
+
+	if kc.card != nil {
+		kc.transmitChannel <- &transmitRequest{
+			card:       kc.card,
+			disconnect: true,
+		}
+	}
+
+	kc.card = nil
+	kc.clearCardConnection()
+	kc.forceScan()
+}
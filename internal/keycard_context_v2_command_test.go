@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/status-im/keycard-go/io"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+func TestCommandTimesOutWaitingForLock(t *testing.T) {
+	kc, card, _ := newTestContext(t, Ready)
+
+	// Another command is in progress
+	kc.cmdSetMutex.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err, _ := kc.VerifyPIN(ctx, utils.Secret("123456"))
+	if AsError(err).Code != ErrorCodeTimeout {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if card.sent() != 0 {
+		t.Fatal("no command should have been sent")
+	}
+	if state := kc.GetStatus().State; state != Ready {
+		t.Fatalf("expected %s, got %s", Ready, state)
+	}
+
+	// The command proceeds once the lock is released
+	kc.cmdSetMutex.Unlock()
+	err, _ = kc.VerifyPIN(context.Background(), utils.Secret("123456"))
+	if AsError(err).Code == ErrorCodeTimeout {
+		t.Fatalf("unexpected timeout error")
+	}
+	if card.sent() == 0 {
+		t.Fatal("the command should have been sent")
+	}
+}
+
+func TestAbandonedExchangeResetsConnection(t *testing.T) {
+	kc, _, _ := newTestContext(t, Ready)
+
+	// Exchanges go through Transmit, nobody answers them as the communication routine isn't started
+	transmitContext, cancelTransmit := context.WithCancel(context.Background())
+	defer cancelTransmit()
+	kc.transmitContext = transmitContext
+	kc.setChannel(io.NewNormalChannel(kc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err, _ := kc.VerifyPIN(ctx, utils.Secret("123456"))
+	if AsError(err).Code != ErrorCodeTimeout {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+
+	// The secure channel can't be used anymore, the card is connected again by the detection
+	if kc.cmdSet != nil {
+		t.Fatal("expected the card connection to be reset")
+	}
+	select {
+	case <-kc.forceScanC:
+	default:
+		t.Fatal("expected a scan to be forced")
+	}
+
+	err, _ = kc.VerifyPIN(context.Background(), utils.Secret("123456"))
+	if AsError(err).Code != ErrorCodeNotConnected {
+		t.Fatalf("expected a not-connected error, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ebfe/scard"
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/apdu"
+)
+
+// ErrorCode is a stable, machine-readable error code of the Session API.
+// Values are in the JSON-RPC "server error" range, so they can be used as the error object code.
+type ErrorCode int
+
+const (
+	// Standard JSON-RPC 2.0 errors
+	ErrorCodeParse          ErrorCode = -32700
+	ErrorCodeInvalidRequest ErrorCode = -32600
+	ErrorCodeMethodNotFound ErrorCode = -32601
+	ErrorCodeInvalidParams  ErrorCode = -32602
+	ErrorCodeRPCInternal    ErrorCode = -32603
+
+	ErrorCodeInternal       ErrorCode = -32000
+	ErrorCodeNotStarted     ErrorCode = -32001
+	ErrorCodeAlreadyStarted ErrorCode = -32002
+	ErrorCodeNotConnected   ErrorCode = -32003
+	ErrorCodeNotInitialized ErrorCode = -32004
+	ErrorCodeNotReady       ErrorCode = -32005
+	ErrorCodeNotAuthorized  ErrorCode = -32006
+	ErrorCodeNotBlocked     ErrorCode = -32007
+	ErrorCodeNoKeys         ErrorCode = -32008
+	ErrorCodeWrongPIN       ErrorCode = -32009
+	ErrorCodeWrongPUK       ErrorCode = -32010
+	ErrorCodeBlocked        ErrorCode = -32011
+	ErrorCodeValidation     ErrorCode = -32012
+	ErrorCodeTransport      ErrorCode = -32013
+	ErrorCodeCard           ErrorCode = -32014
+	ErrorCodeForbidden      ErrorCode = -32015
+	ErrorCodeShuttingDown   ErrorCode = -32016
+	ErrorCodeTimeout        ErrorCode = -32017
+	ErrorCodeReauthRequired ErrorCode = -32018
+	ErrorCodeRejected       ErrorCode = -32019
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrorCodeParse:          "parse-error",
+	ErrorCodeInvalidRequest: "invalid-request",
+	ErrorCodeMethodNotFound: "method-not-found",
+	ErrorCodeInvalidParams:  "invalid-params",
+	ErrorCodeRPCInternal:    "rpc-internal",
+	ErrorCodeInternal:       "internal",
+	ErrorCodeNotStarted:     "not-started",
+	ErrorCodeAlreadyStarted: "already-started",
+	ErrorCodeNotConnected:   "not-connected",
+	ErrorCodeNotInitialized: "not-initialized",
+	ErrorCodeNotReady:       "not-ready",
+	ErrorCodeNotAuthorized:  "not-authorized",
+	ErrorCodeNotBlocked:     "not-blocked",
+	ErrorCodeNoKeys:         "no-keys",
+	ErrorCodeWrongPIN:       "wrong-pin",
+	ErrorCodeWrongPUK:       "wrong-puk",
+	ErrorCodeBlocked:        "blocked",
+	ErrorCodeValidation:     "validation",
+	ErrorCodeTransport:      "transport",
+	ErrorCodeCard:           "card",
+	ErrorCodeForbidden:      "forbidden",
+	ErrorCodeShuttingDown:   "shutting-down",
+	ErrorCodeTimeout:        "timeout",
+	ErrorCodeReauthRequired: "reauth-required",
+	ErrorCodeRejected:       "rejected",
+}
+
+// String returns the name of the code, e.g. `wrong-pin`
+func (c ErrorCode) String() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(c))
+}
+
+// ErrorData carries the structured details of an Error.
+// Only the fields relevant to the error code are set.
+type ErrorData struct {
+	// Type is the name of the error code, e.g. `wrong-pin`
+	Type string `json:"type"`
+
+	// RemainingAttempts is set for `wrong-pin` and `wrong-puk`
+	RemainingAttempts *int `json:"remainingAttempts,omitempty"`
+
+	// Credential is set for `blocked`, either `pin` or `puk`
+	Credential string `json:"credential,omitempty"`
+
+	// State is the current keycard state, set for `not-ready`, `not-authorized`, `not-blocked` and `blocked`
+	State State `json:"state,omitempty"`
+
+	// SW is the status word returned by the keycard, set for `card`
+	SW string `json:"sw,omitempty"`
+
+	// Fields lists the failed validation rules, set for `validation`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+// Error is a Session API error with a stable code
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Data    ErrorData
+	cause   error
+}
+
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Data:    ErrorData{Type: code.String()},
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors by code, so that `errors.Is(err, NewError(ErrorCodeWrongPIN, ""))` works
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !errors.As(target, &t) {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+func (e *Error) withState(state State) *Error {
+	e.Data.State = state
+	return e
+}
+
+func (e *Error) withCause(err error) *Error {
+	e.cause = err
+	return e
+}
+
+// AsError converts any error to an *Error.
+// Errors that are not known to the taxonomy get the `internal` code.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return contextError(err)
+	}
+
+	var wrongPIN *keycard.WrongPINError
+	if errors.As(err, &wrongPIN) {
+		e = NewError(ErrorCodeWrongPIN, wrongPIN.Error()).withCause(err)
+		e.Data.RemainingAttempts = &wrongPIN.RemainingAttempts
+		return e
+	}
+
+	var wrongPUK *keycard.WrongPUKError
+	if errors.As(err, &wrongPUK) {
+		e = NewError(ErrorCodeWrongPUK, wrongPUK.Error()).withCause(err)
+		e.Data.RemainingAttempts = &wrongPUK.RemainingAttempts
+		return e
+	}
+
+	var scardErr scard.Error
+	if errors.As(err, &scardErr) {
+		return NewError(ErrorCodeTransport, err.Error()).withCause(err)
+	}
+
+	var badResponse *apdu.ErrBadResponse
+	if errors.As(err, &badResponse) {
+		e = NewError(ErrorCodeCard, err.Error()).withCause(err)
+		e.Data.SW = fmt.Sprintf("%04x", badResponse.Sw)
+		return e
+	}
+
+	return NewError(ErrorCodeInternal, err.Error()).withCause(err)
+}
+
+// contextError converts the error of a done context to a `timeout` error
+func contextError(err error) *Error {
+	if errors.Is(err, context.Canceled) {
+		return NewError(ErrorCodeTimeout, "card command cancelled").withCause(err)
+	}
+	return NewError(ErrorCodeTimeout, "card command timed out").withCause(err)
+}
+
+func blockedError(state State) *Error {
+	e := NewError(ErrorCodeBlocked, "keycard is blocked").withState(state)
+	switch state {
+	case BlockedPIN:
+		e.Data.Credential = "pin"
+	case BlockedPUK:
+		e.Data.Credential = "puk"
+	}
+	return e
+}
+
+type errorObject struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Data    ErrorData `json:"data"`
+}
+
+// MarshalJSON encodes the error as a JSON-RPC error object
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorObject{Code: e.Code, Message: e.Message, Data: e.Data})
+}
+
+// UnmarshalJSON decodes the error from a JSON-RPC error object
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var obj errorObject
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return err
+	}
+
+	e.Code = obj.Code
+	e.Message = obj.Message
+	e.Data = obj.Data
+	return nil
+}
@@ -2,11 +2,17 @@ package internal
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/status-im/keycard-go/io"
 	"go.uber.org/zap"
 
 	"github.com/status-im/status-keycard-go/internal/logging"
+	"github.com/status-im/status-keycard-go/pkg/audit"
+	"github.com/status-im/status-keycard-go/pkg/metrics"
 	"github.com/status-im/status-keycard-go/pkg/pairing"
+	"github.com/status-im/status-keycard-go/pkg/trace"
+	"github.com/status-im/status-keycard-go/signal"
 )
 
 type Option func(*KeycardContextV2)
@@ -17,26 +23,87 @@ func WithStorage(store *pairing.Store) Option {
 	}
 }
 
-func WithLogging(enabled bool, filePath string) Option {
+// WithTrace records all APDUs exchanged with the keycard.
+func WithTrace(recorder *trace.Recorder) Option {
 	return func(k *KeycardContextV2) {
-		var logger *zap.Logger
+		k.tracer = recorder
+	}
+}
 
-		defer func() {
-			zap.ReplaceGlobals(logger)
-			k.logger = zap.L().Named("keycard")
-		}()
+// WithTransport exchanges APDUs through the given transport instead of PC/SC, e.g. to replay a trace with trace.Replayer.
+// The keycard is connected once on Start, as if inserted in a reader named TransportReader.
+func WithTransport(transport io.Transmitter) Option {
+	return func(k *KeycardContextV2) {
+		k.transport = transport
+	}
+}
 
+// WithLogging builds a logger for this context only, the global zap logger is left intact
+func WithLogging(enabled bool, filePath string) Option {
+	return func(k *KeycardContextV2) {
 		if !enabled {
-			logger = zap.NewNop()
+			k.logger = zap.NewNop()
 			return
 		}
 
-		var err error
-		logger, err = buildLogger(filePath)
-
+		logger, err := buildLogger(filePath)
 		if err != nil {
 			fmt.Printf("failed to initialize log: %v\n", err)
+			logger = zap.NewNop()
 		}
+		k.logger = logger.Named("keycard")
+	}
+}
+
+// WithLogger uses the given logger, instead of building one with WithLogging
+func WithLogger(logger *zap.Logger) Option {
+	return func(k *KeycardContextV2) {
+		k.logger = logger.Named("keycard")
+	}
+}
+
+// WithSignalBus publishes status changes to the given bus instead of signal.Default
+func WithSignalBus(bus *signal.Bus) Option {
+	return func(k *KeycardContextV2) {
+		k.bus = bus
+	}
+}
+
+// WithMetrics records the context metrics to the given metrics instead of metrics.Default
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(k *KeycardContextV2) {
+		k.metrics = m
+	}
+}
+
+// WithReader only uses readers which name contains the given string, e.g. to run a context per reader.
+// All readers are used when empty.
+func WithReader(reader string) Option {
+	return func(k *KeycardContextV2) {
+		k.readerFilter = reader
+	}
+}
+
+// WithAuthorizationTimeouts deauthorizes the keycard when no command was executed for idleTimeout,
+// or when it has been authorized for maxDuration. Zero disables the corresponding timeout.
+func WithAuthorizationTimeouts(idleTimeout, maxDuration time.Duration) Option {
+	return func(k *KeycardContextV2) {
+		k.authTimer.idleTimeout = idleTimeout
+		k.authTimer.maxDuration = maxDuration
+	}
+}
+
+// WithExportPolicy restricts the derivation paths which keys can be exported for
+func WithExportPolicy(policy *ExportPolicy) Option {
+	return func(k *KeycardContextV2) {
+		k.exportPolicy = policy
+	}
+}
+
+// WithAuditLog records security-relevant card operations to the given audit log
+func WithAuditLog(log *audit.Log) Option {
+	return func(k *KeycardContextV2) {
+		k.auditLog = log
 	}
 }
 
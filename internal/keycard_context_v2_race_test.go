@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ebfe/scard"
+
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// TestConcurrentDetection runs the detection routine alongside RPC-side calls.
+// It's meant to be run with -race.
+func TestConcurrentDetection(t *testing.T) {
+	kc, _, bus := newTestContext(t, WaitingForReader)
+	subscription := bus.Subscribe(16, signal.DropOnOverflow)
+	defer subscription.Close()
+
+	readers := ReadersStates{{Reader: "reader", EventState: scard.StateEmpty}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const iterations = 200
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				f(i)
+			}
+		}()
+	}
+
+	run(func(int) {
+		_, err := kc.connectCard(ctx, readers)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) {
+		select {
+		case <-kc.forceScanC:
+		default:
+		}
+	})
+	run(func(int) { kc.forceScan() })
+	run(func(i int) {
+		errorToSimulate := simulatedSelectAppletError
+		if i%2 == 0 {
+			errorToSimulate = nil
+		}
+		if err := kc.SimulateError(errorToSimulate); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) { _ = kc.simulateError(nil, simulatedSelectAppletError) })
+	run(func(int) { _ = kc.GetStatus() })
+	run(func(int) {
+		select {
+		case <-subscription.Events():
+		default:
+		}
+	})
+
+	wg.Wait()
+
+	if state := kc.GetStatus().State; state != WaitingForCard {
+		t.Fatalf("expected %s, got %s", WaitingForCard, state)
+	}
+}
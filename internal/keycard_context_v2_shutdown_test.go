@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebfe/scard"
+)
+
+func TestShutdownLeavesStuckRoutines(t *testing.T) {
+	kc, _, _ := newTestContext(t, WaitingForCard)
+	kc.cardCtx = &scard.Context{}
+	kc.shutdown = func() {}
+
+	// A routine stuck in a card transmission, which PC/SC can't cancel
+	stuck := make(chan struct{})
+	defer close(stuck)
+	kc.routines.Add(1)
+	go func() {
+		defer kc.routines.Done()
+		<-stuck
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- kc.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error for the stuck routine")
+		}
+	case <-time.After(routinesGracePeriod + time.Second):
+		t.Fatal("shutdown didn't return when ctx was done")
+	}
+
+	if state := kc.GetStatus().State; state != UnknownReaderState {
+		t.Fatalf("expected %s, got %s", UnknownReaderState, state)
+	}
+}
+
+func TestShutdownWaitsForRoutines(t *testing.T) {
+	kc, _, _ := newTestContext(t, WaitingForCard)
+	kc.cardCtx = &scard.Context{}
+
+	stopped := false
+	routineCtx, stop := context.WithCancel(context.Background())
+	kc.shutdown = stop
+	kc.routines.Add(1)
+	go func() {
+		defer kc.routines.Done()
+		<-routineCtx.Done()
+		time.Sleep(10 * time.Millisecond)
+		stopped = true
+	}()
+
+	err := kc.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stopped {
+		t.Fatal("shutdown returned before the routine stopped")
+	}
+	if kc.cardCtx != nil {
+		t.Fatal("PC/SC context not released")
+	}
+}
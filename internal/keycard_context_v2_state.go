@@ -2,6 +2,11 @@ package internal
 
 import (
 	"errors"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/status-im/status-keycard-go/signal"
 )
 
 type State string
@@ -70,11 +75,48 @@ const (
 	FactoryResetting State = "factory-resetting"
 )
 
+// Status is a snapshot of the session status.
+// Version increases with each change, Timestamp is the time of the change.
 type Status struct {
 	State     State              `json:"state"`
 	AppInfo   *ApplicationInfoV2 `json:"keycardInfo"`
 	AppStatus *ApplicationStatus `json:"keycardStatus"`
 	Metadata  *Metadata          `json:"metadata"`
+	Version   uint64             `json:"version"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+// Status holds no secrets, but logging it explicitly keeps it off the reflection-based JSON path.
+func (s *Status) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("state", string(s.State))
+	enc.AddUint64("version", s.Version)
+	if s.AppInfo != nil {
+		err := enc.AddReflected("keycardInfo", s.AppInfo)
+		if err != nil {
+			return err
+		}
+	}
+	if s.AppStatus != nil {
+		err := enc.AddReflected("keycardStatus", s.AppStatus)
+		if err != nil {
+			return err
+		}
+	}
+	if s.Metadata != nil {
+		return enc.AddReflected("metadata", s.Metadata)
+	}
+	return nil
+}
+
+// StatusChangedEvent is published with each status change, as the `status-changed` signal.
+// The status is a snapshot shared by all subscribers, it must not be modified.
+type StatusChangedEvent struct {
+	Status
+}
+
+func (e StatusChangedEvent) SignalType() string {
+	return signal.StatusChanged
 }
 
 func NewStatus() *Status {
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// statusOwner serializes all status changes and publications.
+// Each change increments the status version. Readers get deep copies, so a snapshot
+// never changes after it was taken, and callers can't change the owned status.
+// State changes are validated against the transition table, see CanTransition.
+type statusOwner struct {
+	logger *zap.Logger
+	lock   sync.Mutex
+	status Status
+
+	// publishLock serializes publications. lock is only taken for the snapshot,
+	// so that publishing doesn't block status changes and readers.
+	publishLock    sync.Mutex
+	publishedState State
+}
+
+func newStatusOwner(logger *zap.Logger) *statusOwner {
+	o := &statusOwner{logger: logger}
+	o.status.Reset(UnknownReaderState)
+	o.status.Timestamp = time.Now()
+	return o
+}
+
+// update applies the change and increments the version.
+// A change to a state not reachable from the current one is logged and dropped.
+func (o *statusOwner) update(change func(status *Status)) bool {
+	o.lock.Lock()
+
+	status := o.status.clone()
+	change(&status)
+
+	from := o.status.State
+	if !CanTransition(from, status.State) {
+		o.lock.Unlock()
+		o.logger.Error("illegal state transition",
+			zap.String("from", string(from)),
+			zap.String("to", string(status.State)))
+		return false
+	}
+
+	status.Version = o.status.Version + 1
+	status.Timestamp = time.Now()
+	o.status = status
+	o.lock.Unlock()
+	return true
+}
+
+func (o *statusOwner) setState(state State) bool {
+	return o.update(func(status *Status) {
+		status.State = state
+	})
+}
+
+func (o *statusOwner) reset(state State) bool {
+	return o.update(func(status *Status) {
+		status.Reset(state)
+	})
+}
+
+func (o *statusOwner) state() State {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.status.State
+}
+
+func (o *statusOwner) snapshot() Status {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.status.clone()
+}
+
+// publish passes a snapshot to the given function. Calls are serialized, so that
+// snapshots are published in version order. transition is true when the state
+// differs from the previously published one.
+// The status lock is not held while publishing, so the function can read the status.
+func (o *statusOwner) publish(publish func(status Status, transition bool)) {
+	o.publishLock.Lock()
+	defer o.publishLock.Unlock()
+
+	status := o.snapshot()
+	transition := status.State != o.publishedState
+	o.publishedState = status.State
+	publish(status, transition)
+}
+
+// clone returns a deep copy of the status
+func (s *Status) clone() Status {
+	c := *s
+
+	if s.AppInfo != nil {
+		appInfo := *s.AppInfo
+		appInfo.InstanceUID = bytes.Clone(s.AppInfo.InstanceUID)
+		appInfo.KeyUID = bytes.Clone(s.AppInfo.KeyUID)
+		c.AppInfo = &appInfo
+	}
+
+	if s.AppStatus != nil {
+		appStatus := *s.AppStatus
+		c.AppStatus = &appStatus
+	}
+
+	if s.Metadata != nil {
+		metadata := *s.Metadata
+		metadata.Wallets = make([]Wallet, len(s.Metadata.Wallets))
+		for i, wallet := range s.Metadata.Wallets {
+			metadata.Wallets[i] = wallet
+			metadata.Wallets[i].PublicKey = bytes.Clone(wallet.PublicKey)
+		}
+		if s.Metadata.Wallets == nil {
+			metadata.Wallets = nil
+		}
+		c.Metadata = &metadata
+	}
+
+	return c
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:generate go run ../cmd/keycard-states -o ../api/states.dot
+
+// detectionStates are set by the detection routine, which runs again whenever the card
+// connection is lost or reset, whatever the state was.
+var detectionStates = []State{
+	WaitingForReader,
+	WaitingForCard,
+	ConnectingCard,
+	ConnectionError,
+	NotKeycard,
+	InternalError,
+}
+
+// transitions lists the allowed state changes, besides staying in the same state and
+// going back to UnknownReaderState when the monitoring is stopped.
+// FactoryResetting is reachable from any state in which the card can still be connected,
+// i.e. after the applet was selected, as FactoryReset only requires a connected card.
+var transitions = map[State][]State{
+	UnknownReaderState:      append([]State{NoPCSC}, detectionStates...),
+	NoPCSC:                  nil,
+	InternalError:           append([]State{FactoryResetting}, detectionStates...),
+	WaitingForReader:        detectionStates,
+	WaitingForCard:          detectionStates,
+	ConnectionError:         append([]State{FactoryResetting}, detectionStates...),
+	NotKeycard:              detectionStates,
+	ConnectingCard:          append([]State{EmptyKeycard, NoAvailablePairingSlots, PairingError, Ready, BlockedPIN, BlockedPUK, FactoryResetting}, detectionStates...),
+	EmptyKeycard:            append([]State{FactoryResetting}, detectionStates...),
+	NoAvailablePairingSlots: append([]State{FactoryResetting}, detectionStates...),
+	PairingError:            append([]State{FactoryResetting}, detectionStates...),
+	Ready:                   append([]State{Authorized, BlockedPIN, BlockedPUK, FactoryResetting}, detectionStates...),
+	Authorized:              append([]State{Ready, BlockedPIN, BlockedPUK, FactoryResetting}, detectionStates...),
+	BlockedPIN:              append([]State{Ready, BlockedPUK, FactoryResetting}, detectionStates...),
+	BlockedPUK:              append([]State{FactoryResetting}, detectionStates...),
+	FactoryResetting:        detectionStates,
+}
+
+// CanTransition reports whether the state can change from `from` to `to`
+func CanTransition(from, to State) bool {
+	if from == to || to == UnknownReaderState {
+		return true
+	}
+	for _, state := range transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transitions returns a copy of the transition table.
+// Staying in the same state, and going to UnknownReaderState, is allowed from any state.
+func Transitions() map[State][]State {
+	table := make(map[State][]State, len(transitions))
+	for from, to := range transitions {
+		table[from] = append([]State(nil), to...)
+	}
+	return table
+}
+
+// TransitionsDot renders the transition table as a Graphviz digraph
+func TransitionsDot() string {
+	states := make([]string, 0, len(transitions))
+	for state := range transitions {
+		states = append(states, string(state))
+	}
+	sort.Strings(states)
+
+	var b strings.Builder
+	b.WriteString("// Code generated by cmd/keycard-states. DO NOT EDIT.\n")
+	b.WriteString("digraph keycard_states {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	fmt.Fprintf(&b, "  %q [style=\"rounded,bold\"];\n", UnknownReaderState)
+	b.WriteString("  \"*\" [shape=point];\n")
+	fmt.Fprintf(&b, "  \"*\" -> %q [style=dashed, label=\"Stop\"];\n", UnknownReaderState)
+
+	for _, from := range states {
+		to := transitions[State(from)]
+		targets := make([]string, 0, len(to))
+		for _, state := range to {
+			targets = append(targets, string(state))
+		}
+		sort.Strings(targets)
+		for _, target := range targets {
+			fmt.Fprintf(&b, "  %q -> %q;\n", from, target)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,372 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ebfe/scard"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/io"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-keycard-go/pkg/pairing"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+var allStates = []State{
+	UnknownReaderState,
+	NoPCSC,
+	InternalError,
+	WaitingForReader,
+	WaitingForCard,
+	ConnectingCard,
+	ConnectionError,
+	NotKeycard,
+	EmptyKeycard,
+	NoAvailablePairingSlots,
+	PairingError,
+	BlockedPIN,
+	BlockedPUK,
+	Ready,
+	Authorized,
+	FactoryResetting,
+}
+
+// expectedTransitions is written out independently of the transition table,
+// so that any change to the table has to be reflected here.
+var expectedTransitions = map[State][]State{
+	UnknownReaderState:      {NoPCSC, WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError},
+	NoPCSC:                  {},
+	InternalError:           {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, FactoryResetting},
+	WaitingForReader:        {WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError},
+	WaitingForCard:          {WaitingForReader, ConnectingCard, ConnectionError, NotKeycard, InternalError},
+	ConnectingCard:          {WaitingForReader, WaitingForCard, ConnectionError, NotKeycard, InternalError, EmptyKeycard, NoAvailablePairingSlots, PairingError, Ready, BlockedPIN, BlockedPUK, FactoryResetting},
+	ConnectionError:         {WaitingForReader, WaitingForCard, ConnectingCard, NotKeycard, InternalError, FactoryResetting},
+	NotKeycard:              {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, InternalError},
+	EmptyKeycard:            {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, FactoryResetting},
+	NoAvailablePairingSlots: {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, FactoryResetting},
+	PairingError:            {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, FactoryResetting},
+	BlockedPIN:              {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, Ready, BlockedPUK, FactoryResetting},
+	BlockedPUK:              {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, FactoryResetting},
+	Ready:                   {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, Authorized, BlockedPIN, BlockedPUK, FactoryResetting},
+	Authorized:              {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError, Ready, BlockedPIN, BlockedPUK, FactoryResetting},
+	FactoryResetting:        {WaitingForReader, WaitingForCard, ConnectingCard, ConnectionError, NotKeycard, InternalError},
+}
+
+func TestCanTransition(t *testing.T) {
+	if len(Transitions()) != len(allStates) {
+		t.Fatalf("transition table has %d states, expected %d", len(Transitions()), len(allStates))
+	}
+
+	for _, from := range allStates {
+		allowed := map[State]bool{from: true, UnknownReaderState: true}
+		for _, to := range expectedTransitions[from] {
+			allowed[to] = true
+		}
+
+		for _, to := range allStates {
+			if got := CanTransition(from, to); got != allowed[to] {
+				t.Errorf("CanTransition(%s, %s) = %v, expected %v", from, to, got, allowed[to])
+			}
+		}
+	}
+}
+
+func TestStatusOwnerRejectsIllegalTransitions(t *testing.T) {
+	for _, from := range allStates {
+		for _, to := range allStates {
+			owner := newStatusOwner(zap.NewNop())
+			owner.status.State = from
+			version := owner.status.Version
+
+			applied := owner.setState(to)
+			if applied != CanTransition(from, to) {
+				t.Errorf("setState(%s -> %s) = %v", from, to, applied)
+			}
+
+			expectedState, expectedVersion := from, version
+			if applied {
+				expectedState, expectedVersion = to, version+1
+			}
+			status := owner.snapshot()
+			if status.State != expectedState || status.Version != expectedVersion {
+				t.Errorf("%s -> %s: got state %s version %d, expected %s version %d",
+					from, to, status.State, status.Version, expectedState, expectedVersion)
+			}
+		}
+	}
+}
+
+func TestFactoryResetStates(t *testing.T) {
+	tests := []struct {
+		state   State
+		allowed bool
+	}{
+		{ConnectingCard, true},
+		{ConnectionError, true},
+		{InternalError, true},
+		{EmptyKeycard, true},
+		{NoAvailablePairingSlots, true},
+		{PairingError, true},
+		{Ready, true},
+		{Authorized, true},
+		{BlockedPIN, true},
+		{BlockedPUK, true},
+		{WaitingForCard, false},
+		{NotKeycard, false},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.state), func(t *testing.T) {
+			kc, card, bus := newTestContext(t, test.state)
+			subscription := bus.Subscribe(16, signal.DropOnOverflow)
+			defer subscription.Close()
+
+			// The fake card rejects all commands, so the reset itself fails
+			err := kc.FactoryReset(context.Background())
+			if err == nil {
+				t.Fatal("expected an error from the fake card")
+			}
+
+			var rpcErr *Error
+			refused := errors.As(err, &rpcErr) && rpcErr.Code == ErrorCodeNotReady
+			if refused == test.allowed {
+				t.Fatalf("refused = %v, error: %v", refused, err)
+			}
+
+			if test.allowed {
+				waitForState(t, subscription, FactoryResetting)
+				if card.sent() == 0 {
+					t.Fatal("no command sent to the card")
+				}
+			} else if card.sent() != 0 {
+				t.Fatalf("%d commands sent to the card in %s state", card.sent(), test.state)
+			}
+		})
+	}
+}
+
+var (
+	swOK                   = []byte{0x90, 0x00}
+	swFileNotFound         = []byte{0x6A, 0x82}
+	swNoAvailableSlots     = []byte{0x6A, 0x84}
+	swSecurityNotSatisfied = []byte{0x69, 0x82}
+)
+
+func TestConnectionTransitions(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	instanceUID := bytes.Repeat([]byte{0x01}, 16)
+
+	secureCard := func(responses map[byte][]byte) io.Transmitter {
+		return &fakeSecureCard{key: cardKey, instanceUID: instanceUID, responses: responses}
+	}
+	queuedCard := func(responses ...[]byte) io.Transmitter {
+		return &fakeCard{responses: responses}
+	}
+
+	tests := []struct {
+		name      string
+		readers   ReadersStates
+		card      io.Transmitter
+		paired    bool
+		connected State
+		state     State
+	}{
+		{
+			name:    "no reader",
+			readers: ReadersStates{},
+			card:    queuedCard(),
+			state:   WaitingForReader,
+		},
+		{
+			name:    "no card",
+			readers: ReadersStates{{Reader: TransportReader, EventState: scard.StateEmpty}},
+			card:    queuedCard(),
+			state:   WaitingForCard,
+		},
+		{
+			name:  "selection fails",
+			card:  queuedCard(swInsNotSupported),
+			state: ConnectionError,
+		},
+		{
+			name:  "not a keycard",
+			card:  queuedCard(swFileNotFound),
+			state: NotKeycard,
+		},
+		{
+			name:      "empty keycard",
+			card:      queuedCard(selectResponse(nil, &cardKey.PublicKey)),
+			connected: ConnectingCard,
+			state:     EmptyKeycard,
+		},
+		{
+			name:      "no available pairing slots",
+			card:      queuedCard(selectResponse(instanceUID, &cardKey.PublicKey), swNoAvailableSlots),
+			connected: ConnectingCard,
+			state:     NoAvailablePairingSlots,
+		},
+		{
+			name:      "pairing fails",
+			card:      queuedCard(selectResponse(instanceUID, &cardKey.PublicKey), swSecurityNotSatisfied),
+			connected: ConnectingCard,
+			state:     PairingError,
+		},
+		{
+			name:      "secure channel fails",
+			card:      queuedCard(selectResponse(instanceUID, &cardKey.PublicKey), swSecurityNotSatisfied),
+			paired:    true,
+			connected: ConnectingCard,
+			state:     ConnectionError,
+		},
+		{
+			name:      "status fails",
+			card:      secureCard(map[byte][]byte{keycard.InsGetStatus: swSecurityNotSatisfied}),
+			paired:    true,
+			connected: ConnectingCard,
+			state:     ConnectionError,
+		},
+		{
+			name:      "ready",
+			card:      secureCard(map[byte][]byte{keycard.InsGetStatus: statusResponse(3, 5)}),
+			paired:    true,
+			connected: ConnectingCard,
+			state:     Ready,
+		},
+		{
+			name:      "blocked PIN",
+			card:      secureCard(map[byte][]byte{keycard.InsGetStatus: statusResponse(0, 5)}),
+			paired:    true,
+			connected: ConnectingCard,
+			state:     BlockedPIN,
+		},
+		{
+			name:      "blocked PUK",
+			card:      secureCard(map[byte][]byte{keycard.InsGetStatus: statusResponse(0, 0)}),
+			paired:    true,
+			connected: ConnectingCard,
+			state:     BlockedPUK,
+		},
+		{
+			name:      "metadata fails",
+			card:      secureCard(map[byte][]byte{keycard.InsGetStatus: statusResponse(3, 5), keycard.InsGetData: swSecurityNotSatisfied}),
+			paired:    true,
+			connected: ConnectingCard,
+			state:     ConnectionError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := pairing.NewStore(filepath.Join(t.TempDir(), "pairings.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.paired {
+				err = store.Store(hex.EncodeToString(instanceUID), &pairing.Info{Key: testPairingKey, Index: 0})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			kc, _ := newTransportContext(t, test.card, WithStorage(store))
+			kc.status.status.State = WaitingForCard
+
+			readers := test.readers
+			if readers == nil {
+				readers = ReadersStates{{Reader: TransportReader, EventState: scard.StatePresent}}
+			}
+
+			card, _ := kc.connectCard(context.Background(), readers)
+			if (card != nil) != (test.connected != "") {
+				t.Fatalf("expected connected %v, got %v", test.connected != "", card != nil)
+			}
+			if card != nil {
+				if state := kc.status.state(); state != test.connected {
+					t.Fatalf("expected %s after connectCard, got %s", test.connected, state)
+				}
+				_ = kc.connectKeycard()
+			}
+
+			if state := kc.status.state(); state != test.state {
+				t.Fatalf("expected %s, got %s", test.state, state)
+			}
+		})
+	}
+}
+
+func TestUpdateApplicationStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     State
+		response []byte
+		state    State
+	}{
+		{"ready", Ready, statusResponse(3, 5), Ready},
+		{"PIN retries left", Ready, statusResponse(1, 5), Ready},
+		{"PIN blocked", Ready, statusResponse(0, 5), BlockedPIN},
+		{"PUK blocked", Ready, statusResponse(0, 0), BlockedPUK},
+		{"authorization lost", Authorized, statusResponse(3, 5), Ready},
+		{"PIN unblocked", BlockedPIN, statusResponse(3, 5), Ready},
+		{"card error", Ready, swSecurityNotSatisfied, ConnectionError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kc, card, _ := newTestContext(t, test.from)
+			card.responses = [][]byte{test.response}
+
+			err := kc.updateApplicationStatus()
+			if (err != nil) != (test.state == ConnectionError) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if state := kc.status.state(); state != test.state {
+				t.Fatalf("expected %s, got %s", test.state, state)
+			}
+		})
+	}
+}
+
+func TestOnAuthorizeInteractionsTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       State
+		response   []byte
+		authorized bool
+		state      State
+	}{
+		{"PIN verified", Ready, statusResponse(3, 5), true, Authorized},
+		{"wrong PIN", Ready, statusResponse(2, 5), false, Ready},
+		{"PIN blocked", Ready, statusResponse(0, 5), false, BlockedPIN},
+		{"already authorized", Authorized, statusResponse(3, 5), true, Authorized},
+		{"wrong PIN when authorized", Authorized, statusResponse(2, 5), false, Ready},
+		{"PIN unblocked", BlockedPIN, statusResponse(3, 5), true, Authorized},
+		{"wrong PUK", BlockedPIN, statusResponse(0, 4), false, BlockedPIN},
+		{"PUK blocked", BlockedPIN, statusResponse(0, 0), false, BlockedPUK},
+		{"card error", Ready, swSecurityNotSatisfied, true, ConnectionError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kc, card, bus := newTestContext(t, test.from)
+			card.responses = [][]byte{test.response}
+			subscription := bus.Subscribe(16, signal.DropOnOverflow)
+			defer subscription.Close()
+
+			kc.onAuthorizeInteractions(test.authorized)
+
+			if state := kc.status.state(); state != test.state {
+				t.Fatalf("expected %s, got %s", test.state, state)
+			}
+			waitForState(t, subscription, test.state)
+		})
+	}
+}
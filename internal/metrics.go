@@ -0,0 +1,14 @@
+package internal
+
+import (
+	"time"
+)
+
+func (kc *KeycardContextV2) observeAPDU(started time.Time, err error) {
+	kc.metrics.APDUDuration.Observe(time.Since(started))
+	if err != nil {
+		kc.metrics.APDUs.Inc("error")
+	} else {
+		kc.metrics.APDUs.Inc("ok")
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/types"
+)
+
+var errInvalidOpenSecureChannelResponse = errors.New("invalid open secure channel response")
+
+// secureChannel is the channel given to the keycard command set. It opens the keycard secure channel itself,
+// instead of the command set, so that the session encryption key is known to decrypt the trace.
+// Commands the command set sends in plain are passed through, all others are encrypted once the channel is open.
+type secureChannel struct {
+	c      types.Channel
+	sc     *keycard.SecureChannel
+	encKey []byte
+}
+
+func newSecureChannel(c types.Channel) *secureChannel {
+	return &secureChannel{
+		c:  c,
+		sc: keycard.NewSecureChannel(c),
+	}
+}
+
+// Send implements types.Channel
+func (s *secureChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	if isSelect(cmd) {
+		return s.sendSelect(cmd)
+	}
+
+	if isPlain(cmd) {
+		return s.c.Send(cmd)
+	}
+
+	return s.sc.Send(cmd)
+}
+
+// sendSelect closes the secure channel, selecting the applet always closes it on the card.
+func (s *secureChannel) sendSelect(cmd *apdu.Command) (*apdu.Response, error) {
+	s.close()
+
+	resp, err := s.c.Send(cmd)
+	if err != nil || resp.Sw != apdu.SwOK || len(resp.Data) == 0 {
+		return resp, err
+	}
+
+	// The command set reports invalid application info itself
+	appInfo, err := types.ParseApplicationInfo(resp.Data)
+	if err == nil && appInfo.HasSecureChannelCapability() {
+		err = s.sc.GenerateSecret(appInfo.SecureChannelPublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// open opens the secure channel with the given pairing, as keycard.CommandSet.OpenSecureChannel does.
+func (s *secureChannel) open(pairing *types.PairingInfo) error {
+	if pairing == nil {
+		return errors.New("cannot open secure channel without setting PairingInfo")
+	}
+
+	s.close()
+
+	cmd := keycard.NewCommandOpenSecureChannel(uint8(pairing.Index), s.sc.RawPublicKey())
+	resp, err := s.c.Send(cmd)
+	if err = checkOK(resp, err); err != nil {
+		return err
+	}
+
+	if len(resp.Data) < 48 {
+		return errInvalidOpenSecureChannelResponse
+	}
+
+	encKey, macKey, iv := crypto.DeriveSessionKeys(s.sc.Secret(), pairing.Key, resp.Data)
+	s.sc.Init(iv, encKey, macKey)
+	s.encKey = encKey
+
+	challenge := make([]byte, 32)
+	if _, err = rand.Read(challenge); err != nil {
+		return err
+	}
+
+	resp, err = s.sc.Send(keycard.NewCommandMutuallyAuthenticate(challenge))
+	return checkOK(resp, err)
+}
+
+func (s *secureChannel) close() {
+	s.sc.Reset()
+	s.encKey = nil
+}
+
+// encryptionKey returns the session encryption key, or nil if the secure channel isn't open.
+func (s *secureChannel) encryptionKey() []byte {
+	if s == nil {
+		return nil
+	}
+	return s.encKey
+}
+
+func isSelect(cmd *apdu.Command) bool {
+	return cmd.Cla == globalplatform.ClaISO7816 && cmd.Ins == globalplatform.InsSelect
+}
+
+// isPlain returns true for the commands keycard.CommandSet never sends through the secure channel
+func isPlain(cmd *apdu.Command) bool {
+	switch cmd.Ins {
+	case keycard.InsInit, keycard.InsPair, keycard.InsOpenSecureChannel, keycard.InsFactoryReset:
+		return true
+	case keycard.InsSign:
+		return cmd.P1 == keycard.P1SignPinless
+	}
+	return false
+}
+
+func checkOK(resp *apdu.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if resp.Sw != apdu.SwOK {
+		return apdu.NewErrBadResponse(resp.Sw, "unexpected response")
+	}
+
+	return nil
+}
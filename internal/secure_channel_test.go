@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/io"
+
+	"github.com/status-im/status-keycard-go/pkg/trace"
+)
+
+var testPairingKey = bytes.Repeat([]byte{0x42}, 32)
+
+// fakeSecureCard is a keycard which opens a secure channel with testPairingKey.
+// Commands in the secure channel are answered with the response queued for their instruction, or 9000.
+// The card is pre-initialized unless instanceUID is set.
+type fakeSecureCard struct {
+	key         *ecdsa.PrivateKey
+	instanceUID []byte
+	responses   map[byte][]byte
+	encKey      []byte
+	macKey      []byte
+	iv          []byte
+}
+
+func newFakeSecureCard(t *testing.T) *fakeSecureCard {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeSecureCard{key: key}
+}
+
+func (c *fakeSecureCard) Transmit(raw []byte) ([]byte, error) {
+	cmd, err := apdu.ParseCommand(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cmd.Cla == globalplatform.ClaISO7816 && cmd.Ins == globalplatform.InsSelect:
+		c.encKey = nil
+		return selectResponse(c.instanceUID, &c.key.PublicKey), nil
+	case cmd.Ins == keycard.InsOpenSecureChannel:
+		clientKey, err := ethcrypto.UnmarshalPubkey(cmd.Data)
+		if err != nil {
+			return nil, err
+		}
+		cardData := make([]byte, 48)
+		if _, err = rand.Read(cardData); err != nil {
+			return nil, err
+		}
+		secret := crypto.GenerateECDHSharedSecret(c.key, clientKey)
+		c.encKey, c.macKey, c.iv = crypto.DeriveSessionKeys(secret, testPairingKey, cardData)
+		return append(cardData, 0x90, 0x00), nil
+	case c.encKey == nil:
+		return swInsNotSupported, nil
+	}
+
+	commandMAC := cmd.Data[:16]
+	if _, err = crypto.DecryptData(cmd.Data[16:], c.encKey, c.iv); err != nil {
+		return nil, err
+	}
+
+	plainResponse := []byte{0x90, 0x00}
+	if response, ok := c.responses[cmd.Ins]; ok {
+		plainResponse = response
+	}
+	if cmd.Ins == keycard.InsMutuallyAuthenticate {
+		plainResponse = append(make([]byte, 32), plainResponse...)
+	}
+
+	encrypted, err := crypto.EncryptData(plainResponse, c.encKey, commandMAC)
+	if err != nil {
+		return nil, err
+	}
+	meta := make([]byte, 16)
+	meta[0] = byte(16 + len(encrypted))
+	c.iv, err = crypto.CalculateMac(meta, encrypted, c.macKey)
+	if err != nil {
+		return nil, err
+	}
+
+	response := append(append([]byte{}, c.iv...), encrypted...)
+	return append(response, 0x90, 0x00), nil
+}
+
+// tracingTransmitter records the exchanges like KeycardContext.Transmit
+type tracingTransmitter struct {
+	kc   *KeycardContext
+	card *fakeSecureCard
+}
+
+func (t tracingTransmitter) Transmit(command []byte) ([]byte, error) {
+	started := time.Now()
+	response, err := t.card.Transmit(command)
+	t.kc.traceAPDU(started, command, response, err)
+	return response, err
+}
+
+// traceVerifyPIN opens a secure channel with a fake card and verifies the PIN, it returns the recorded trace
+func traceVerifyPIN(t *testing.T, decrypt bool) []trace.Entry {
+	t.Helper()
+
+	tracePath := filepath.Join(t.TempDir(), "trace.jsonl")
+	recorder, err := trace.NewRecorder(tracePath, decrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kc := &KeycardContext{tracer: recorder}
+	kc.setChannel(io.NewNormalChannel(tracingTransmitter{kc: kc, card: newFakeSecureCard(t)}))
+
+	if _, err = kc.SelectApplet(); err != nil {
+		t.Fatal(err)
+	}
+	if err = kc.OpenSecureChannel(0, testPairingKey); err != nil {
+		t.Fatal(err)
+	}
+	if err = kc.VerifyPin("123456"); err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := trace.Load(tracePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestTraceDecryptsSecureChannel(t *testing.T) {
+	entries := traceVerifyPIN(t, true)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+
+	// select and open secure channel are exchanged in plain
+	for _, entry := range entries[:2] {
+		if entry.Decrypted != nil {
+			t.Fatalf("plain exchange %X shouldn't be decrypted", entry.Command)
+		}
+	}
+
+	authenticate := entries[2].Decrypted
+	if authenticate == nil || authenticate.Command[1] != keycard.InsMutuallyAuthenticate || authenticate.Redacted {
+		t.Fatalf("unexpected mutual authentication %+v", authenticate)
+	}
+
+	verifyPIN := entries[3].Decrypted
+	if verifyPIN == nil {
+		t.Fatal("the PIN verification should be decrypted")
+	}
+	if !bytes.Equal(verifyPIN.Command, []byte{0x80, keycard.InsVerifyPIN, 0x00, 0x00, 0x06}) || !verifyPIN.Redacted {
+		t.Fatalf("the PIN should be redacted, got %X", []byte(verifyPIN.Command))
+	}
+	if !bytes.Equal(verifyPIN.Response, []byte{0x90, 0x00}) {
+		t.Fatalf("unexpected decrypted response %X", []byte(verifyPIN.Response))
+	}
+}
+
+func TestTraceWithoutSecureChannelDecryption(t *testing.T) {
+	entries := traceVerifyPIN(t, false)
+	for _, entry := range entries {
+		if entry.Decrypted != nil {
+			t.Fatalf("exchange %X shouldn't be decrypted", entry.Command)
+		}
+	}
+}
+
+// TestReplaySecureChannel shows that secure channel traffic can't be replayed:
+// the session keys are derived from a new key pair, so the recorded responses fail the MAC verification.
+func TestReplaySecureChannel(t *testing.T) {
+	entries := traceVerifyPIN(t, false)
+	replayer := trace.NewReplayer(entries, true)
+
+	kc := &KeycardContext{}
+	kc.setChannel(io.NewNormalChannel(replayer))
+
+	if _, err := kc.SelectApplet(); err != nil {
+		t.Fatal(err)
+	}
+	err := kc.OpenSecureChannel(0, testPairingKey)
+	if !errors.Is(err, keycard.ErrInvalidResponseMAC) {
+		t.Fatalf("expected %v, got %v", keycard.ErrInvalidResponseMAC, err)
+	}
+
+	// Both the public key opening the secure channel and the encrypted challenge differ
+	divergences := replayer.Divergences()
+	if len(divergences) != 2 || divergences[0].Index != 1 || divergences[1].Index != 2 {
+		t.Fatalf("unexpected divergences %v", divergences)
+	}
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/status-im/status-keycard-go/pkg/trace"
+)
+
+func (kc *KeycardContext) traceAPDU(started time.Time, apdu []byte, rpdu []byte, err error) {
+	if kc.tracer == nil {
+		return
+	}
+
+	entry := &trace.Entry{
+		Timestamp: started,
+		Duration:  time.Since(started),
+		Reader:    kc.reader,
+		Command:   apdu,
+		Response:  rpdu,
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+	} else if kc.tracer.DecryptsSecureChannel() {
+		entry.Decrypted = kc.scDecoder.Decode(apdu, rpdu, kc.secureChannel.encryptionKey())
+	}
+
+	err = kc.tracer.Record(entry)
+	if err != nil {
+		Printf("failed to record apdu trace %+v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"go.uber.org/zap/zapcore"
+
 	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
@@ -41,10 +43,23 @@ type ApplicationStatus struct {
 }
 
 type KeyPair struct {
-	Address    string          `json:"address"`
-	PublicKey  utils.HexString `json:"publicKey"`
-	PrivateKey utils.HexString `json:"privateKey,omitempty"`
-	ChainCode  utils.HexString `json:"chainCode,omitempty"`
+	Address    string                `json:"address"`
+	PublicKey  utils.HexString       `json:"publicKey"`
+	PrivateKey utils.SecretHexString `json:"privateKey,omitempty"`
+	ChainCode  utils.HexString       `json:"chainCode,omitempty"`
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler, the private key is redacted
+func (k *KeyPair) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("address", k.Address)
+	enc.AddString("publicKey", k.PublicKey.String())
+	if len(k.PrivateKey) > 0 {
+		enc.AddString("privateKey", utils.Redacted)
+	}
+	if len(k.ChainCode) > 0 {
+		enc.AddString("chainCode", k.ChainCode.String())
+	}
+	return nil
 }
 
 type Wallet struct {
@@ -63,6 +78,14 @@ type LoginKeys struct {
 	WhisperPrivateKey    *KeyPair `json:"whisperPrivateKey"`
 }
 
+// MarshalLogObject implements zapcore.ObjectMarshaler, private keys are redacted
+func (k *LoginKeys) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return addKeyPairs(enc, map[string]*KeyPair{
+		"encryptionPrivateKey": k.EncryptionPrivateKey,
+		"whisperPrivateKey":    k.WhisperPrivateKey,
+	})
+}
+
 type RecoverKeys struct {
 	LoginKeys
 	EIP1581key    *KeyPair `json:"eip1581"`
@@ -70,3 +93,28 @@ type RecoverKeys struct {
 	WalletKey     *KeyPair `json:"walletKey"`
 	MasterKey     *KeyPair `json:"masterKey"`
 }
+
+// MarshalLogObject implements zapcore.ObjectMarshaler, private keys are redacted
+func (k *RecoverKeys) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return addKeyPairs(enc, map[string]*KeyPair{
+		"encryptionPrivateKey": k.EncryptionPrivateKey,
+		"whisperPrivateKey":    k.WhisperPrivateKey,
+		"eip1581":              k.EIP1581key,
+		"walletRootKey":        k.WalletRootKey,
+		"walletKey":            k.WalletKey,
+		"masterKey":            k.MasterKey,
+	})
+}
+
+func addKeyPairs(enc zapcore.ObjectEncoder, keys map[string]*KeyPair) error {
+	for name, key := range keys {
+		if key == nil {
+			continue
+		}
+		err := enc.AddObject(name, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Events recorded in the audit log, with the Details keys of each event
+const (
+	PINVerified  = "pin-verified"
+	PINFailed    = "pin-failed" // remainingAttempts
+	PINChanged   = "pin-changed"
+	PINUnblocked = "pin-unblocked"
+	PUKFailed    = "puk-failed" // remainingAttempts
+	PUKChanged   = "puk-changed"
+	Paired       = "paired"   // index
+	Unpaired     = "unpaired" // index
+	KeyLoaded    = "key-loaded"
+	KeyGenerated = "key-generated"
+	KeyRemoved   = "key-removed"
+	KeyExported  = "key-exported"  // path, kind
+	ExportDenied = "export-denied" // path, kind
+	Signed       = "signed"        // path, hash
+	FactoryReset = "factory-reset" // result
+)
+
+// Entry is a single audit record. Each entry holds the hash of the previous one,
+// so that changing, removing or reordering entries breaks the chain, see Verify.
+// Hashes are HMAC-SHA256 keyed with the log key, so that the chain can't be recomputed
+// by whoever can write to the log, without the key.
+type Entry struct {
+	Seq         uint64            `json:"seq"`
+	Time        time.Time         `json:"time"`
+	Event       string            `json:"event"`
+	InstanceUID string            `json:"instanceUID"`
+	KeyUID      string            `json:"keyUID"`
+	Details     map[string]string `json:"details,omitempty"`
+	PrevHash    string            `json:"prevHash"`
+	Hash        string            `json:"hash"`
+}
+
+// computeHash returns the HMAC-SHA256 of the entry encoded without its hash
+func (e Entry) computeHash(key []byte) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// checkHash returns true if the entry hash matches its content
+func (e Entry) checkHash(key []byte) (bool, error) {
+	hash, err := e.computeHash(key)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(hash), []byte(e.Hash)), nil
+}
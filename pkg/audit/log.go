@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// maxEntryLength limits the length of a line read from the log
+const maxEntryLength = 1 << 20
+
+// KeySize is the minimum length of the key the log is authenticated with
+const KeySize = 32
+
+var (
+	errKeyTooShort = fmt.Errorf("audit key must be at least %d bytes", KeySize)
+	errKeyMismatch = errors.New("audit log was recorded with another key, or its last entry was modified")
+)
+
+// Log appends hash-chained entries to the audit log file, one JSON-encoded Entry per line.
+// The file is only ever appended to, and synced after each entry.
+// A nil Log is valid and records nothing.
+type Log struct {
+	mutex    sync.Mutex
+	file     *os.File
+	key      []byte
+	length   int64
+	lastSeq  uint64
+	lastHash string
+}
+
+// NewLog opens the audit log for appending. An existing log is continued from its last entry.
+// The key authenticates the entries and must be stored apart from the log, it's needed to verify the log.
+// A partial last line, left by an interrupted write, is removed.
+func NewLog(filePath string, key []byte) (*Log, error) {
+	if len(key) < KeySize {
+		return nil, errKeyTooShort
+	}
+
+	err := os.MkdirAll(filepath.Dir(filePath), 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{file: file, key: append([]byte(nil), key...)}
+
+	err = l.continueChain()
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrap(err, "failed to read audit log")
+	}
+
+	return l, nil
+}
+
+// continueChain reads the last entry of the log, and truncates a partial last line
+func (l *Log) continueChain() error {
+	last, validLength, err := lastEntry(l.file)
+	if err != nil {
+		return err
+	}
+
+	err = l.file.Truncate(validLength)
+	if err != nil {
+		return err
+	}
+	l.length = validLength
+
+	if last == nil {
+		return nil
+	}
+
+	valid, err := last.checkHash(l.key)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errKeyMismatch
+	}
+
+	l.lastSeq = last.Seq
+	l.lastHash = last.Hash
+	return nil
+}
+
+// lastEntry returns the last entry, and the length of the log up to the end of the last complete line.
+// Entries are written with their line ending at once, so a last line without it was never fully written.
+func lastEntry(r io.Reader) (*Entry, int64, error) {
+	var last *Entry
+	var length int64
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return last, length, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(line) > maxEntryLength {
+			return nil, 0, bufio.ErrTooLong
+		}
+
+		length += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		entry := &Entry{}
+		err = json.Unmarshal(line, entry)
+		if err != nil {
+			return nil, 0, err
+		}
+		last = entry
+	}
+}
+
+// Record appends the event. Seq, Time and the hashes are set by the log.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return os.ErrClosed
+	}
+
+	entry.Seq = l.lastSeq + 1
+	entry.Time = time.Now().UTC()
+	entry.PrevHash = l.lastHash
+
+	var err error
+	entry.Hash, err = entry.computeHash(l.key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	n, err := l.file.Write(append(data, '\n'))
+	if err != nil {
+		// Don't leave a partial line for the next entry to be appended to
+		if n > 0 {
+			_ = l.file.Truncate(l.length)
+		}
+		return err
+	}
+	l.length += int64(n)
+
+	err = l.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	l.lastSeq = entry.Seq
+	l.lastHash = entry.Hash
+	return nil
+}
+
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	utils.Wipe(l.key)
+	return err
+}
@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, KeySize)
+
+// writeTestLog records the events to a new log and returns its path
+func writeTestLog(t *testing.T, events ...string) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := NewLog(filePath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		err = l.Record(Entry{Event: event, InstanceUID: "instance", KeyUID: "key"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func readLines(t *testing.T, filePath string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestVerify(t *testing.T) {
+	filePath := writeTestLog(t, PINVerified, KeyGenerated, Signed)
+
+	count, err := VerifyFile(filePath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 entries, got %d", count)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	lines := readLines(t, writeTestLog(t, PINVerified, PINFailed, KeyExported, FactoryReset))
+
+	testCases := []struct {
+		name  string
+		lines []string
+		line  int
+	}{
+		{
+			name:  "modified",
+			lines: []string{lines[0], strings.Replace(lines[1], PINFailed, PINVerified, 1), lines[2], lines[3]},
+			line:  2,
+		},
+		{
+			name:  "removed",
+			lines: []string{lines[0], lines[2], lines[3]},
+			line:  2,
+		},
+		{
+			name:  "reordered",
+			lines: []string{lines[0], lines[2], lines[1], lines[3]},
+			line:  2,
+		},
+		{
+			name:  "inserted",
+			lines: []string{lines[0], lines[1], lines[1], lines[2], lines[3]},
+			line:  3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(strings.Join(tc.lines, "")), testKey)
+
+			var verifyErr *VerifyError
+			if !errors.As(err, &verifyErr) {
+				t.Fatalf("expected a VerifyError, got %v", err)
+			}
+			if verifyErr.Line != tc.line {
+				t.Fatalf("expected line %d, got %d: %v", tc.line, verifyErr.Line, verifyErr)
+			}
+		})
+	}
+}
+
+// TestVerifyDetectsRecomputedChain rewrites an entry and recomputes the chain without the key
+func TestVerifyDetectsRecomputedChain(t *testing.T) {
+	filePath := writeTestLog(t, PINVerified, PINFailed)
+
+	forgedPath := filepath.Join(t.TempDir(), "forged.jsonl")
+	forged, err := NewLog(forgedPath, bytes.Repeat([]byte{0x24}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []string{PINVerified, PINVerified} {
+		if err = forged.Record(Entry{Event: event, InstanceUID: "instance", KeyUID: "key"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = forged.Close()
+
+	_, err = VerifyFile(forgedPath, testKey)
+	var verifyErr *VerifyError
+	if !errors.As(err, &verifyErr) || verifyErr.Line != 1 {
+		t.Fatalf("expected the first line to be rejected, got %v", err)
+	}
+
+	_, err = NewLog(filePath, bytes.Repeat([]byte{0x24}, KeySize))
+	if err == nil {
+		t.Fatal("expected the log not to be continued with another key")
+	}
+}
+
+func TestNewLogRejectsShortKey(t *testing.T) {
+	_, err := NewLog(filepath.Join(t.TempDir(), "audit.jsonl"), testKey[:KeySize-1])
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestReopenContinuesChain(t *testing.T) {
+	filePath := writeTestLog(t, PINVerified, Signed)
+
+	l, err := NewLog(filePath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Record(Entry{Event: KeyRemoved}); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+
+	count, err := VerifyFile(filePath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 entries, got %d", count)
+	}
+}
+
+func TestReopenTruncatesPartialLastLine(t *testing.T) {
+	filePath := writeTestLog(t, PINVerified, Signed)
+	lines := readLines(t, filePath)
+
+	// Simulate a write interrupted in the middle of the third entry
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString(lines[1][:len(lines[1])/2])
+	_ = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLog(filePath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Record(Entry{Event: KeyRemoved}); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+
+	count, err := VerifyFile(filePath, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 entries, got %d", count)
+	}
+}
+
+func TestNewLogRejectsCorruptedEntry(t *testing.T) {
+	filePath := writeTestLog(t, PINVerified, Signed)
+	lines := readLines(t, filePath)
+
+	err := os.WriteFile(filePath, []byte(lines[0]+"{\n"+lines[1]), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewLog(filePath, testKey)
+	if err == nil {
+		t.Fatal("expected a corrupted complete line not to be truncated")
+	}
+}
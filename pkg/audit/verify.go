@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// VerifyError tells which entry breaks the hash chain
+type VerifyError struct {
+	Line   int
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Verify checks the hash chain of the whole log with the key it was recorded with, and returns the number of entries.
+// Changed, removed, inserted or reordered entries are reported with a VerifyError.
+// Entries removed from the end of the log can't be detected, the caller should compare
+// the returned count, or the last hash, with a copy kept elsewhere.
+func Verify(r io.Reader, key []byte) (int, error) {
+	var prev Entry
+	count := 0
+	line := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxEntryLength)
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return count, &VerifyError{Line: line, Reason: "invalid entry: " + err.Error()}
+		}
+
+		if entry.Seq != prev.Seq+1 {
+			return count, &VerifyError{Line: line, Reason: fmt.Sprintf("expected seq %d, got %d", prev.Seq+1, entry.Seq)}
+		}
+		if entry.PrevHash != prev.Hash {
+			return count, &VerifyError{Line: line, Reason: "previous hash mismatch"}
+		}
+
+		valid, err := entry.checkHash(key)
+		if err != nil {
+			return count, &VerifyError{Line: line, Reason: err.Error()}
+		}
+		if !valid {
+			return count, &VerifyError{Line: line, Reason: "hash mismatch, entry was modified or the key is wrong"}
+		}
+
+		prev = entry
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// VerifyFile checks the hash chain of the log file, see Verify
+func VerifyFile(filePath string, key []byte) (int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return Verify(file, key)
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+const serviceName = "keycard"
+
+// Transport carries JSON-RPC requests and signals between the Client and a KeycardService
+type Transport interface {
+	// Call sends an encoded JSON-RPC request and returns the encoded response
+	Call(ctx context.Context, request []byte) ([]byte, error)
+
+	// Subscribe receives signals, each encoded as the JSON envelope, until ctx is done.
+	// The channel is closed when the subscription ends, e.g. the connection is lost.
+	Subscribe(ctx context.Context) (<-chan []byte, error)
+
+	Close() error
+}
+
+// Client calls the Session API methods with the request and response types of pkg/session.
+// Failed calls return a *session.Error.
+type Client struct {
+	transport Transport
+	lastID    uint64
+}
+
+func New(transport Transport) *Client {
+	return &Client{transport: transport}
+}
+
+// Close closes the transport
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+type rpcRequest struct {
+	Version string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *session.Error  `json:"error"`
+}
+
+// Call calls the method, e.g. "GetStatus", and decodes its result to result, unless nil.
+// params can be nil for methods without params.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	request := rpcRequest{
+		Version: "2.0",
+		ID:      atomic.AddUint64(&c.lastID, 1),
+		Method:  serviceName + "." + method,
+		Params:  []interface{}{},
+	}
+	if params != nil {
+		request.Params = append(request.Params, params)
+	}
+
+	// Both the request and the response may contain secrets
+	payload, err := utils.MarshalRevealingSecrets(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode request")
+	}
+	defer utils.Wipe(payload)
+
+	data, err := c.transport.Call(ctx, payload)
+	if err != nil {
+		return err
+	}
+	defer utils.Wipe(data)
+
+	var response rpcResponse
+	err = json.Unmarshal(data, &response)
+	defer utils.Wipe(response.Result)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(response.Result, result), "failed to decode result")
+}
+
+func (c *Client) Start(ctx context.Context, request *session.StartRequest) error {
+	return c.Call(ctx, "Start", request, nil)
+}
+
+func (c *Client) Stop(ctx context.Context) error {
+	return c.Call(ctx, "Stop", nil, nil)
+}
+
+func (c *Client) GetStatus(ctx context.Context) (*session.Status, error) {
+	status := &session.Status{}
+	err := c.Call(ctx, "GetStatus", nil, status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (c *Client) Initialize(ctx context.Context, request *session.InitializeRequest) error {
+	return c.Call(ctx, "Initialize", request, nil)
+}
+
+func (c *Client) Authorize(ctx context.Context, request *session.AuthorizeRequest) (*session.AuthorizeResponse, error) {
+	response := &session.AuthorizeResponse{}
+	err := c.Call(ctx, "Authorize", request, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) Deauthorize(ctx context.Context) error {
+	return c.Call(ctx, "Deauthorize", nil, nil)
+}
+
+func (c *Client) ChangePIN(ctx context.Context, request *session.ChangePINRequest) error {
+	return c.Call(ctx, "ChangePIN", request, nil)
+}
+
+func (c *Client) ChangePUK(ctx context.Context, request *session.ChangePUKRequest) error {
+	return c.Call(ctx, "ChangePUK", request, nil)
+}
+
+func (c *Client) Unblock(ctx context.Context, request *session.UnblockRequest) error {
+	return c.Call(ctx, "Unblock", request, nil)
+}
+
+func (c *Client) GenerateMnemonic(ctx context.Context, request *session.GenerateMnemonicRequest) (*session.GenerateMnemonicResponse, error) {
+	response := &session.GenerateMnemonicResponse{}
+	err := c.Call(ctx, "GenerateMnemonic", request, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) LoadMnemonic(ctx context.Context, request *session.LoadMnemonicRequest) (*session.LoadMnemonicResponse, error) {
+	response := &session.LoadMnemonicResponse{}
+	err := c.Call(ctx, "LoadMnemonic", request, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) FactoryReset(ctx context.Context, request *session.FactoryResetRequest) error {
+	return c.Call(ctx, "FactoryReset", request, nil)
+}
+
+func (c *Client) GetMetadata(ctx context.Context) (*session.GetMetadataResponse, error) {
+	response := &session.GetMetadataResponse{}
+	err := c.Call(ctx, "GetMetadata", nil, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) StoreMetadata(ctx context.Context, request *session.StoreMetadataRequest) error {
+	return c.Call(ctx, "StoreMetadata", request, nil)
+}
+
+func (c *Client) ExportLoginKeys(ctx context.Context, request *session.ExportKeysRequest) (*session.ExportLoginKeysResponse, error) {
+	response := &session.ExportLoginKeysResponse{}
+	err := c.Call(ctx, "ExportLoginKeys", request, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) ExportRecoverKeys(ctx context.Context, request *session.ExportKeysRequest) (*session.ExportRecoveredKeysResponse, error) {
+	response := &session.ExportRecoveredKeysResponse{}
+	err := c.Call(ctx, "ExportRecoverKeys", request, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) SimulateError(ctx context.Context, request *session.SimulateErrorRequest) error {
+	return c.Call(ctx, "SimulateError", request, nil)
+}
+
+// Approve approves the request sent with the `confirmation-required` signal
+func (c *Client) Approve(ctx context.Context, id string) error {
+	return c.Call(ctx, "Approve", &session.ResolveConfirmationRequest{ID: id}, nil)
+}
+
+// Reject rejects the request sent with the `confirmation-required` signal
+func (c *Client) Reject(ctx context.Context, id string) error {
+	return c.Call(ctx, "Reject", &session.ResolveConfirmationRequest{ID: id}, nil)
+}
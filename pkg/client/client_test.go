@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+const testToken = "test-token"
+
+// testServer serves a service which is not started, with the endpoints of status-keycard-server.
+// Signals are published to bus by the tests.
+type testServer struct {
+	*httptest.Server
+	bus *signal.Bus
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	bus := signal.NewBus()
+	rpcServer, err := session.NewRPCServer(session.NewKeycardService(session.WithSignalBus(bus)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{bus: bus}
+	mux := http.NewServeMux()
+	mux.Handle("/rpc", rpcServer)
+	mux.HandleFunc("/events", s.events)
+	mux.HandleFunc("/signals", func(w http.ResponseWriter, r *http.Request) {
+		s.signals(t, rpcServer, w, r)
+	})
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) events(w http.ResponseWriter, r *http.Request) {
+	subscription := s.bus.Subscribe(16, signal.CloseOnOverflow)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case envelope, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+			_, _ = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", envelope.Seq, envelope.JSON())
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func (s *testServer) signals(t *testing.T, rpcServer *session.RPCServer, w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	var writeLock sync.Mutex
+	write := func(data []byte) {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		_ = conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	subscription := s.bus.Subscribe(16, signal.CloseOnOverflow)
+	defer subscription.Close()
+	go func() {
+		for envelope := range subscription.Events() {
+			write(envelope.JSON())
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		write(rpcServer.Call(r.Context(), message))
+	}
+}
+
+func (s *testServer) publishState(state session.State) {
+	s.bus.Publish(session.StatusChangedEvent{Status: session.Status{State: state}})
+}
+
+// forEachTransport runs the test with a new server and a client connected to it, for each transport
+func forEachTransport(t *testing.T, test func(t *testing.T, s *testServer, c *Client)) {
+	transports := map[string]func(s *testServer) (Transport, error){
+		"http": func(s *testServer) (Transport, error) {
+			return NewHTTPTransport(s.URL, WithToken(testToken)), nil
+		},
+		"websocket": func(s *testServer) (Transport, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			return DialWebSocket(ctx, s.URL, WithToken(testToken))
+		},
+	}
+
+	for name, newTransport := range transports {
+		t.Run(name, func(t *testing.T) {
+			s := newTestServer(t)
+			transport, err := newTransport(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := New(transport)
+			defer c.Close()
+
+			test(t, s, c)
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testServer, c *Client) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		_, err := c.GetStatus(ctx)
+		sessionErr, ok := err.(*session.Error)
+		if !ok || sessionErr.Code != session.ErrorCodeNotStarted {
+			t.Fatalf("expected a not-started error, got %v", err)
+		}
+
+		// Without PC/SC, the service is started in the `no-pcsc` state with an error
+		err = c.Start(ctx, &session.StartRequest{StorageFilePath: filepath.Join(t.TempDir(), "pairings.json")})
+		if _, ok := err.(*session.Error); err != nil && !ok {
+			t.Fatalf("expected a session error, got %v", err)
+		}
+		defer c.Stop(ctx)
+
+		status, err := c.GetStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.State == "" {
+			t.Fatalf("expected a state, got %+v", status)
+		}
+	})
+}
+
+func TestHTTPTransportRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+	c := New(NewHTTPTransport(s.URL))
+	defer c.Close()
+
+	_, err := c.GetStatus(context.Background())
+	if err == nil {
+		t.Fatal("expected an error without token")
+	}
+	if _, ok := err.(*session.Error); ok {
+		t.Fatalf("expected a transport error, got %v", err)
+	}
+}
+
+func TestSubscribeStatus(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testServer, c *Client) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		statuses, err := c.SubscribeStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Other signals are skipped
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			s.bus.Send("flow-result", map[string]string{})
+			s.publishState(session.WaitingForCard)
+		}()
+		select {
+		case status, ok := <-statuses:
+			if !ok {
+				t.Fatal("subscription closed")
+			}
+			if status.State != session.WaitingForCard {
+				t.Fatalf("expected %s, got %s", session.WaitingForCard, status.State)
+			}
+		case <-ctx.Done():
+			t.Fatal("no status received")
+		}
+	})
+}
+
+func TestWaitForState(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testServer, c *Client) {
+		s.publishState(session.WaitingForReader)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			s.publishState(session.WaitingForCard)
+			s.publishState(session.Ready)
+		}()
+
+		status, err := c.WaitForState(ctx, session.Ready, session.Authorized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.State != session.Ready {
+			t.Fatalf("expected %s, got %s", session.Ready, status.State)
+		}
+
+		// Already in the state
+		status, err = c.WaitForState(ctx, session.Ready)
+		if err != nil || status.State != session.Ready {
+			t.Fatalf("expected %s, got %v, %v", session.Ready, status, err)
+		}
+	})
+}
+
+func TestWaitForStateTimeout(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testServer, c *Client) {
+		s.publishState(session.WaitingForReader)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := c.WaitForState(ctx, session.Ready)
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected a deadline error, got %v", err)
+		}
+	})
+}
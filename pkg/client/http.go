@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPTransport sends requests to the `/rpc` endpoint of status-keycard-server,
+// and receives signals from the `/events` stream.
+type HTTPTransport struct {
+	address string
+	options *options
+	client  *http.Client
+}
+
+// NewHTTPTransport connects to the server at the given address, e.g. `http://127.0.0.1:8080`
+func NewHTTPTransport(address string, opts ...Option) *HTTPTransport {
+	o := buildOptions(opts)
+
+	client := o.httpClient
+	if client == nil {
+		client = &http.Client{}
+		if o.tlsConfig != nil {
+			client.Transport = &http.Transport{TLSClientConfig: o.tlsConfig}
+		}
+	}
+
+	return &HTTPTransport{
+		address: strings.TrimSuffix(address, "/"),
+		options: o,
+		client:  client,
+	}
+}
+
+func (t *HTTPTransport) Call(ctx context.Context, request []byte) ([]byte, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, t.address+"/rpc", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	t.options.authorize(httpRequest.Header)
+
+	response, err := t.client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected response status: %s", response.Status)
+	}
+
+	return io.ReadAll(response.Body)
+}
+
+func (t *HTTPTransport) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, t.address+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Accept", "text/event-stream")
+	t.options.authorize(httpRequest.Header)
+
+	response, err := t.client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, errors.Errorf("unexpected response status: %s", response.Status)
+	}
+
+	signals := make(chan []byte, signalQueueSize)
+	go func() {
+		defer close(signals)
+		defer response.Body.Close()
+
+		var data []byte
+		scanner := bufio.NewScanner(response.Body)
+		scanner.Buffer(make([]byte, 4096), maxSignalLength)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			switch {
+			case len(line) == 0 && len(data) > 0:
+				// An empty line ends the event
+				select {
+				case signals <- data:
+				case <-ctx.Done():
+					return
+				}
+				data = nil
+			case bytes.HasPrefix(line, []byte("data:")):
+				data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+			}
+		}
+	}()
+	return signals, nil
+}
+
+func (t *HTTPTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
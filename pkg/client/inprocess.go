@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// InProcessTransport calls the RPC server directly, the same way as the `KeycardCallRPC` C binding,
+// and receives signals from the signal bus of the served KeycardService.
+type InProcessTransport struct {
+	server *session.RPCServer
+}
+
+func NewInProcessTransport(server *session.RPCServer) *InProcessTransport {
+	return &InProcessTransport{server: server}
+}
+
+func (t *InProcessTransport) Call(ctx context.Context, request []byte) ([]byte, error) {
+	return t.server.Call(ctx, request), nil
+}
+
+func (t *InProcessTransport) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	subscription := t.server.Service().Bus().Subscribe(signalQueueSize, signal.CloseOnOverflow)
+
+	signals := make(chan []byte, signalQueueSize)
+	go func() {
+		defer close(signals)
+		defer subscription.Close()
+
+		for {
+			select {
+			case envelope, ok := <-subscription.Events():
+				if !ok {
+					return
+				}
+				// The client gets its own copy, signals carrying secrets are wiped once released
+				data := append([]byte(nil), envelope.JSON()...)
+				envelope.Release()
+				select {
+				case signals <- data:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return signals, nil
+}
+
+// Close does nothing, the RPC server is owned by the caller
+func (t *InProcessTransport) Close() error {
+	return nil
+}
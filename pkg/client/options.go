@@ -0,0 +1,50 @@
+package client
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+type options struct {
+	token      string
+	tlsConfig  *tls.Config
+	httpClient *http.Client
+}
+
+// Option configures the HTTP and WebSocket transports
+type Option func(*options)
+
+// WithToken authenticates with the given bearer token
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTLSConfig connects with the given TLS config, e.g. with a client certificate or a custom CA
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithHTTPClient sends HTTP requests with the given client. WithTLSConfig is ignored by the HTTP transport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+func buildOptions(opts []Option) *options {
+	o := &options{}
+	for _, option := range opts {
+		option(o)
+	}
+	return o
+}
+
+func (o *options) authorize(header http.Header) {
+	if o.token != "" {
+		header.Set("Authorization", "Bearer "+o.token)
+	}
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+const (
+	// signalQueueSize is the number of signals queued for a slow subscriber
+	signalQueueSize = 256
+
+	// maxSignalLength limits the length of a signal, or a response, read from the server
+	maxSignalLength = 1 << 20
+)
+
+var errSubscriptionClosed = errors.New("signals subscription closed")
+
+// Signal is a signal received from the service, its event is decoded on demand
+type Signal struct {
+	Type  string          `json:"type"`
+	Seq   uint64          `json:"seq"`
+	Event json.RawMessage `json:"event"`
+}
+
+// Decode decodes the event, e.g. to session.ConfirmationRequiredEvent
+func (s *Signal) Decode(event interface{}) error {
+	return json.Unmarshal(s.Event, event)
+}
+
+// Status decodes the event of a `status-changed` signal
+func (s *Signal) Status() (*session.Status, error) {
+	if s.Type != signal.StatusChanged {
+		return nil, errors.New("not a status-changed signal: " + s.Type)
+	}
+
+	status := &session.Status{}
+	err := s.Decode(status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Subscribe receives signals until ctx is done. The transports start with the current status, if any.
+// The channel is closed when the subscription ends, or when signals were not received fast enough.
+func (c *Client) Subscribe(ctx context.Context) (<-chan Signal, error) {
+	raw, err := c.transport.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	signals := make(chan Signal, signalQueueSize)
+	go func() {
+		defer close(signals)
+		for data := range raw {
+			var s Signal
+			if json.Unmarshal(data, &s) != nil {
+				continue
+			}
+			select {
+			case signals <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return signals, nil
+}
+
+// SubscribeStatus receives the status from `status-changed` signals until ctx is done, see Subscribe
+func (c *Client) SubscribeStatus(ctx context.Context) (<-chan *session.Status, error) {
+	signals, err := c.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(chan *session.Status, signalQueueSize)
+	go func() {
+		defer close(statuses)
+		for s := range signals {
+			if s.Type != signal.StatusChanged {
+				continue
+			}
+			status, err := s.Status()
+			if err != nil {
+				continue
+			}
+			select {
+			case statuses <- status:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return statuses, nil
+}
+
+// WaitForState waits until the session is in one of the given states and returns its status,
+// e.g. `WaitForState(ctx, session.Ready, session.Authorized)`.
+func (c *Client) WaitForState(ctx context.Context, states ...session.State) (*session.Status, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	statuses, err := c.SubscribeStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current status is checked as well, in case the transport doesn't start with it
+	status, err := c.GetStatus(ctx)
+	if err != nil && session.AsError(err).Code != session.ErrorCodeNotStarted {
+		return nil, err
+	}
+	if err == nil && inStates(status.State, states) {
+		return status, nil
+	}
+
+	for {
+		select {
+		case status, ok := <-statuses:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, errSubscriptionClosed
+			}
+			if inStates(status.State, states) {
+				return status, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func inStates(state session.State, states []session.State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
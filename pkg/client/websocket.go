@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+var errConnectionClosed = errors.New("websocket connection closed")
+
+// WebSocketTransport sends requests and receives signals over a single websocket connection
+// to the `/signals` endpoint of status-keycard-server.
+// The server executes requests from a connection one by one, except Approve and Reject,
+// so a request waiting for confirmation can be resolved over the same connection.
+type WebSocketTransport struct {
+	conn *websocket.Conn
+
+	writeLock sync.Mutex
+
+	// lock protects the fields below
+	lock        sync.Mutex
+	pending     map[string]chan []byte
+	subscribers map[chan []byte]struct{}
+	lastStatus  []byte
+	err         error
+}
+
+// DialWebSocket connects to the server at the given address, e.g. `http://127.0.0.1:8080`.
+// `ws://` and `wss://` addresses are accepted as well.
+func DialWebSocket(ctx context.Context, address string, opts ...Option) (*WebSocketTransport, error) {
+	o := buildOptions(opts)
+
+	url := strings.TrimSuffix(address, "/") + "/signals"
+	url = strings.Replace(url, "http://", "ws://", 1)
+	url = strings.Replace(url, "https://", "wss://", 1)
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = o.tlsConfig
+
+	header := http.Header{}
+	o.authorize(header)
+
+	conn, response, err := dialer.DialContext(ctx, url, header)
+	if err != nil {
+		if response != nil {
+			return nil, errors.Wrapf(err, "failed to connect: %s", response.Status)
+		}
+		return nil, errors.Wrap(err, "failed to connect")
+	}
+	conn.SetReadLimit(maxSignalLength)
+
+	t := &WebSocketTransport{
+		conn:        conn,
+		pending:     map[string]chan []byte{},
+		subscribers: map[chan []byte]struct{}{},
+	}
+	go t.readMessages()
+	return t, nil
+}
+
+// readMessages dispatches responses to the pending calls, and signals to the subscribers
+func (t *WebSocketTransport) readMessages() {
+	for {
+		_, message, err := t.conn.ReadMessage()
+		if err != nil {
+			t.fail(err)
+			return
+		}
+
+		var header struct {
+			Version string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Type    string          `json:"type"`
+		}
+		if json.Unmarshal(message, &header) != nil {
+			continue
+		}
+
+		t.lock.Lock()
+		if header.Version != "" {
+			if result, ok := t.pending[string(header.ID)]; ok {
+				delete(t.pending, string(header.ID))
+				result <- message
+			}
+		} else {
+			t.publish(header.Type, message)
+		}
+		t.lock.Unlock()
+	}
+}
+
+// publish must be called with lock held. Subscribers which don't keep up are closed.
+func (t *WebSocketTransport) publish(typ string, message []byte) {
+	if typ == signal.StatusChanged {
+		t.lastStatus = message
+	}
+
+	for subscriber := range t.subscribers {
+		select {
+		case subscriber <- message:
+		default:
+			delete(t.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// fail ends all pending calls and subscriptions
+func (t *WebSocketTransport) fail(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err != nil {
+		return
+	}
+	t.err = err
+
+	for id, result := range t.pending {
+		delete(t.pending, id)
+		close(result)
+	}
+	for subscriber := range t.subscribers {
+		delete(t.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+func (t *WebSocketTransport) Call(ctx context.Context, request []byte) ([]byte, error) {
+	var header struct {
+		ID json.RawMessage `json:"id"`
+	}
+	err := json.Unmarshal(request, &header)
+	if err != nil || header.ID == nil {
+		return nil, errors.New("request must have an id")
+	}
+	id := string(header.ID)
+
+	result := make(chan []byte, 1)
+	t.lock.Lock()
+	if t.err != nil {
+		t.lock.Unlock()
+		return nil, errConnectionClosed
+	}
+	t.pending[id] = result
+	t.lock.Unlock()
+
+	defer func() {
+		t.lock.Lock()
+		delete(t.pending, id)
+		t.lock.Unlock()
+	}()
+
+	t.writeLock.Lock()
+	err = t.conn.WriteMessage(websocket.TextMessage, request)
+	t.writeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case response, ok := <-result:
+		if !ok {
+			return nil, errConnectionClosed
+		}
+		return response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Subscribe starts with the last status received on the connection, if any
+func (t *WebSocketTransport) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	subscriber := make(chan []byte, signalQueueSize)
+
+	t.lock.Lock()
+	if t.err != nil {
+		t.lock.Unlock()
+		return nil, errConnectionClosed
+	}
+	if t.lastStatus != nil {
+		subscriber <- t.lastStatus
+	}
+	t.subscribers[subscriber] = struct{}{}
+	t.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		if _, ok := t.subscribers[subscriber]; ok {
+			delete(t.subscribers, subscriber)
+			close(subscriber)
+		}
+	}()
+	return subscriber, nil
+}
+
+// Close closes the connection with a close frame
+func (t *WebSocketTransport) Close() error {
+	t.writeLock.Lock()
+	_ = t.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	t.writeLock.Unlock()
+
+	err := t.conn.Close()
+	t.fail(errConnectionClosed)
+	return err
+}
@@ -1,7 +1,9 @@
 package flow
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -15,8 +17,58 @@ import (
 	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
+// confirm asks the confirmer, if any, to approve the operation
+func (f *KeycardFlow) confirm(method string, description string) error {
+	if f.confirmer == nil {
+		return nil
+	}
+
+	request, err := internal.NewConfirmationRequest(method, description)
+	if err != nil {
+		return err
+	}
+	request.InstanceUID = f.cardInfo.instanceUID
+	request.KeyUID = f.cardInfo.keyUID
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f.confirmationLock.Lock()
+	f.cancelConfirmation = cancel
+	f.confirmationLock.Unlock()
+
+	defer func() {
+		f.confirmationLock.Lock()
+		f.cancelConfirmation = nil
+		f.confirmationLock.Unlock()
+	}()
+
+	// Cancel sets the state before it looks for cancelConfirmation
+	if f.state == Cancelling {
+		return giveupErr()
+	}
+
+	err = f.confirmer.Confirm(ctx, request)
+
+	if f.state == Cancelling {
+		return giveupErr()
+	} else if err != nil && internal.AsError(err).Code == internal.ErrorCodeTimeout {
+		return errors.New(internal.ErrorTimeout)
+	} else if err != nil {
+		return errors.New(internal.ErrorRejected)
+	}
+
+	return nil
+}
+
 func (f *KeycardFlow) factoryReset(kc *internal.KeycardContext) error {
-	err := kc.FactoryReset(true)
+	err := f.confirm("FactoryReset", "Factory reset the keycard, removing its keys and pairings")
+
+	if err != nil {
+		return err
+	}
+
+	err = kc.FactoryReset(true)
 
 	if err == nil {
 		delete(f.params, FactoryReset)
@@ -422,6 +474,13 @@ func (f *KeycardFlow) exportBIP44Key(kc *internal.KeycardContext) (interface{},
 		exportPrivParam, ok := f.params[ExportPriv]
 		exportPrivate := (!ok || !exportPrivParam.(bool))
 
+		if !exportPrivate {
+			err := f.confirm("ExportPrivateKeys", fmt.Sprintf("Export the private keys at %v", path))
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		if pathStr, ok := path.(string); ok {
 			return f.exportKey(kc, pathStr, exportPrivate)
 		} else if paths, ok := path.([]interface{}); ok {
@@ -598,6 +657,11 @@ func (f *KeycardFlow) sign(kc *internal.KeycardContext) (*internal.Signature, er
 		return f.sign(kc)
 	}
 
+	err = f.confirm("Sign", fmt.Sprintf("Sign the hash %s with the key at %s", hash, path))
+	if err != nil {
+		return nil, err
+	}
+
 	signature, err := kc.SignWithPath(rawHash, path.(string))
 
 	if internal.IsSCardError(err) {
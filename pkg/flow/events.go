@@ -0,0 +1,55 @@
+package flow
+
+import (
+	"encoding/json"
+
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// ResultEvent is published when a flow finishes, with its result or error
+type ResultEvent struct {
+	Status FlowStatus
+}
+
+func (e ResultEvent) SignalType() string {
+	return FlowResult
+}
+
+// MarshalJSON encodes the event as the flow status, as in the `keycard.flow-result` signal
+func (e ResultEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Status)
+}
+
+// JSONValue implements utils.JSONValuer, so that signals reveal the secrets of the status
+func (e ResultEvent) JSONValue() interface{} {
+	return e.Status
+}
+
+// ActionEvent is published when a flow waits for an action, e.g. EnterPIN, or reports one, e.g. CardInserted
+type ActionEvent struct {
+	Action string
+	Status FlowStatus
+}
+
+func (e ActionEvent) SignalType() string {
+	return e.Action
+}
+
+// MarshalJSON encodes the event as the flow status, as in the `keycard.action.*` signals
+func (e ActionEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Status)
+}
+
+// JSONValue implements utils.JSONValuer, so that signals reveal the secrets of the status
+func (e ActionEvent) JSONValue() interface{} {
+	return e.Status
+}
+
+// PublishSignal publishes a FlowResult signal as ResultEvent, and any other as ActionEvent
+func PublishSignal(typ string, status FlowStatus) {
+	if typ == FlowResult {
+		signal.Publish(ResultEvent{Status: status})
+	} else {
+		signal.Publish(ActionEvent{Action: typ, Status: status})
+	}
+}
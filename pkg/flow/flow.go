@@ -1,14 +1,18 @@
 package flow
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/io"
 
 	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/audit"
 	"github.com/status-im/status-keycard-go/pkg/pairing"
-	"github.com/status-im/status-keycard-go/signal"
+	"github.com/status-im/status-keycard-go/pkg/trace"
 )
 
 type cardStatus struct {
@@ -28,9 +32,61 @@ type KeycardFlow struct {
 	params   FlowParams
 	cardInfo cardStatus
 	knownCA  []string
+	tracer   *trace.Recorder
+
+	// transport replaces PC/SC when set, see WithTransport
+	transport io.Transmitter
+
+	exportPolicy *ExportPolicy
+	auditLog     *audit.Log
+	confirmer    Confirmer
+
+	// cancelConfirmation ends the confirmation in progress, when the flow is cancelled
+	confirmationLock   sync.Mutex
+	cancelConfirmation context.CancelFunc
+}
+
+type Option func(*KeycardFlow)
+
+// WithTrace records all APDUs exchanged with the keycard.
+func WithTrace(recorder *trace.Recorder) Option {
+	return func(f *KeycardFlow) {
+		f.tracer = recorder
+	}
+}
+
+// WithTransport exchanges APDUs through the given transport instead of PC/SC,
+// e.g. to replay a trace recorded with WithTrace using trace.Replayer.
+func WithTransport(transport io.Transmitter) Option {
+	return func(f *KeycardFlow) {
+		f.transport = transport
+	}
+}
+
+// WithExportPolicy restricts the derivation paths which keys can be exported for,
+// e.g. private keys exported with the ExportPriv param.
+func WithExportPolicy(policy *ExportPolicy) Option {
+	return func(f *KeycardFlow) {
+		f.exportPolicy = policy
+	}
+}
+
+// WithAuditLog records security-relevant card operations to the given audit log.
+func WithAuditLog(log *audit.Log) Option {
+	return func(f *KeycardFlow) {
+		f.auditLog = log
+	}
 }
 
-func NewFlow(storageDir string) (*KeycardFlow, error) {
+// WithConfirmer asks the confirmer to approve signing, private key exports and factory reset.
+// A refused operation ends the flow with the `rejected` error, an unanswered one with the `timeout` error.
+func WithConfirmer(confirmer Confirmer) Option {
+	return func(f *KeycardFlow) {
+		f.confirmer = confirmer
+	}
+}
+
+func NewFlow(storageDir string, options ...Option) (*KeycardFlow, error) {
 	p, err := pairing.NewStore(storageDir)
 
 	if err != nil {
@@ -43,11 +99,15 @@ func NewFlow(storageDir string) (*KeycardFlow, error) {
 		knownCA:  []string{},
 	}
 
+	for _, option := range options {
+		option(flow)
+	}
+
 	return flow, nil
 }
 
-func NewFlowWithCA(storageDir string, knownCA []string) (*KeycardFlow, error) {
-	f, err := NewFlow(storageDir)
+func NewFlowWithCA(storageDir string, knownCA []string, options ...Option) (*KeycardFlow, error) {
+	f, err := NewFlow(storageDir, options...)
 
 	if err != nil {
 		return nil, err
@@ -97,6 +157,12 @@ func (f *KeycardFlow) Cancel() error {
 		f.wakeUp <- struct{}{}
 	}
 
+	f.confirmationLock.Lock()
+	if f.cancelConfirmation != nil {
+		f.cancelConfirmation()
+	}
+	f.confirmationLock.Unlock()
+
 	return nil
 }
 
@@ -121,7 +187,7 @@ func (f *KeycardFlow) runFlow() {
 	}
 
 	if f.state != Cancelling {
-		signal.Send(FlowResult, result)
+		PublishSignal(FlowResult, result)
 	}
 
 	f.params = nil
@@ -142,7 +208,7 @@ func (f *KeycardFlow) pause(action string, errMsg string, status FlowParams) {
 		status[PUKRetries] = f.cardInfo.pukRetries
 	}
 
-	signal.Send(action, status)
+	PublishSignal(action, FlowStatus(status))
 	f.state = Paused
 }
 
@@ -203,12 +269,15 @@ func (f *KeycardFlow) closeKeycard(kc *internal.KeycardContext) {
 }
 
 func (f *KeycardFlow) connect() (*internal.KeycardContext, error) {
-	kc, err := internal.StartKeycardContext()
+	kc, err := internal.StartKeycardContext(f.tracer, f.transport)
 
 	if err != nil {
 		return nil, err
 	}
 
+	kc.SetExportPolicy(f.exportPolicy)
+	kc.SetAuditLog(f.auditLog)
+
 	t := time.NewTimer(150 * time.Millisecond)
 
 	for {
@@ -225,7 +294,7 @@ func (f *KeycardFlow) connect() (*internal.KeycardContext, error) {
 			t.Stop()
 			if f.state == Paused {
 				f.state = Running
-				signal.Send(CardInserted, FlowStatus{})
+				PublishSignal(CardInserted, FlowStatus{})
 			}
 
 			return kc, nil
@@ -321,6 +390,16 @@ func (f *KeycardFlow) exportKeysFlow(kc *internal.KeycardContext, recover bool)
 		return nil, err
 	}
 
+	if recover {
+		err = f.confirm("ExportRecoverKeys", "Export the whisper and encryption private keys, and the wallet public keys")
+	} else {
+		err = f.confirm("ExportLoginKeys", "Export the whisper and encryption private keys")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
 	result := FlowStatus{KeyUID: f.cardInfo.keyUID, InstanceUID: f.cardInfo.instanceUID}
 
 	key, err := f.exportKey(kc, internal.EncryptionPath, false)
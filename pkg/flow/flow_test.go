@@ -0,0 +1,127 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/status-im/status-keycard-go/internal"
+)
+
+// testConfirmer records the requests and waits for a decision or ctx
+type testConfirmer struct {
+	requests chan ConfirmationRequest
+	decision chan error
+}
+
+func (c *testConfirmer) Confirm(ctx context.Context, request ConfirmationRequest) error {
+	c.requests <- request
+	select {
+	case err := <-c.decision:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newTestFlow(t *testing.T, options ...Option) *KeycardFlow {
+	t.Helper()
+
+	f, err := NewFlow(filepath.Join(t.TempDir(), "pairings.json"), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.state = Running
+	f.cardInfo = cardStatus{instanceUID: "aa", keyUID: "bb"}
+	return f
+}
+
+func TestConfirm(t *testing.T) {
+	cases := []struct {
+		name    string
+		resolve func(f *KeycardFlow, c *testConfirmer)
+		check   func(err error) bool
+	}{
+		{
+			name:    "approve",
+			resolve: func(f *KeycardFlow, c *testConfirmer) { c.decision <- nil },
+			check:   func(err error) bool { return err == nil },
+		},
+		{
+			name:    "reject",
+			resolve: func(f *KeycardFlow, c *testConfirmer) { c.decision <- errors.New("no") },
+			check:   func(err error) bool { return err != nil && err.Error() == internal.ErrorRejected },
+		},
+		{
+			name: "confirmation timed out",
+			resolve: func(f *KeycardFlow, c *testConfirmer) {
+				c.decision <- internal.NewError(internal.ErrorCodeTimeout, "Sign confirmation timed out")
+			},
+			check: func(err error) bool { return err != nil && err.Error() == internal.ErrorTimeout },
+		},
+		{
+			name:    "confirmer deadline exceeded",
+			resolve: func(f *KeycardFlow, c *testConfirmer) { c.decision <- context.DeadlineExceeded },
+			check:   func(err error) bool { return err != nil && err.Error() == internal.ErrorTimeout },
+		},
+		{
+			name:    "confirmer cancelled",
+			resolve: func(f *KeycardFlow, c *testConfirmer) { c.decision <- context.Canceled },
+			check:   func(err error) bool { return err != nil && err.Error() == internal.ErrorTimeout },
+		},
+		{
+			name:    "flow cancelled",
+			resolve: func(f *KeycardFlow, c *testConfirmer) { _ = f.Cancel() },
+			check: func(err error) bool {
+				_, ok := err.(*giveupError)
+				return ok
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			confirmer := &testConfirmer{
+				requests: make(chan ConfirmationRequest, 1),
+				decision: make(chan error, 1),
+			}
+			f := newTestFlow(t, WithConfirmer(confirmer))
+
+			done := make(chan error, 1)
+			go func() {
+				done <- f.confirm("Sign", "Sign the hash")
+			}()
+
+			var request ConfirmationRequest
+			select {
+			case request = <-confirmer.requests:
+			case <-time.After(time.Second):
+				t.Fatal("no confirmation request")
+			}
+			if request.ID == "" || request.Method != "Sign" || request.InstanceUID != "aa" || request.KeyUID != "bb" {
+				t.Fatalf("unexpected request %+v", request)
+			}
+
+			tc.resolve(f, confirmer)
+			select {
+			case err := <-done:
+				if !tc.check(err) {
+					t.Fatalf("unexpected result %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("confirmation not resolved")
+			}
+		})
+	}
+}
+
+func TestConfirmWithoutConfirmer(t *testing.T) {
+	f := newTestFlow(t)
+
+	err := f.confirm("Sign", "Sign the hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
 	"github.com/status-im/status-keycard-go/pkg/pairing"
-	"github.com/status-im/status-keycard-go/signal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
 type MockedKeycardFlow struct {
@@ -192,10 +192,10 @@ func (mkf *MockedKeycardFlow) RegisterKeycard(cardIndex int, readerState MockedR
 func (mkf *MockedKeycardFlow) runFlow() {
 	switch mkf.currentReaderState {
 	case NoReader:
-		signal.Send(flow.FlowResult, flow.FlowStatus{internal.ErrorKey: internal.ErrorNoReader})
+		flow.PublishSignal(flow.FlowResult, flow.FlowStatus{internal.ErrorKey: internal.ErrorNoReader})
 		return
 	case NoKeycard:
-		signal.Send(flow.InsertCard, flow.FlowStatus{internal.ErrorKey: internal.ErrorConnection})
+		flow.PublishSignal(flow.InsertCard, flow.FlowStatus{internal.ErrorKey: internal.ErrorConnection})
 		return
 	default:
 		switch mkf.flowType {
@@ -237,7 +237,7 @@ func (mkf *MockedKeycardFlow) runFlow() {
 }
 
 func (mkf *MockedKeycardFlow) storeRegisteredKeycards() error {
-	data, err := json.Marshal(struct {
+	data, err := utils.MarshalRevealingSecrets(struct {
 		RegisteredKeycards       map[int]*MockedKeycard
 		RegisteredKeycardHelpers map[int]*MockedKeycard
 	}{
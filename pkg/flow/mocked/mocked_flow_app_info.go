@@ -4,7 +4,6 @@ import (
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
 	"github.com/status-im/status-keycard-go/pkg/utils"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleGetAppInfoFlow() {
@@ -16,7 +15,7 @@ func (mkf *MockedKeycardFlow) handleGetAppInfoFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -29,7 +28,7 @@ func (mkf *MockedKeycardFlow) handleGetAppInfoFlow() {
 		flowStatus[internal.ErrorKey] = internal.ErrorNoKeys
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -49,7 +48,7 @@ func (mkf *MockedKeycardFlow) handleGetAppInfoFlow() {
 	if factoryReset {
 		mkf.state = flow.Idle
 		*mkf.insertedKeycard = MockedKeycard{}
-		signal.Send(flow.FlowResult, flow.FlowStatus{
+		flow.PublishSignal(flow.FlowResult, flow.FlowStatus{
 			internal.ErrorKey: internal.ErrorOK,
 			flow.Paired:       false,
 			flow.AppInfo: internal.ApplicationInfo{
@@ -75,7 +74,7 @@ func (mkf *MockedKeycardFlow) handleGetAppInfoFlow() {
 			KeyUID:         utils.HexString(mkf.insertedKeycard.KeyUID),
 		}
 		mkf.state = flow.Idle
-		signal.Send(flow.FlowResult, flowStatus)
+		flow.PublishSignal(flow.FlowResult, flowStatus)
 		return
 	}
 
@@ -83,5 +82,5 @@ func (mkf *MockedKeycardFlow) handleGetAppInfoFlow() {
 	flowStatus[flow.InstanceUID] = mkf.insertedKeycard.InstanceUID
 	flowStatus[flow.KeyUID] = mkf.insertedKeycard.KeyUID
 	mkf.state = flow.Paused
-	signal.Send(flow.EnterPIN, flowStatus)
+	flow.PublishSignal(flow.EnterPIN, flowStatus)
 }
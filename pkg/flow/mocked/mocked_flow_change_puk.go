@@ -3,7 +3,6 @@ package mocked
 import (
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleChangePukFlow() {
@@ -15,7 +14,7 @@ func (mkf *MockedKeycardFlow) handleChangePukFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -28,7 +27,7 @@ func (mkf *MockedKeycardFlow) handleChangePukFlow() {
 		flowStatus[internal.ErrorKey] = internal.ErrorRequireInit
 		flowStatus[flow.FreeSlots] = mkf.insertedKeycard.FreePairingSlots
 		mkf.state = flow.Paused
-		signal.Send(flow.EnterNewPIN, flowStatus)
+		flow.PublishSignal(flow.EnterNewPIN, flowStatus)
 		return
 	}
 
@@ -36,7 +35,7 @@ func (mkf *MockedKeycardFlow) handleChangePukFlow() {
 		flowStatus[internal.ErrorKey] = flow.FreeSlots
 		flowStatus[flow.FreeSlots] = mkf.insertedKeycard.FreePairingSlots
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -118,7 +117,7 @@ func (mkf *MockedKeycardFlow) handleChangePukFlow() {
 			mkf.insertedKeycard.PukRetries = internal.MaxPUKRetries
 			mkf.insertedKeycard.Puk = enteredPUK
 			mkf.state = flow.Idle
-			signal.Send(flow.FlowResult, flowStatus)
+			flow.PublishSignal(flow.FlowResult, flowStatus)
 			return
 		}
 	}
@@ -127,5 +126,5 @@ func (mkf *MockedKeycardFlow) handleChangePukFlow() {
 	flowStatus[flow.PINRetries] = mkf.insertedKeycard.PinRetries
 	flowStatus[flow.PUKRetries] = mkf.insertedKeycard.PukRetries
 	mkf.state = flow.Paused
-	signal.Send(finalType, flowStatus)
+	flow.PublishSignal(finalType, flowStatus)
 }
@@ -7,7 +7,6 @@ import (
 
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleExportPublicFlow() {
@@ -19,7 +18,7 @@ func (mkf *MockedKeycardFlow) handleExportPublicFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -32,7 +31,7 @@ func (mkf *MockedKeycardFlow) handleExportPublicFlow() {
 		flowStatus[internal.ErrorKey] = internal.ErrorNoKeys
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -170,7 +169,7 @@ func (mkf *MockedKeycardFlow) handleExportPublicFlow() {
 		}
 
 		mkf.state = flow.Idle
-		signal.Send(flow.FlowResult, flowStatus)
+		flow.PublishSignal(flow.FlowResult, flowStatus)
 		return
 	}
 
@@ -178,5 +177,5 @@ func (mkf *MockedKeycardFlow) handleExportPublicFlow() {
 	flowStatus[flow.PINRetries] = mkf.insertedKeycard.PinRetries
 	flowStatus[flow.PUKRetries] = mkf.insertedKeycard.PukRetries
 	mkf.state = flow.Paused
-	signal.Send(finalType, flowStatus)
+	flow.PublishSignal(finalType, flowStatus)
 }
@@ -7,7 +7,6 @@ import (
 
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleGetMetadataFlow() {
@@ -19,13 +18,13 @@ func (mkf *MockedKeycardFlow) handleGetMetadataFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
 	if mkf.insertedKeycard.InstanceUID == "" || mkf.insertedKeycard.KeyUID == "" {
 		mkf.state = flow.Idle
-		signal.Send(flow.FlowResult, flow.FlowStatus{internal.ErrorKey: internal.ErrorNoKeys})
+		flow.PublishSignal(flow.FlowResult, flow.FlowStatus{internal.ErrorKey: internal.ErrorNoKeys})
 		return
 	}
 
@@ -39,7 +38,7 @@ func (mkf *MockedKeycardFlow) handleGetMetadataFlow() {
 			flowStatus[internal.ErrorKey] = flow.FreeSlots
 			flowStatus[flow.FreeSlots] = mkf.insertedKeycard.FreePairingSlots
 			mkf.state = flow.Paused
-			signal.Send(flow.SwapCard, flowStatus)
+			flow.PublishSignal(flow.SwapCard, flowStatus)
 			return
 		}
 
@@ -120,7 +119,7 @@ func (mkf *MockedKeycardFlow) handleGetMetadataFlow() {
 			flowStatus[internal.ErrorKey] = ""
 			flowStatus[flow.CardMeta] = mkf.insertedKeycard.Metadata
 			mkf.state = flow.Idle
-			signal.Send(flow.FlowResult, flowStatus)
+			flow.PublishSignal(flow.FlowResult, flowStatus)
 			return
 		}
 
@@ -128,7 +127,7 @@ func (mkf *MockedKeycardFlow) handleGetMetadataFlow() {
 		flowStatus[flow.PINRetries] = mkf.insertedKeycard.PinRetries
 		flowStatus[flow.PUKRetries] = mkf.insertedKeycard.PukRetries
 		mkf.state = flow.Paused
-		signal.Send(finalType, flowStatus)
+		flow.PublishSignal(finalType, flowStatus)
 		return
 	}
 
@@ -143,5 +142,5 @@ func (mkf *MockedKeycardFlow) handleGetMetadataFlow() {
 
 	flowStatus[flow.CardMeta] = pubMetadata
 	mkf.state = flow.Idle
-	signal.Send(flow.FlowResult, flowStatus)
+	flow.PublishSignal(flow.FlowResult, flowStatus)
 }
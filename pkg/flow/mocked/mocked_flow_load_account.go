@@ -6,7 +6,6 @@ import (
 
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleLoadAccountFlow() {
@@ -18,7 +17,7 @@ func (mkf *MockedKeycardFlow) handleLoadAccountFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -77,7 +76,7 @@ func (mkf *MockedKeycardFlow) handleLoadAccountFlow() {
 		flowStatus[internal.ErrorKey] = internal.ErrorHasKeys
 		flowStatus[flow.FreeSlots] = mkf.insertedKeycard.FreePairingSlots
 		mkf.state = flow.Paused
-		signal.Send(finalType, flowStatus)
+		flow.PublishSignal(finalType, flowStatus)
 		return
 	}
 
@@ -108,7 +107,7 @@ func (mkf *MockedKeycardFlow) handleLoadAccountFlow() {
 			flowStatus[flow.PINRetries] = mkf.insertedKeycard.PinRetries
 			flowStatus[flow.PUKRetries] = mkf.insertedKeycard.PukRetries
 			mkf.state = flow.Paused
-			signal.Send(finalType, flowStatus)
+			flow.PublishSignal(finalType, flowStatus)
 			return
 		} else {
 			realMnemonicLength := len(strings.Split(enteredMnemonic, " "))
@@ -132,7 +131,7 @@ func (mkf *MockedKeycardFlow) handleLoadAccountFlow() {
 				flowStatus[flow.InstanceUID] = mkf.insertedKeycard.InstanceUID
 				flowStatus[flow.KeyUID] = mkf.insertedKeycard.KeyUID
 				mkf.state = flow.Idle
-				signal.Send(finalType, flowStatus)
+				flow.PublishSignal(finalType, flowStatus)
 				return
 			}
 		}
@@ -141,5 +140,5 @@ func (mkf *MockedKeycardFlow) handleLoadAccountFlow() {
 	finalType = flow.EnterNewPIN
 	flowStatus[internal.ErrorKey] = internal.ErrorRequireInit
 	mkf.state = flow.Paused
-	signal.Send(finalType, flowStatus)
+	flow.PublishSignal(finalType, flowStatus)
 }
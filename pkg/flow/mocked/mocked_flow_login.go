@@ -3,7 +3,6 @@ package mocked
 import (
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleLoginFlow() {
@@ -15,7 +14,7 @@ func (mkf *MockedKeycardFlow) handleLoginFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -29,7 +28,7 @@ func (mkf *MockedKeycardFlow) handleLoginFlow() {
 		flowStatus[internal.ErrorKey] = internal.ErrorNoKeys
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(finalType, flowStatus)
+		flow.PublishSignal(finalType, flowStatus)
 		return
 	}
 
@@ -99,7 +98,7 @@ func (mkf *MockedKeycardFlow) handleLoginFlow() {
 		flowStatus[flow.WhisperKey] = mkf.insertedKeycardHelper.ExportedKey[internal.WhisperPath]
 		flowStatus[flow.EncKey] = mkf.insertedKeycardHelper.ExportedKey[internal.EncryptionPath]
 		mkf.state = flow.Idle
-		signal.Send(flow.FlowResult, flowStatus)
+		flow.PublishSignal(flow.FlowResult, flowStatus)
 		return
 	}
 
@@ -107,5 +106,5 @@ func (mkf *MockedKeycardFlow) handleLoginFlow() {
 	flowStatus[flow.PINRetries] = mkf.insertedKeycard.PinRetries
 	flowStatus[flow.PUKRetries] = mkf.insertedKeycard.PukRetries
 	mkf.state = flow.Paused
-	signal.Send(finalType, flowStatus)
+	flow.PublishSignal(finalType, flowStatus)
 }
@@ -6,7 +6,6 @@ import (
 
 	"github.com/status-im/status-keycard-go/internal"
 	"github.com/status-im/status-keycard-go/pkg/flow"
-	"github.com/status-im/status-keycard-go/signal"
 )
 
 func (mkf *MockedKeycardFlow) handleStoreMetadataFlow() {
@@ -18,7 +17,7 @@ func (mkf *MockedKeycardFlow) handleStoreMetadataFlow() {
 		flowStatus[flow.KeyUID] = ""
 		flowStatus[flow.FreeSlots] = 0
 		mkf.state = flow.Paused
-		signal.Send(flow.SwapCard, flowStatus)
+		flow.PublishSignal(flow.SwapCard, flowStatus)
 		return
 	}
 
@@ -77,7 +76,7 @@ func (mkf *MockedKeycardFlow) handleStoreMetadataFlow() {
 		}
 
 		mkf.state = flow.Idle
-		signal.Send(finalType, flowStatus)
+		flow.PublishSignal(finalType, flowStatus)
 
 		return
 	}
@@ -92,5 +91,5 @@ func (mkf *MockedKeycardFlow) handleStoreMetadataFlow() {
 	flowStatus[flow.PINRetries] = mkf.insertedKeycard.PinRetries
 	flowStatus[flow.PUKRetries] = mkf.insertedKeycard.PukRetries
 	mkf.state = flow.Paused
-	signal.Send(finalType, flowStatus)
+	flow.PublishSignal(finalType, flowStatus)
 }
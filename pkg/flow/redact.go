@@ -0,0 +1,118 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// secretKeys lists the FlowParams and FlowStatus entries which must never be logged.
+var secretKeys = map[string]bool{
+	PairingPass:  true,
+	NewPairing:   true,
+	PIN:          true,
+	NewPIN:       true,
+	PUK:          true,
+	NewPUK:       true,
+	Mnemonic:     true,
+	MnemonicIdxs: true,
+	MasterKey:    true,
+	WalleRootKey: true,
+	WalletKey:    true,
+	EIP1581Key:   true,
+	WhisperKey:   true,
+	EncKey:       true,
+	ExportedKey:  true,
+}
+
+// IsSecret reports whether the value stored under the given key is sensitive
+func IsSecret(key string) bool {
+	return secretKeys[key]
+}
+
+func redactedValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case *internal.KeyPair, []*internal.KeyPair, internal.KeyPair:
+		// Key pairs redact their private key themselves, the public part is fine to log
+		return v
+	}
+
+	if secretKeys[key] {
+		return utils.Redacted
+	}
+
+	return value
+}
+
+func redactedString(m map[string]interface{}) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("map[")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		fmt.Fprintf(&sb, "%s:%+v", k, redactedValue(k, m[k]))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func marshalRedacted(m map[string]interface{}, enc zapcore.ObjectEncoder) error {
+	for k, v := range m {
+		v = redactedValue(k, v)
+		switch x := v.(type) {
+		case zapcore.ObjectMarshaler:
+			err := enc.AddObject(k, x)
+			if err != nil {
+				return err
+			}
+		case []*internal.KeyPair:
+			err := enc.AddArray(k, zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+				for _, kp := range x {
+					err := arr.AppendObject(kp)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+			if err != nil {
+				return err
+			}
+		default:
+			enc.AddString(k, fmt.Sprintf("%+v", x))
+		}
+	}
+	return nil
+}
+
+// String implements fmt.Stringer, secrets are redacted
+func (p FlowParams) String() string {
+	return redactedString(p)
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler, secrets are redacted
+func (p FlowParams) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(p, enc)
+}
+
+// String implements fmt.Stringer, secrets are redacted
+func (s FlowStatus) String() string {
+	return redactedString(s)
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler, secrets are redacted
+func (s FlowStatus) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(s, enc)
+}
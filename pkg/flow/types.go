@@ -1,5 +1,9 @@
 package flow
 
+import (
+	"github.com/status-im/status-keycard-go/internal"
+)
+
 type FlowType int
 type FlowParams map[string]interface{}
 type FlowStatus map[string]interface{}
@@ -114,3 +118,12 @@ const (
 	WalletPaths  = "wallet-paths"
 	SkipAuthUID  = "skip-auth-uid"
 )
+
+// ExportPolicy restricts the derivation paths which keys can be exported for, see WithExportPolicy
+type ExportPolicy = internal.ExportPolicy
+
+// Confirmer approves operations before they run, see WithConfirmer
+type Confirmer = internal.Confirmer
+
+// ConfirmationRequest describes an operation waiting for the user approval
+type ConfirmationRequest = internal.ConfirmationRequest
@@ -0,0 +1,63 @@
+// Package metrics collects the keycard service metrics and exposes them in Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+)
+
+var (
+	rpcBuckets  = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	apduBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+)
+
+// Metrics are the metrics of a keycard service, of the signal bus it publishes to and of the server serving it.
+// Each Metrics has its own registry, so that several services in one process are reported apart.
+type Metrics struct {
+	*Registry
+
+	RPCCalls         *CounterVec
+	RPCDuration      *HistogramVec
+	APDUs            *CounterVec
+	APDUDuration     *HistogramVec
+	StateTransitions *CounterVec
+	SignalsDropped   *CounterVec
+	WebSocketClients *Gauge
+	PINFailures      *CounterVec
+	PUKFailures      *CounterVec
+}
+
+// Default is used by services and buses which aren't given their own metrics
+var Default = New()
+
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		Registry: r,
+		RPCCalls: r.NewCounterVec("keycard_rpc_calls_total",
+			"Number of RPC calls by method and result. Result is ok or the error type.", "method", "result"),
+		RPCDuration: r.NewHistogramVec("keycard_rpc_duration_seconds",
+			"RPC call latency by method.", rpcBuckets, "method"),
+		APDUs: r.NewCounterVec("keycard_apdu_total",
+			"Number of APDUs exchanged with the keycard by result, ok or error.", "result"),
+		APDUDuration: r.NewHistogramVec("keycard_apdu_duration_seconds",
+			"APDU exchange latency.", apduBuckets),
+		StateTransitions: r.NewCounterVec("keycard_state_transitions_total",
+			"Number of transitions to each session state.", "state"),
+		SignalsDropped: r.NewCounterVec("keycard_signals_dropped_total",
+			"Number of signals dropped for subscribers which didn't keep up."),
+		WebSocketClients: r.NewGauge("keycard_websocket_clients",
+			"Number of connected websocket clients."),
+		PINFailures: r.NewCounterVec("keycard_pin_failures_total",
+			"Number of wrong PIN entries."),
+		PUKFailures: r.NewCounterVec("keycard_puk_failures_total",
+			"Number of wrong PUK entries."),
+	}
+}
+
+// Handler serves all metrics of the registry in Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WritePrometheus(w)
+	})
+}
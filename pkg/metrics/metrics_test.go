@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	calls := r.NewCounterVec("test_calls_total", "Test calls.", "method", "result")
+	r.NewCounterVec("test_failures_total", "Test failures.")
+	clients := r.NewGauge("test_clients", "Test clients.")
+	duration := r.NewHistogramVec("test_duration_seconds", "Test duration.", []float64{.1, 1}, "method")
+
+	calls.Inc("GetStatus", "ok")
+	calls.Inc("GetStatus", "ok")
+	calls.Inc("Authorize", `wrong "pin"`)
+	clients.Add(2)
+	clients.Add(-1)
+	duration.Observe(50*time.Millisecond, "GetStatus")
+	duration.Observe(500*time.Millisecond, "GetStatus")
+	duration.Observe(5*time.Second, "GetStatus")
+
+	recorder := httptest.NewRecorder()
+	r.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	body, _ := io.ReadAll(recorder.Body)
+
+	expected := []string{
+		"# TYPE test_calls_total counter",
+		`test_calls_total{method="Authorize",result="wrong \"pin\""} 1`,
+		`test_calls_total{method="GetStatus",result="ok"} 2`,
+		// Counters without labels are exposed before any increment
+		"test_failures_total 0",
+		"# TYPE test_clients gauge",
+		"test_clients 1",
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{method="GetStatus",le="0.1"} 1`,
+		`test_duration_seconds_bucket{method="GetStatus",le="1"} 2`,
+		`test_duration_seconds_bucket{method="GetStatus",le="+Inf"} 3`,
+		`test_duration_seconds_sum{method="GetStatus"} 5.55`,
+		`test_duration_seconds_count{method="GetStatus"} 3`,
+	}
+	lines := strings.Split(string(body), "\n")
+	for _, line := range expected {
+		if !contains(lines, line) {
+			t.Errorf("missing line %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestLabelsCountMismatch(t *testing.T) {
+	calls := NewRegistry().NewCounterVec("test_mismatch_total", "Test mismatch.", "method")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	calls.Inc()
+}
+
+func TestInstancesAreIndependent(t *testing.T) {
+	first, second := New(), New()
+	first.PINFailures.Inc()
+	first.PINFailures.Inc()
+	second.PINFailures.Inc()
+
+	for _, tc := range []struct {
+		metrics  *Metrics
+		expected string
+	}{
+		{first, "keycard_pin_failures_total 2"},
+		{second, "keycard_pin_failures_total 1"},
+	} {
+		var body strings.Builder
+		if err := tc.metrics.WritePrometheus(&body); err != nil {
+			t.Fatal(err)
+		}
+		if !contains(strings.Split(body.String(), "\n"), tc.expected) {
+			t.Errorf("missing line %q in:\n%s", tc.expected, body.String())
+		}
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/rpc"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+const jsonRPCVersion = "2.0"
+
+var null = json.RawMessage("null")
+
+// serverRequest is a single JSON-RPC 2.0 request
+type serverRequest struct {
+	Version string
+	Method  string
+	Params  json.RawMessage
+	// ID is nil for notifications
+	ID json.RawMessage
+}
+
+type successResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type errorResponse struct {
+	Version string          `json:"jsonrpc"`
+	Error   *Error          `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func (r *serverRequest) isNotification() bool {
+	return r.ID == nil
+}
+
+func (r *serverRequest) wipe() {
+	utils.Wipe(r.Params)
+}
+
+// parseRequest validates a single request object.
+// The returned request is not nil even on error, so that the id can be used in the error response.
+func parseRequest(raw json.RawMessage) (*serverRequest, *Error) {
+	request := &serverRequest{}
+
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(raw, &fields)
+	if err != nil || fields == nil {
+		request.ID = null
+		return request, internal.NewError(internal.ErrorCodeInvalidRequest, "request must be an object")
+	}
+	defer func() {
+		for key, value := range fields {
+			if key != "params" {
+				utils.Wipe(value)
+			}
+		}
+	}()
+
+	if id, ok := fields["id"]; ok {
+		switch firstByte(id) {
+		case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			request.ID = append(json.RawMessage(nil), id...)
+		default:
+			request.ID = null
+			return request, internal.NewError(internal.ErrorCodeInvalidRequest, "id must be a string, a number or null")
+		}
+	}
+
+	// Requests without version are accepted for compatibility with JSON-RPC 1.0 clients
+	if version, ok := fields["jsonrpc"]; ok {
+		if json.Unmarshal(version, &request.Version) != nil || request.Version != jsonRPCVersion {
+			return request, internal.NewError(internal.ErrorCodeInvalidRequest, "jsonrpc must be \"2.0\"")
+		}
+	}
+
+	if json.Unmarshal(fields["method"], &request.Method) != nil || request.Method == "" {
+		return request, internal.NewError(internal.ErrorCodeInvalidRequest, "method must be a non-empty string")
+	}
+
+	if params, ok := fields["params"]; ok {
+		switch firstByte(params) {
+		case '[', '{', 'n':
+			request.Params = params
+		default:
+			return request, internal.NewError(internal.ErrorCodeInvalidRequest, "params must be an array or an object")
+		}
+	}
+
+	return request, nil
+}
+
+// timeout returns the deadline of the request, set in milliseconds with the `timeoutMs` param.
+// Returns zero when no deadline is set.
+func (r *serverRequest) timeout() (time.Duration, *Error) {
+	params := r.Params
+	if firstByte(params) == '[' {
+		var list []json.RawMessage
+		if json.Unmarshal(params, &list) != nil || len(list) != 1 {
+			return 0, nil
+		}
+		defer utils.Wipe(list[0])
+		params = list[0]
+	}
+	if firstByte(params) != '{' {
+		return 0, nil
+	}
+
+	var deadline struct {
+		TimeoutMs *int64 `json:"timeoutMs"`
+	}
+	err := json.Unmarshal(params, &deadline)
+	if err != nil {
+		return 0, internal.NewError(internal.ErrorCodeInvalidParams, "timeoutMs must be an integer")
+	}
+	if deadline.TimeoutMs == nil {
+		return 0, nil
+	}
+	if *deadline.TimeoutMs <= 0 {
+		return 0, internal.NewError(internal.ErrorCodeInvalidParams, "timeoutMs must be positive")
+	}
+	return time.Duration(*deadline.TimeoutMs) * time.Millisecond, nil
+}
+
+func firstByte(raw json.RawMessage) byte {
+	raw = bytes.TrimLeft(raw, " \t\r\n")
+	if len(raw) == 0 {
+		return 0
+	}
+	return raw[0]
+}
+
+// codec plugs into gorilla/rpc to dispatch an already parsed request.
+// Instead of writing the response, it hands the reply over to RPCServer, which encodes it.
+type codec struct{}
+
+func (c *codec) NewRequest(r *http.Request) rpc.CodecRequest {
+	call, _ := r.Context().Value(rpcCallKey{}).(*rpcCall)
+	return &codecRequest{call: call}
+}
+
+type codecRequest struct {
+	call *rpcCall
+}
+
+func (c *codecRequest) Method() (string, error) {
+	return c.call.request.Method, nil
+}
+
+// ReadRequest fills the method arguments.
+// Params can be passed by name as an object, or by position as an array with a single object.
+// Missing params leave the arguments empty.
+func (c *codecRequest) ReadRequest(args interface{}) error {
+	params := c.call.request.Params
+
+	switch firstByte(params) {
+	case 0, 'n':
+		return nil
+	case '[':
+		var list []json.RawMessage
+		err := json.Unmarshal(params, &list)
+		defer func() {
+			for _, item := range list {
+				utils.Wipe(item)
+			}
+		}()
+		if err != nil {
+			c.call.paramsErr = err
+			return err
+		}
+		if len(list) > 1 {
+			c.call.paramsErr = internal.NewError(internal.ErrorCodeInvalidParams, "expected a single params object")
+			return c.call.paramsErr
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		params = list[0]
+	}
+
+	err := json.Unmarshal(params, args)
+	if err != nil {
+		c.call.paramsErr = err
+	}
+	return err
+}
+
+func (c *codecRequest) WriteResponse(w http.ResponseWriter, reply interface{}, methodErr error) error {
+	c.call.reply = reply
+	c.call.methodErr = methodErr
+	c.call.done = true
+	return nil
+}
@@ -0,0 +1,209 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// codecTestResponse is a decoded response object, with the id kept raw to tell `null` from a missing id
+type codecTestResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// expectedResponse is the id of a response and its error code, zero for a result
+type expectedResponse struct {
+	id   string
+	code ErrorCode
+}
+
+func decodeResponses(t *testing.T, response []byte) []codecTestResponse {
+	t.Helper()
+
+	if len(response) > 0 && response[0] == '[' {
+		var batch []codecTestResponse
+		if err := json.Unmarshal(response, &batch); err != nil {
+			t.Fatalf("invalid batch response %s: %v", response, err)
+		}
+		return batch
+	}
+
+	var single codecTestResponse
+	if err := json.Unmarshal(response, &single); err != nil {
+		t.Fatalf("invalid response %s: %v", response, err)
+	}
+	return []codecTestResponse{single}
+}
+
+func TestCall(t *testing.T) {
+	s, _ := newTestService(t)
+	startTestService(t, s, &StartRequest{})
+	server, err := NewRPCServer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		payload   string
+		batch     bool
+		responses []expectedResponse
+	}{
+		{
+			name:      "request",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus"}`,
+			responses: []expectedResponse{{id: "1"}},
+		},
+		{
+			name:      "string id and params by name",
+			payload:   `{"jsonrpc":"2.0","id":"a","method":"keycard.GetStatus","params":{}}`,
+			responses: []expectedResponse{{id: `"a"`}},
+		},
+		{
+			name:      "request without version",
+			payload:   `{"id":1,"method":"keycard.GetStatus","params":[{}]}`,
+			responses: []expectedResponse{{id: "1"}},
+		},
+		{
+			name:    "notification",
+			payload: `{"jsonrpc":"2.0","method":"keycard.GetStatus"}`,
+		},
+		{
+			name:    "failed notification",
+			payload: `{"jsonrpc":"2.0","method":"keycard.Unknown"}`,
+		},
+		{
+			name:      "parse error",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":`,
+			responses: []expectedResponse{{id: "null", code: ErrorCodeParse}},
+		},
+		{
+			name:      "not an object",
+			payload:   `"keycard.GetStatus"`,
+			responses: []expectedResponse{{id: "null", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:      "wrong version",
+			payload:   `{"jsonrpc":"1.0","id":1,"method":"keycard.GetStatus"}`,
+			responses: []expectedResponse{{id: "1", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:      "missing method",
+			payload:   `{"jsonrpc":"2.0","id":1}`,
+			responses: []expectedResponse{{id: "1", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:      "invalid id",
+			payload:   `{"jsonrpc":"2.0","id":{},"method":"keycard.GetStatus"}`,
+			responses: []expectedResponse{{id: "null", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:      "invalid params",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus","params":"{}"}`,
+			responses: []expectedResponse{{id: "1", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:      "invalid notification",
+			payload:   `{"jsonrpc":"2.0","method":"keycard.GetStatus","params":1}`,
+			responses: []expectedResponse{{id: "null", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:      "unknown method",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":"keycard.Unknown"}`,
+			responses: []expectedResponse{{id: "1", code: ErrorCodeMethodNotFound}},
+		},
+		{
+			name:      "too many params",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus","params":[{},{}]}`,
+			responses: []expectedResponse{{id: "1", code: ErrorCodeInvalidParams}},
+		},
+		{
+			name:      "timeout param",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus","params":{"timeoutMs":1000}}`,
+			responses: []expectedResponse{{id: "1"}},
+		},
+		{
+			name:      "invalid timeout param",
+			payload:   `{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus","params":[{"timeoutMs":0}]}`,
+			responses: []expectedResponse{{id: "1", code: ErrorCodeInvalidParams}},
+		},
+		{
+			name:      "empty batch",
+			payload:   `[]`,
+			responses: []expectedResponse{{id: "null", code: ErrorCodeInvalidRequest}},
+		},
+		{
+			name:    "batch",
+			payload: `[{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus"},{"jsonrpc":"2.0","id":2,"method":"keycard.Unknown"}]`,
+			batch:   true,
+			responses: []expectedResponse{
+				{id: "1"},
+				{id: "2", code: ErrorCodeMethodNotFound},
+			},
+		},
+		{
+			name: "batch with notifications and invalid requests",
+			payload: `[{"jsonrpc":"2.0","method":"keycard.GetStatus"},1,` +
+				`{"jsonrpc":"2.0","id":3,"method":"keycard.GetStatus"},{"jsonrpc":"2.0","method":""}]`,
+			batch: true,
+			responses: []expectedResponse{
+				{id: "null", code: ErrorCodeInvalidRequest},
+				{id: "3"},
+				{id: "null", code: ErrorCodeInvalidRequest},
+			},
+		},
+		{
+			name:    "batch of notifications",
+			payload: `[{"jsonrpc":"2.0","method":"keycard.GetStatus"},{"jsonrpc":"2.0","method":"keycard.GetStatus"}]`,
+		},
+		{
+			name:      "invalid JSON in a batch",
+			payload:   `[{"jsonrpc":"2.0","id":1,"method":"keycard.GetStatus"},]`,
+			responses: []expectedResponse{{id: "null", code: ErrorCodeParse}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := server.Call(context.Background(), []byte(tc.payload))
+			if len(tc.responses) == 0 {
+				if response != nil {
+					t.Fatalf("expected no response, got %s", response)
+				}
+				return
+			}
+			if response == nil {
+				t.Fatal("expected a response")
+			}
+			if isBatch := response[0] == '['; isBatch != tc.batch {
+				t.Fatalf("expected batch %v, got %s", tc.batch, response)
+			}
+
+			responses := decodeResponses(t, response)
+			if len(responses) != len(tc.responses) {
+				t.Fatalf("expected %d responses, got %s", len(tc.responses), response)
+			}
+			for i, expected := range tc.responses {
+				actual := responses[i]
+				if actual.Version != jsonRPCVersion || string(actual.ID) != expected.id {
+					t.Fatalf("response %d: expected id %s, got %s", i, expected.id, response)
+				}
+				if expected.code == 0 {
+					if actual.Error != nil || actual.Result == nil {
+						t.Fatalf("response %d: expected a result, got %s", i, response)
+					}
+					continue
+				}
+				if actual.Error == nil || actual.Error.Code != expected.code || actual.Result != nil {
+					t.Fatalf("response %d: expected error %d, got %s", i, expected.code, response)
+				}
+				if actual.Error.Data.Type != expected.code.String() {
+					t.Fatalf("response %d: expected error type %s, got %s", i, expected.code.String(), actual.Error.Data.Type)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// ConfirmationRequired is the type of signals asking the user to approve an operation
+const ConfirmationRequired = "confirmation-required"
+
+// DefaultConfirmationTimeout is used by SignalConfirmer when no timeout is given
+const DefaultConfirmationTimeout = time.Minute
+
+// ConfirmationRequest describes an operation waiting for the user approval
+type ConfirmationRequest = internal.ConfirmationRequest
+
+// ConfirmationRequiredEvent is published by SignalConfirmer, as the `confirmation-required` signal
+type ConfirmationRequiredEvent struct {
+	ConfirmationRequest
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (e ConfirmationRequiredEvent) SignalType() string {
+	return ConfirmationRequired
+}
+
+// Confirmer approves operations before they run: private key exports and factory reset.
+// Embedders can implement it to show their own UI. The same interface is used by the Flow API.
+type Confirmer = internal.Confirmer
+
+// confirmationResolver is a Confirmer which is resolved with the Approve and Reject methods
+type confirmationResolver interface {
+	Resolve(id string, approved bool) error
+}
+
+// AutoApprove approves all operations. It's used when no other Confirmer is set.
+type AutoApprove struct{}
+
+func (AutoApprove) Confirm(ctx context.Context, request ConfirmationRequest) error {
+	return nil
+}
+
+// SignalConfirmer publishes the `confirmation-required` signal and waits for the Approve
+// or Reject method to be called with the request ID. The operation is rejected after the timeout.
+type SignalConfirmer struct {
+	bus     *signal.Bus
+	timeout time.Duration
+
+	mutex   sync.Mutex
+	pending map[string]chan bool
+}
+
+func NewSignalConfirmer(bus *signal.Bus, timeout time.Duration) *SignalConfirmer {
+	if timeout <= 0 {
+		timeout = DefaultConfirmationTimeout
+	}
+
+	return &SignalConfirmer{
+		bus:     bus,
+		timeout: timeout,
+		pending: map[string]chan bool{},
+	}
+}
+
+func (c *SignalConfirmer) Confirm(ctx context.Context, request ConfirmationRequest) error {
+	result := make(chan bool, 1)
+
+	c.mutex.Lock()
+	c.pending[request.ID] = result
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, request.ID)
+		c.mutex.Unlock()
+	}()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	c.bus.Publish(ConfirmationRequiredEvent{
+		ConfirmationRequest: request,
+		ExpiresAt:           time.Now().Add(c.timeout),
+	})
+
+	select {
+	case approved := <-result:
+		if !approved {
+			return internal.NewError(internal.ErrorCodeRejected, request.Method+" rejected")
+		}
+		return nil
+	case <-timer.C:
+		return internal.NewError(internal.ErrorCodeTimeout, request.Method+" confirmation timed out")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resolve approves or rejects the pending request
+func (c *SignalConfirmer) Resolve(id string, approved bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result, ok := c.pending[id]
+	if !ok {
+		return internal.NewError(internal.ErrorCodeValidation, "no pending confirmation "+id)
+	}
+	delete(c.pending, id)
+
+	result <- approved
+	return nil
+}
+
+// activeConfirmer returns the Confirmer set with WithConfirmer, the signal confirmer
+// enabled with StartRequest.ConfirmationTimeoutMs, or AutoApprove
+func (s *KeycardService) activeConfirmer() Confirmer {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.confirmer != nil {
+		return s.confirmer
+	}
+	if s.signalConfirmer != nil {
+		return s.signalConfirmer
+	}
+	return AutoApprove{}
+}
+
+// confirm asks the active Confirmer to approve the method
+func (s *KeycardService) confirm(ctx context.Context, kc *internal.KeycardContextV2, method string, description string) error {
+	request, err := internal.NewConfirmationRequest(method, description)
+	if err != nil {
+		return err
+	}
+
+	status := kc.GetStatus()
+	if status.AppInfo != nil {
+		request.InstanceUID = utils.Btox(status.AppInfo.InstanceUID)
+		request.KeyUID = utils.Btox(status.AppInfo.KeyUID)
+	}
+
+	return s.activeConfirmer().Confirm(ctx, request)
+}
+
+// ResolvesConfirmation reports whether the payload is a single Approve or Reject request.
+// Transports which execute requests one by one must not queue such requests behind
+// the call waiting for the confirmation.
+func ResolvesConfirmation(payload []byte) bool {
+	var request struct {
+		Method string `json:"method"`
+	}
+	if firstByte(payload) != '{' || json.Unmarshal(payload, &request) != nil {
+		return false
+	}
+	return request.Method == serviceName+".Approve" || request.Method == serviceName+".Reject"
+}
+
+type ResolveConfirmationRequest struct {
+	// ID of the request sent with the `confirmation-required` signal
+	ID string `json:"id" validate:"required"`
+}
+
+// Approve lets the operation waiting for confirmation run
+func (s *KeycardService) Approve(r *http.Request, args *ResolveConfirmationRequest, reply *struct{}) error {
+	return s.resolveConfirmation(args, true)
+}
+
+// Reject fails the operation waiting for confirmation with the `rejected` error
+func (s *KeycardService) Reject(r *http.Request, args *ResolveConfirmationRequest, reply *struct{}) error {
+	return s.resolveConfirmation(args, false)
+}
+
+func (s *KeycardService) resolveConfirmation(args *ResolveConfirmationRequest, approved bool) error {
+	err := validateRequest(args)
+	if err != nil {
+		return err
+	}
+
+	resolver, ok := s.activeConfirmer().(confirmationResolver)
+	if !ok {
+		return internal.NewError(internal.ErrorCodeValidation, "no pending confirmation "+args.ID)
+	}
+	return resolver.Resolve(args.ID, approved)
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// waitConfirmationRequired waits for the `confirmation-required` signal
+func waitConfirmationRequired(t *testing.T, subscription *signal.Subscription) ConfirmationRequiredEvent {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case envelope := <-subscription.Events():
+			if event, ok := envelope.Event.(ConfirmationRequiredEvent); ok {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("no confirmation-required signal")
+		}
+	}
+}
+
+func TestSignalConfirmer(t *testing.T) {
+	cases := []struct {
+		name    string
+		timeout time.Duration
+		resolve func(confirmer *SignalConfirmer, id string, cancel context.CancelFunc)
+		check   func(err error) bool
+	}{
+		{
+			name:    "approve",
+			timeout: time.Minute,
+			resolve: func(confirmer *SignalConfirmer, id string, cancel context.CancelFunc) {
+				_ = confirmer.Resolve(id, true)
+			},
+			check: func(err error) bool { return err == nil },
+		},
+		{
+			name:    "reject",
+			timeout: time.Minute,
+			resolve: func(confirmer *SignalConfirmer, id string, cancel context.CancelFunc) {
+				_ = confirmer.Resolve(id, false)
+			},
+			check: func(err error) bool { return hasErrorCode(err, ErrorCodeRejected) },
+		},
+		{
+			name:    "timeout",
+			timeout: 10 * time.Millisecond,
+			resolve: func(confirmer *SignalConfirmer, id string, cancel context.CancelFunc) {},
+			check:   func(err error) bool { return hasErrorCode(err, ErrorCodeTimeout) },
+		},
+		{
+			name:    "context cancelled",
+			timeout: time.Minute,
+			resolve: func(confirmer *SignalConfirmer, id string, cancel context.CancelFunc) {
+				cancel()
+			},
+			check: func(err error) bool { return errors.Is(err, context.Canceled) },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := signal.NewBus()
+			subscription := bus.Subscribe(16, signal.DropOnOverflow)
+			defer subscription.Close()
+
+			confirmer := NewSignalConfirmer(bus, tc.timeout)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- confirmer.Confirm(ctx, ConfirmationRequest{ID: "42", Method: "FactoryReset"})
+			}()
+
+			event := waitConfirmationRequired(t, subscription)
+			if event.ID != "42" || event.Method != "FactoryReset" {
+				t.Fatalf("unexpected event %+v", event)
+			}
+			tc.resolve(confirmer, event.ID, cancel)
+
+			select {
+			case err := <-done:
+				if !tc.check(err) {
+					t.Fatalf("unexpected result %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("confirmation not resolved")
+			}
+
+			// The request is no longer pending
+			if err := confirmer.Resolve(event.ID, true); !hasErrorCode(err, ErrorCodeValidation) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConfirmationOverRPC(t *testing.T) {
+	for _, method := range []string{"Approve", "Reject"} {
+		t.Run(method, func(t *testing.T) {
+			service, bus := newTestService(t)
+			subscription := bus.Subscribe(16, signal.DropOnOverflow)
+			defer subscription.Close()
+
+			startTestService(t, service, &StartRequest{ConfirmationTimeoutMs: 60000})
+			server, err := NewRPCServer(service)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			responses := callAsync(server, "FactoryReset", "{}")
+			event := waitConfirmationRequired(t, subscription)
+
+			id, _ := json.Marshal(ResolveConfirmationRequest{ID: event.ID})
+			response := <-callAsync(server, method, string(id))
+			if response.Error != nil {
+				t.Fatal(response.Error)
+			}
+
+			// Approved, the factory reset fails without a keycard
+			response = <-responses
+			if response.Error == nil {
+				t.Fatal("expected an error")
+			}
+			rejected := response.Error.Code == ErrorCodeRejected
+			if rejected != (method == "Reject") {
+				t.Fatalf("unexpected error %+v", response.Error)
+			}
+		})
+	}
+}
+
+func TestResolveUnknownConfirmation(t *testing.T) {
+	service, _ := newTestService(t)
+	startTestService(t, service, &StartRequest{})
+
+	err := service.Approve(nil, &ResolveConfirmationRequest{ID: "42"}, &struct{}{})
+	if !hasErrorCode(err, ErrorCodeValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestResolvesConfirmation(t *testing.T) {
+	cases := map[string]bool{
+		`{"jsonrpc":"2.0","id":1,"method":"keycard.Approve","params":[{"id":"42"}]}`: true,
+		` {"method":"keycard.Reject"}`:                                           true,
+		`{"jsonrpc":"2.0","id":1,"method":"keycard.FactoryReset","params":[{}]}`: false,
+		`[{"jsonrpc":"2.0","id":1,"method":"keycard.Approve"}]`:                  false,
+		`{"method":"Approve"}`:                                                   false,
+		`not json`:                                                               false,
+	}
+
+	for payload, expected := range cases {
+		if ResolvesConfirmation([]byte(payload)) != expected {
+			t.Errorf("%s: expected %v", payload, expected)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"github.com/status-im/status-keycard-go/internal"
+)
+
+// Error is returned by all KeycardService methods.
+// In JSON-RPC responses it's encoded as the error object: `{"code": -32009, "message": "...", "data": {...}}`.
+type Error = internal.Error
+type ErrorCode = internal.ErrorCode
+type ErrorData = internal.ErrorData
+type FieldError = internal.FieldError
+
+const (
+	ErrorCodeParse          = internal.ErrorCodeParse
+	ErrorCodeInvalidRequest = internal.ErrorCodeInvalidRequest
+	ErrorCodeMethodNotFound = internal.ErrorCodeMethodNotFound
+	ErrorCodeInvalidParams  = internal.ErrorCodeInvalidParams
+	ErrorCodeRPCInternal    = internal.ErrorCodeRPCInternal
+
+	ErrorCodeInternal       = internal.ErrorCodeInternal
+	ErrorCodeNotStarted     = internal.ErrorCodeNotStarted
+	ErrorCodeAlreadyStarted = internal.ErrorCodeAlreadyStarted
+	ErrorCodeNotConnected   = internal.ErrorCodeNotConnected
+	ErrorCodeNotInitialized = internal.ErrorCodeNotInitialized
+	ErrorCodeNotReady       = internal.ErrorCodeNotReady
+	ErrorCodeNotAuthorized  = internal.ErrorCodeNotAuthorized
+	ErrorCodeNotBlocked     = internal.ErrorCodeNotBlocked
+	ErrorCodeNoKeys         = internal.ErrorCodeNoKeys
+	ErrorCodeWrongPIN       = internal.ErrorCodeWrongPIN
+	ErrorCodeWrongPUK       = internal.ErrorCodeWrongPUK
+	ErrorCodeBlocked        = internal.ErrorCodeBlocked
+	ErrorCodeValidation     = internal.ErrorCodeValidation
+	ErrorCodeTransport      = internal.ErrorCodeTransport
+	ErrorCodeCard           = internal.ErrorCodeCard
+	ErrorCodeForbidden      = internal.ErrorCodeForbidden
+	ErrorCodeShuttingDown   = internal.ErrorCodeShuttingDown
+	ErrorCodeTimeout        = internal.ErrorCodeTimeout
+	ErrorCodeReauthRequired = internal.ErrorCodeReauthRequired
+	ErrorCodeRejected       = internal.ErrorCodeRejected
+)
+
+// AsError converts any error to an *Error, unknown errors get the `internal` code
+func AsError(err error) *Error {
+	return internal.AsError(err)
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/status-im/keycard-go"
+)
+
+// errorObject is the JSON-RPC error object, decoded without Error.UnmarshalJSON to check the encoding
+type errorObject struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Type              string `json:"type"`
+		RemainingAttempts *int   `json:"remainingAttempts"`
+		SW                string `json:"sw"`
+		Fields            []struct {
+			Field string `json:"field"`
+			Rule  string `json:"rule"`
+		} `json:"fields"`
+	} `json:"data"`
+}
+
+func TestErrorObject(t *testing.T) {
+	attempts := func(n int) *int { return &n }
+
+	testCases := []struct {
+		name              string
+		verifyPIN         []byte
+		method            string
+		params            string
+		code              ErrorCode
+		remainingAttempts *int
+		sw                string
+		field             string
+	}{
+		{
+			name:              "wrong PIN",
+			verifyPIN:         []byte{0x63, 0xC2},
+			method:            "Authorize",
+			params:            `{"pin":"000000"}`,
+			code:              ErrorCodeWrongPIN,
+			remainingAttempts: attempts(2),
+		},
+		{
+			name:              "last PIN attempt",
+			verifyPIN:         []byte{0x63, 0xC0},
+			method:            "Authorize",
+			params:            `{"pin":"000000"}`,
+			code:              ErrorCodeWrongPIN,
+			remainingAttempts: attempts(0),
+		},
+		{
+			name:      "card error",
+			verifyPIN: []byte{0x69, 0x85},
+			method:    "Authorize",
+			params:    `{"pin":"000000"}`,
+			code:      ErrorCodeCard,
+			sw:        "6985",
+		},
+		{
+			name:   "validation",
+			method: "ChangePIN",
+			params: `{"newPin":"123"}`,
+			code:   ErrorCodeValidation,
+			field:  "newPin",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			responses := map[byte][]byte{}
+			if tc.verifyPIN != nil {
+				responses[keycard.InsVerifyPIN] = tc.verifyPIN
+			}
+			server, err := NewRPCServer(startWithFakeCard(t, responses))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payload := `{"jsonrpc":"2.0","id":1,"method":"keycard.` + tc.method + `","params":[` + tc.params + `]}`
+			var response struct {
+				Result json.RawMessage `json:"result"`
+				Error  *errorObject    `json:"error"`
+			}
+			if err = json.Unmarshal(server.Call(context.Background(), []byte(payload)), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			rpcErr := response.Error
+			if rpcErr == nil {
+				t.Fatalf("expected an error, got result %s", response.Result)
+			}
+			if rpcErr.Code != int(tc.code) || rpcErr.Data.Type != tc.code.String() || rpcErr.Message == "" {
+				t.Fatalf("expected error %d (%s), got %+v", tc.code, tc.code.String(), rpcErr)
+			}
+			if (rpcErr.Data.RemainingAttempts == nil) != (tc.remainingAttempts == nil) ||
+				(tc.remainingAttempts != nil && *rpcErr.Data.RemainingAttempts != *tc.remainingAttempts) {
+				t.Fatalf("expected remaining attempts %v, got %v", tc.remainingAttempts, rpcErr.Data.RemainingAttempts)
+			}
+			if rpcErr.Data.SW != tc.sw {
+				t.Fatalf("expected sw %q, got %q", tc.sw, rpcErr.Data.SW)
+			}
+			if tc.field != "" && (len(rpcErr.Data.Fields) != 1 || rpcErr.Data.Fields[0].Field != tc.field) {
+				t.Fatalf("expected a validation error for %s, got %+v", tc.field, rpcErr.Data.Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package session
+
+import (
+	"github.com/status-im/status-keycard-go/internal"
+)
+
+// Status is the session status, sent with the `status-changed` signal and returned by GetStatus
+type Status = internal.Status
+type State = internal.State
+
+// StatusChangedEvent is published to the signal bus with each status change.
+// Subscribe with `signal.On(bus, func(e session.StatusChangedEvent) {...})`.
+type StatusChangedEvent = internal.StatusChangedEvent
+
+const (
+	UnknownReaderState      = internal.UnknownReaderState
+	NoPCSC                  = internal.NoPCSC
+	InternalError           = internal.InternalError
+	WaitingForReader        = internal.WaitingForReader
+	WaitingForCard          = internal.WaitingForCard
+	ConnectingCard          = internal.ConnectingCard
+	ConnectionError         = internal.ConnectionError
+	NotKeycard              = internal.NotKeycard
+	EmptyKeycard            = internal.EmptyKeycard
+	NoAvailablePairingSlots = internal.NoAvailablePairingSlots
+	PairingError            = internal.PairingError
+	BlockedPIN              = internal.BlockedPIN
+	BlockedPUK              = internal.BlockedPUK
+	Ready                   = internal.Ready
+	Authorized              = internal.Authorized
+	FactoryResetting        = internal.FactoryResetting
+)
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/pairing"
+)
+
+var (
+	testInstanceUID = bytes.Repeat([]byte{0x01}, 16)
+	testPairingKey  = bytes.Repeat([]byte{0x42}, 32)
+)
+
+// fakeCard is an initialized keycard which opens a secure channel with testPairingKey.
+// Commands in the secure channel are answered with the response set for their instruction, or 9000.
+// GET STATUS reports 3 PIN and 5 PUK retries unless set otherwise.
+type fakeCard struct {
+	key       *ecdsa.PrivateKey
+	responses map[byte][]byte
+	encKey    []byte
+	macKey    []byte
+	iv        []byte
+}
+
+func newFakeCard(t *testing.T, responses map[byte][]byte) *fakeCard {
+	t.Helper()
+
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := responses[keycard.InsGetStatus]; !ok {
+		responses[keycard.InsGetStatus] = []byte{0xA3, 0x09, 0x02, 0x01, 0x03, 0x02, 0x01, 0x05, 0x01, 0x01, 0x00, 0x90, 0x00}
+	}
+	return &fakeCard{key: key, responses: responses}
+}
+
+func (c *fakeCard) Transmit(raw []byte) ([]byte, error) {
+	cmd, err := apdu.ParseCommand(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cmd.Cla == globalplatform.ClaISO7816 && cmd.Ins == globalplatform.InsSelect:
+		c.encKey = nil
+		return c.selectResponse(), nil
+	case cmd.Ins == keycard.InsOpenSecureChannel:
+		clientKey, err := ethcrypto.UnmarshalPubkey(cmd.Data)
+		if err != nil {
+			return nil, err
+		}
+		cardData := make([]byte, 48)
+		if _, err = rand.Read(cardData); err != nil {
+			return nil, err
+		}
+		secret := crypto.GenerateECDHSharedSecret(c.key, clientKey)
+		c.encKey, c.macKey, c.iv = crypto.DeriveSessionKeys(secret, testPairingKey, cardData)
+		return append(cardData, 0x90, 0x00), nil
+	case c.encKey == nil:
+		return []byte{0x6D, 0x00}, nil
+	}
+
+	commandMAC := cmd.Data[:16]
+	if _, err = crypto.DecryptData(cmd.Data[16:], c.encKey, c.iv); err != nil {
+		return nil, err
+	}
+
+	plainResponse := []byte{0x90, 0x00}
+	if response, ok := c.responses[cmd.Ins]; ok {
+		plainResponse = response
+	}
+	if cmd.Ins == keycard.InsMutuallyAuthenticate {
+		plainResponse = append(make([]byte, 32), plainResponse...)
+	}
+
+	encrypted, err := crypto.EncryptData(plainResponse, c.encKey, commandMAC)
+	if err != nil {
+		return nil, err
+	}
+	meta := make([]byte, 16)
+	meta[0] = byte(16 + len(encrypted))
+	c.iv, err = crypto.CalculateMac(meta, encrypted, c.macKey)
+	if err != nil {
+		return nil, err
+	}
+
+	response := append(append([]byte{}, c.iv...), encrypted...)
+	return append(response, 0x90, 0x00), nil
+}
+
+// selectResponse is the application info of an initialized keycard with testInstanceUID
+func (c *fakeCard) selectResponse() []byte {
+	tlv := func(tag byte, value ...byte) []byte {
+		return append([]byte{tag, byte(len(value))}, value...)
+	}
+
+	var info []byte
+	info = append(info, tlv(0x8F, testInstanceUID...)...)
+	info = append(info, tlv(0x80, ethcrypto.FromECDSAPub(&c.key.PublicKey)...)...)
+	info = append(info, tlv(0x02, 0x03, 0x01)...)
+	info = append(info, tlv(0x02, 0x05)...)
+	info = append(info, tlv(0x8E)...)
+	info = append(info, tlv(0x8D, 0xFF)...)
+	return append(tlv(0xA4, info...), 0x90, 0x00)
+}
+
+// startWithFakeCard starts a service connected to a paired fakeCard, and waits for the card to be ready
+func startWithFakeCard(t *testing.T, responses map[byte][]byte, options ...Option) *KeycardService {
+	t.Helper()
+
+	s, _ := newTestService(t, append([]Option{WithTransport(newFakeCard(t, responses))}, options...)...)
+
+	storageFilePath := filepath.Join(t.TempDir(), "pairings.json")
+	store, err := pairing.NewStore(storageFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Store(hex.EncodeToString(testInstanceUID), &pairing.Info{Key: testPairingKey, Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startTestService(t, s, &StartRequest{StorageFilePath: storageFilePath})
+	waitForState(t, s, internal.Ready)
+	return s
+}
+
+// waitForState polls the service status until it reaches the state
+func waitForState(t *testing.T, s *KeycardService, state internal.State) {
+	t.Helper()
+
+	var status internal.Status
+	deadline := time.Now().Add(time.Second)
+	for status.State != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the %s state, got %s", state, status.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := s.GetStatus(nil, &struct{}{}, &status); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
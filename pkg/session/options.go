@@ -0,0 +1,50 @@
+package session
+
+import (
+	"github.com/status-im/keycard-go/io"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-keycard-go/pkg/metrics"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+type Option func(*KeycardService)
+
+// WithLogger logs with the given logger. The logging fields of StartRequest are ignored.
+func WithLogger(logger *zap.Logger) Option {
+	return func(s *KeycardService) {
+		s.logger = logger
+	}
+}
+
+// WithConfirmer asks the confirmer to approve private key exports and factory reset.
+// StartRequest.ConfirmationTimeoutMs is ignored.
+func WithConfirmer(confirmer Confirmer) Option {
+	return func(s *KeycardService) {
+		s.confirmer = confirmer
+	}
+}
+
+// WithSignalBus publishes the service signals to the given bus, signal.Default by default.
+// Use a separate bus for each service to keep their signals apart.
+func WithSignalBus(bus *signal.Bus) Option {
+	return func(s *KeycardService) {
+		s.bus = bus
+	}
+}
+
+// WithMetrics records the service metrics to m, metrics.Default by default.
+// Signals dropped for slow subscribers are recorded to the metrics of the bus, see signal.WithMetrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *KeycardService) {
+		s.metrics = m
+	}
+}
+
+// WithTransport exchanges APDUs through the given transport instead of PC/SC, e.g. to replay a trace with trace.Replayer.
+// The keycard is connected once on Start, StartRequest.Reader is ignored.
+func WithTransport(transport io.Transmitter) Option {
+	return func(s *KeycardService) {
+		s.transport = transport
+	}
+}
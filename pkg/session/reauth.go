@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// DefaultSensitiveMethods require re-authentication when ReauthPolicy.Methods is empty
+var DefaultSensitiveMethods = []string{
+	"ExportLoginKeys",
+	"ExportRecoverKeys",
+	"LoadMnemonic",
+	"ChangePUK",
+	"FactoryReset",
+}
+
+// reauthExemptStates lists, per sensitive method, the states in which the PIN can't be verified
+// and the method is allowed anyway. Sensitive methods fail in any other state than `ready` and `authorized`.
+var reauthExemptStates = map[string][]internal.State{
+	// Factory reset is the way out for keycards without a usable PIN or pairing
+	"FactoryReset": {
+		internal.EmptyKeycard,
+		internal.NoAvailablePairingSlots,
+		internal.PairingError,
+		internal.BlockedPIN,
+		internal.BlockedPUK,
+	},
+}
+
+// ReauthPolicy requires a recent PIN verification for sensitive methods.
+// Instead, a sensitive request can include the PIN in its `pin` param, which is verified first.
+// When the keycard state is neither `ready` nor `authorized`, the PIN can't be verified and sensitive
+// methods fail, except in the states where the method is exempt: e.g. a keycard with a blocked PIN
+// can still be factory reset.
+type ReauthPolicy struct {
+	// Methods lists the sensitive methods, e.g. "ExportLoginKeys". DefaultSensitiveMethods when empty.
+	Methods []string `json:"methods,omitempty"`
+
+	// MaxAgeMs is how long a PIN verification is valid for sensitive methods.
+	// When zero, sensitive requests must always include the PIN.
+	MaxAgeMs int64 `json:"maxAgeMs"`
+}
+
+func (p *ReauthPolicy) requires(method string) bool {
+	if p == nil {
+		return false
+	}
+
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = DefaultSensitiveMethods
+	}
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinVerifier is the part of the keycard context the re-authentication policy depends on
+type pinVerifier interface {
+	GetStatus() internal.Status
+	VerifyPIN(ctx context.Context, pin utils.Secret) (error, bool)
+	PINVerifiedAt() time.Time
+}
+
+// reauthorize enforces the re-authentication policy for the method, see ReauthPolicy
+func (s *KeycardService) reauthorize(ctx context.Context, kc pinVerifier, method string, pin utils.Secret) error {
+	s.lock.RLock()
+	policy := s.reauthPolicy
+	s.lock.RUnlock()
+
+	if !policy.requires(method) {
+		return nil
+	}
+
+	state := kc.GetStatus().State
+	if state != internal.Ready && state != internal.Authorized {
+		if reauthExempt(method, state) {
+			return nil
+		}
+		return internal.NewError(internal.ErrorCodeReauthRequired, "PIN can't be verified for "+method+" in state "+string(state))
+	}
+
+	if len(pin) > 0 {
+		err, _ := kc.VerifyPIN(ctx, pin)
+		return err
+	}
+
+	verifiedAt := kc.PINVerifiedAt()
+	maxAge := time.Duration(policy.MaxAgeMs) * time.Millisecond
+	if !verifiedAt.IsZero() && time.Since(verifiedAt) <= maxAge {
+		return nil
+	}
+
+	return internal.NewError(internal.ErrorCodeReauthRequired, "PIN verification required for "+method)
+}
+
+func reauthExempt(method string, state internal.State) bool {
+	for m, states := range reauthExemptStates {
+		if !strings.EqualFold(m, method) {
+			continue
+		}
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+	}
+	return false
+}
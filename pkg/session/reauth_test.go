@@ -0,0 +1,210 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// testPINVerifier accepts the PIN "123456"
+type testPINVerifier struct {
+	state      internal.State
+	verifiedAt time.Time
+	verified   []string
+}
+
+func (v *testPINVerifier) GetStatus() internal.Status {
+	return internal.Status{State: v.state}
+}
+
+func (v *testPINVerifier) VerifyPIN(ctx context.Context, pin utils.Secret) (error, bool) {
+	v.verified = append(v.verified, string(pin.Value()))
+	if string(pin.Value()) != "123456" {
+		return internal.NewError(internal.ErrorCodeWrongPIN, "wrong PIN"), false
+	}
+	v.verifiedAt = time.Now()
+	return nil, true
+}
+
+func (v *testPINVerifier) PINVerifiedAt() time.Time {
+	return v.verifiedAt
+}
+
+func TestReauthorize(t *testing.T) {
+	recently := time.Now().Add(-time.Second)
+	longAgo := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name       string
+		policy     *ReauthPolicy
+		method     string
+		state      internal.State
+		verifiedAt time.Time
+		pin        string
+		err        ErrorCode
+		verified   bool
+	}{
+		{
+			name:   "no policy",
+			method: "FactoryReset",
+			state:  internal.Ready,
+		},
+		{
+			name:   "not sensitive",
+			policy: &ReauthPolicy{MaxAgeMs: 60000},
+			method: "GetMetadata",
+			state:  internal.Ready,
+		},
+		{
+			name:   "never verified",
+			policy: &ReauthPolicy{MaxAgeMs: 60000},
+			method: "ExportLoginKeys",
+			state:  internal.Authorized,
+			err:    ErrorCodeReauthRequired,
+		},
+		{
+			name:       "verified within max age",
+			policy:     &ReauthPolicy{MaxAgeMs: 60000},
+			method:     "ExportLoginKeys",
+			state:      internal.Authorized,
+			verifiedAt: recently,
+		},
+		{
+			name:       "verified before max age",
+			policy:     &ReauthPolicy{MaxAgeMs: 60000},
+			method:     "ExportLoginKeys",
+			state:      internal.Authorized,
+			verifiedAt: longAgo,
+			err:        ErrorCodeReauthRequired,
+		},
+		{
+			name:       "zero max age",
+			policy:     &ReauthPolicy{},
+			method:     "ChangePUK",
+			state:      internal.Authorized,
+			verifiedAt: time.Now(),
+			err:        ErrorCodeReauthRequired,
+		},
+		{
+			name:       "PIN in request",
+			policy:     &ReauthPolicy{},
+			method:     "ChangePUK",
+			state:      internal.Authorized,
+			verifiedAt: longAgo,
+			pin:        "123456",
+			verified:   true,
+		},
+		{
+			name:     "wrong PIN in request",
+			policy:   &ReauthPolicy{MaxAgeMs: 60000},
+			method:   "LoadMnemonic",
+			state:    internal.Ready,
+			pin:      "000000",
+			err:      ErrorCodeWrongPIN,
+			verified: true,
+		},
+		{
+			name:   "custom methods",
+			policy: &ReauthPolicy{Methods: []string{"exportrecoverkeys"}},
+			method: "ExportRecoverKeys",
+			state:  internal.Authorized,
+			err:    ErrorCodeReauthRequired,
+		},
+		{
+			name:   "default method not in custom methods",
+			policy: &ReauthPolicy{Methods: []string{"ExportRecoverKeys"}},
+			method: "FactoryReset",
+			state:  internal.Authorized,
+		},
+		{
+			name:   "PIN can't be verified",
+			policy: &ReauthPolicy{},
+			method: "FactoryReset",
+			state:  internal.BlockedPIN,
+		},
+		{
+			name:   "PIN in request can't be verified",
+			policy: &ReauthPolicy{},
+			method: "ExportLoginKeys",
+			state:  internal.ConnectionError,
+			pin:    "123456",
+			err:    ErrorCodeReauthRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			s.reauthPolicy = tc.policy
+			kc := &testPINVerifier{state: tc.state, verifiedAt: tc.verifiedAt}
+
+			err := s.reauthorize(context.Background(), kc, tc.method, utils.Secret(tc.pin))
+			if tc.err == 0 && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tc.err != 0 && !hasErrorCode(err, tc.err) {
+				t.Fatalf("expected error %d, got %v", tc.err, err)
+			}
+			if verified := len(kc.verified) > 0; verified != tc.verified {
+				t.Fatalf("expected PIN verified %v, got %v", tc.verified, verified)
+			}
+		})
+	}
+}
+
+// TestReauthorizeFailsClosed checks every sensitive method in every state in which the PIN can't be verified
+func TestReauthorizeFailsClosed(t *testing.T) {
+	states := []internal.State{
+		internal.UnknownReaderState,
+		internal.NoPCSC,
+		internal.InternalError,
+		internal.WaitingForReader,
+		internal.WaitingForCard,
+		internal.ConnectingCard,
+		internal.ConnectionError,
+		internal.NotKeycard,
+		internal.EmptyKeycard,
+		internal.NoAvailablePairingSlots,
+		internal.PairingError,
+		internal.BlockedPIN,
+		internal.BlockedPUK,
+		internal.FactoryResetting,
+	}
+
+	exempt := map[string][]internal.State{
+		"FactoryReset": {
+			internal.EmptyKeycard,
+			internal.NoAvailablePairingSlots,
+			internal.PairingError,
+			internal.BlockedPIN,
+			internal.BlockedPUK,
+		},
+	}
+
+	s, _ := newTestService(t)
+	s.reauthPolicy = &ReauthPolicy{MaxAgeMs: 60000}
+
+	for _, method := range DefaultSensitiveMethods {
+		for _, state := range states {
+			allowed := false
+			for _, exemptState := range exempt[method] {
+				allowed = allowed || exemptState == state
+			}
+
+			kc := &testPINVerifier{state: state, verifiedAt: time.Now()}
+			err := s.reauthorize(context.Background(), kc, method, nil)
+			if allowed && err != nil {
+				t.Fatalf("%s in %s: unexpected error %v", method, state, err)
+			}
+			if !allowed && !hasErrorCode(err, ErrorCodeReauthRequired) {
+				t.Fatalf("%s in %s: expected the reauth-required error, got %v", method, state, err)
+			}
+			if len(kc.verified) > 0 {
+				t.Fatalf("%s in %s: the PIN shouldn't be verified", method, state)
+			}
+		}
+	}
+}
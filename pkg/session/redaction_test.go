@@ -0,0 +1,123 @@
+package session
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// Distinct values, so that any of them can be found in the logs
+const (
+	testPIN             = "135791"
+	testNewPIN          = "246802"
+	testPUK             = "975318642013"
+	testPairingPassword = "pairing-secret-5f1e"
+	testMnemonic        = "abandon ability able about above absent absorb abstract absurd abuse access accident"
+	testPassphrase      = "passphrase-9c2d"
+)
+
+var testPrivateKey = utils.SecretHexString{0xde, 0xad, 0xbe, 0xef, 0x01, 0x23}
+
+var testSecrets = []string{testPIN, testNewPIN, testPUK, testPairingPassword, testMnemonic, testPassphrase, hex.EncodeToString(testPrivateKey)}
+
+// loggedValues returns every request and response type that holds secrets
+func loggedValues() map[string]interface{} {
+	keyPair := func() *internal.KeyPair {
+		return &internal.KeyPair{Address: "0x01", PublicKey: utils.HexString{0x04}, PrivateKey: testPrivateKey}
+	}
+
+	return map[string]interface{}{
+		"InitializeRequest": &InitializeRequest{
+			PIN:             utils.Secret(testPIN),
+			PUK:             utils.Secret(testPUK),
+			PairingPassword: utils.Secret(testPairingPassword),
+		},
+		"AuthorizeRequest":    &AuthorizeRequest{PIN: utils.Secret(testPIN)},
+		"ChangePINRequest":    &ChangePINRequest{NewPIN: utils.Secret(testNewPIN)},
+		"ChangePUKRequest":    &ChangePUKRequest{NewPUK: utils.Secret(testPUK), PIN: utils.Secret(testPIN)},
+		"UnblockRequest":      &UnblockRequest{PUK: utils.Secret(testPUK), NewPIN: utils.Secret(testNewPIN)},
+		"FactoryResetRequest": &FactoryResetRequest{PIN: utils.Secret(testPIN)},
+		"ExportKeysRequest":   &ExportKeysRequest{PIN: utils.Secret(testPIN)},
+		"LoadMnemonicRequest": &LoadMnemonicRequest{
+			Mnemonic:   utils.Secret(testMnemonic),
+			Passphrase: utils.Secret(testPassphrase),
+			PIN:        utils.Secret(testPIN),
+		},
+		"ExportLoginKeysResponse": &ExportLoginKeysResponse{Keys: &internal.LoginKeys{
+			EncryptionPrivateKey: keyPair(),
+			WhisperPrivateKey:    keyPair(),
+		}},
+		"ExportRecoveredKeysResponse": &ExportRecoveredKeysResponse{Keys: &internal.RecoverKeys{
+			LoginKeys: internal.LoginKeys{EncryptionPrivateKey: keyPair(), WhisperPrivateKey: keyPair()},
+			MasterKey: keyPair(),
+		}},
+	}
+}
+
+func assertNoSecrets(t *testing.T, name string, output string) {
+	t.Helper()
+
+	for _, secret := range testSecrets {
+		if strings.Contains(output, secret) {
+			t.Errorf("%s: secret %q found in %s", name, secret, output)
+		}
+	}
+}
+
+func TestSecretsRedactedInLogs(t *testing.T) {
+	var buffer bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buffer), zap.DebugLevel)
+	logger := zap.New(core)
+
+	for name, value := range loggedValues() {
+		buffer.Reset()
+		logger.Info("any", zap.Any("value", value))
+		logger.Info("reflect", zap.Reflect("value", value))
+		logger.Sugar().Infow("sugar", "value", value)
+		logger.Info("stringer", zap.String("value", fmt.Sprintf("%v %+v %#v %s", value, value, value, value)))
+		_ = logger.Sync()
+		assertNoSecrets(t, name, buffer.String())
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertNoSecrets(t, name, string(data))
+	}
+}
+
+func TestSecretsRevealedOverRPC(t *testing.T) {
+	for name, value := range loggedValues() {
+		data, err := utils.MarshalRevealingSecrets(successResponse{Version: jsonRPCVersion, Result: value, ID: json.RawMessage("1")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte(utils.Redacted)) {
+			t.Fatalf("%s: secrets redacted: %s", name, data)
+		}
+
+		// The payload is decoded back to the same value
+		response := struct {
+			Result interface{} `json:"result"`
+		}{
+			Result: reflect.New(reflect.TypeOf(value).Elem()).Interface(),
+		}
+		err = json.Unmarshal(data, &response)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(response.Result, value) {
+			t.Fatalf("%s: decoded %+v", name, response.Result)
+		}
+	}
+}
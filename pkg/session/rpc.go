@@ -1,17 +1,350 @@
 package session
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/gorilla/rpc"
-	"github.com/gorilla/rpc/json"
-)
 
-var (
-	globalKeycardService KeycardService
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/metrics"
+	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
-func CreateRPCServer() (*rpc.Server, error) {
+const serviceName = "keycard"
+
+type rpcCallKey struct{}
+
+// rpcCall carries a single request through gorilla/rpc dispatching
+type rpcCall struct {
+	request   *serverRequest
+	paramsErr error
+	reply     interface{}
+	methodErr error
+	done      bool
+}
+
+// RPCServer serves the KeycardService over JSON-RPC 2.0, including notifications and batches.
+// Method lookup and dispatching is done by gorilla/rpc.
+type RPCServer struct {
+	server  *rpc.Server
+	service *KeycardService
+
+	// callsLock protects stopping, so that no call is started after Shutdown
+	callsLock sync.Mutex
+	calls     sync.WaitGroup
+	stopping  bool
+
+	// callsContext is cancelled when Shutdown gives up waiting for calls in progress
+	callsContext context.Context
+	cancelCalls  context.CancelFunc
+}
+
+// CreateRPCServer serves a new KeycardService, which publishes signals to signal.Default
+func CreateRPCServer() (*RPCServer, error) {
+	return NewRPCServer(NewKeycardService())
+}
+
+// NewRPCServer serves the given service. Each service should be served by a single RPCServer.
+func NewRPCServer(service *KeycardService) (*RPCServer, error) {
 	rpcServer := rpc.NewServer()
-	rpcServer.RegisterCodec(json.NewCodec(), "application/json")
-	err := rpcServer.RegisterTCPService(&globalKeycardService, "keycard")
-	return rpcServer, err
+	rpcServer.RegisterCodec(&codec{}, "application/json")
+	err := rpcServer.RegisterService(service, serviceName)
+	callsContext, cancelCalls := context.WithCancel(context.Background())
+	return &RPCServer{
+		server:       rpcServer,
+		service:      service,
+		callsContext: callsContext,
+		cancelCalls:  cancelCalls,
+	}, err
+}
+
+// Service returns the served KeycardService
+func (s *RPCServer) Service() *KeycardService {
+	return s.service
+}
+
+// Shutdown stops accepting new calls, waits for the calls in progress and stops the keycard service.
+// Calls in progress, including the ones waiting for a confirmation, are allowed to finish
+// until ctx is done, then they're cancelled together with the card command.
+func (s *RPCServer) Shutdown(ctx context.Context) error {
+	s.callsLock.Lock()
+	s.stopping = true
+	s.callsLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.calls.Wait()
+		close(done)
+	}()
+
+	var waitErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		waitErr = errors.New("timed out waiting for calls in progress")
+		s.cancelCalls()
+	}
+
+	err := s.service.Shutdown(ctx)
+	if err != nil {
+		return err
+	}
+	return waitErr
+}
+
+// Ready returns an error when the server is shutting down or the keycard service is not ready
+func (s *RPCServer) Ready() error {
+	s.callsLock.Lock()
+	stopping := s.stopping
+	s.callsLock.Unlock()
+
+	if stopping {
+		return internal.NewError(internal.ErrorCodeShuttingDown, "server is shutting down")
+	}
+	return s.service.Ready()
+}
+
+func (s *RPCServer) startCall() bool {
+	s.callsLock.Lock()
+	defer s.callsLock.Unlock()
+
+	if s.stopping {
+		return false
+	}
+	s.calls.Add(1)
+	return true
+}
+
+// ServeHTTP implements http.Handler. Responds with 204 when the payload contains only notifications.
+func (s *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "rpc: POST method required, received "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(r.Body)
+	defer utils.Wipe(payload)
+	if err != nil {
+		http.Error(w, "rpc: failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	response := s.Call(r.Context(), payload)
+	defer utils.Wipe(response)
+
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = w.Write(response)
+}
+
+// Call executes a single request or a batch and returns the encoded response.
+// Returns nil when there is nothing to respond with, i.e. all requests were notifications.
+// The caller owns both buffers and should wipe them, as they may contain secrets.
+func (s *RPCServer) Call(ctx context.Context, payload []byte) []byte {
+	if !json.Valid(payload) {
+		return encodeError(null, internal.NewError(internal.ErrorCodeParse, "invalid JSON"))
+	}
+
+	switch firstByte(payload) {
+	case '[':
+		return s.callBatch(ctx, payload)
+	case '{':
+		request, rpcErr := parseRequest(payload)
+		defer request.wipe()
+		return s.respond(ctx, request, rpcErr)
+	default:
+		return encodeError(null, internal.NewError(internal.ErrorCodeInvalidRequest, "request must be an object or an array"))
+	}
+}
+
+func (s *RPCServer) callBatch(ctx context.Context, payload []byte) []byte {
+	var batch []json.RawMessage
+	err := json.Unmarshal(payload, &batch)
+	defer func() {
+		for _, raw := range batch {
+			utils.Wipe(raw)
+		}
+	}()
+
+	if err != nil {
+		return encodeError(null, internal.NewError(internal.ErrorCodeInvalidRequest, "invalid batch"))
+	}
+	if len(batch) == 0 {
+		return encodeError(null, internal.NewError(internal.ErrorCodeInvalidRequest, "empty batch"))
+	}
+
+	var buffer bytes.Buffer
+	for _, raw := range batch {
+		request, rpcErr := parseRequest(raw)
+		response := s.respond(ctx, request, rpcErr)
+		request.wipe()
+
+		if response == nil {
+			continue
+		}
+		if buffer.Len() == 0 {
+			buffer.WriteByte('[')
+		} else {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(response)
+		utils.Wipe(response)
+	}
+
+	if buffer.Len() == 0 {
+		return nil
+	}
+	buffer.WriteByte(']')
+
+	// Copy to a buffer of exact size, so that the caller can wipe all of it
+	response := bytes.Clone(buffer.Bytes())
+	b := buffer.Bytes()
+	utils.Wipe(b[:cap(b)])
+	return response
+}
+
+// respond dispatches a single parsed request and encodes the response
+func (s *RPCServer) respond(ctx context.Context, request *serverRequest, rpcErr *Error) []byte {
+	if rpcErr != nil {
+		// Invalid requests are always responded to, even if they look like notifications
+		id := request.ID
+		if id == nil {
+			id = null
+		}
+		return encodeError(id, rpcErr)
+	}
+
+	call, rpcErr := s.dispatch(ctx, request)
+	defer utils.WipeSecrets(call.reply)
+
+	if request.isNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return encodeError(request.ID, rpcErr)
+	}
+	if call.methodErr != nil {
+		return encodeError(request.ID, internal.AsError(call.methodErr))
+	}
+
+	response, err := utils.MarshalRevealingSecrets(successResponse{
+		Version: jsonRPCVersion,
+		Result:  call.reply,
+		ID:      request.ID,
+	})
+	if err != nil {
+		return encodeError(request.ID, internal.NewError(internal.ErrorCodeRPCInternal, "failed to encode result"))
+	}
+	return response
 }
+
+func (s *RPCServer) dispatch(ctx context.Context, request *serverRequest) (*rpcCall, *Error) {
+	call := &rpcCall{request: request}
+
+	if !s.server.HasMethod(request.Method) {
+		return call, internal.NewError(internal.ErrorCodeMethodNotFound, "method not found: "+request.Method)
+	}
+
+	if scope, ok := ScopeFromContext(ctx); ok && !scope.Allows(request.Method) {
+		return call, internal.NewError(internal.ErrorCodeForbidden, "method not allowed with scope "+string(scope))
+	}
+
+	timeout, rpcErr := request.timeout()
+	if rpcErr != nil {
+		return call, rpcErr
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if !s.startCall() {
+		return call, internal.NewError(internal.ErrorCodeShuttingDown, "server is shutting down")
+	}
+	defer s.calls.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.callsContext, cancel)
+	defer stop()
+
+	started := time.Now()
+	defer call.observe(s.service.metrics, started)
+
+	ctx = context.WithValue(ctx, rpcCallKey{}, call)
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/rpc", http.NoBody)
+	if err != nil {
+		return call, internal.NewError(internal.ErrorCodeRPCInternal, err.Error())
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	s.server.ServeHTTP(discardResponseWriter{}, r)
+
+	if call.paramsErr != nil {
+		return call, invalidParamsError(call.paramsErr)
+	}
+	if !call.done {
+		return call, internal.NewError(internal.ErrorCodeRPCInternal, "method was not executed")
+	}
+	return call, nil
+}
+
+// observe records the call latency and result in metrics.
+// Only registered methods are recorded, to keep the number of label values bounded.
+func (c *rpcCall) observe(m *metrics.Metrics, started time.Time) {
+	result := "ok"
+	switch {
+	case c.paramsErr != nil:
+		result = invalidParamsError(c.paramsErr).Data.Type
+	case !c.done:
+		result = internal.ErrorCodeRPCInternal.String()
+	case c.methodErr != nil:
+		result = internal.AsError(c.methodErr).Data.Type
+	}
+
+	m.RPCDuration.Observe(time.Since(started), c.request.Method)
+	m.RPCCalls.Inc(c.request.Method, result)
+}
+
+func invalidParamsError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return internal.NewError(internal.ErrorCodeInvalidParams, err.Error())
+}
+
+func encodeError(id json.RawMessage, rpcErr *Error) []byte {
+	response, _ := json.Marshal(errorResponse{
+		Version: jsonRPCVersion,
+		Error:   rpcErr,
+		ID:      id,
+	})
+	return response
+}
+
+// discardResponseWriter drops everything gorilla/rpc writes, responses are encoded by RPCServer
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardResponseWriter) WriteHeader(int) {}
@@ -0,0 +1,277 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/status-im/keycard-go"
+
+	"github.com/status-im/status-keycard-go/pkg/metrics"
+)
+
+// blockingConfirmer signals each confirmation and waits for a decision or ctx
+type blockingConfirmer struct {
+	requests chan ConfirmationRequest
+	decision chan error
+}
+
+func newBlockingConfirmer() *blockingConfirmer {
+	return &blockingConfirmer{
+		requests: make(chan ConfirmationRequest, 1),
+		decision: make(chan error, 1),
+	}
+}
+
+func (c *blockingConfirmer) Confirm(ctx context.Context, request ConfirmationRequest) error {
+	c.requests <- request
+	select {
+	case err := <-c.decision:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *blockingConfirmer) waitRequest(t *testing.T) ConfirmationRequest {
+	t.Helper()
+	select {
+	case request := <-c.requests:
+		return request
+	case <-time.After(time.Second):
+		t.Fatal("no confirmation request")
+		return ConfirmationRequest{}
+	}
+}
+
+// callAsync calls the method in a goroutine, the response is sent to the returned channel
+func callAsync(server *RPCServer, method string, params string) <-chan rpcTestResponse {
+	responses := make(chan rpcTestResponse, 1)
+	go func() {
+		payload := `{"jsonrpc":"2.0","id":1,"method":"keycard.` + method + `","params":[` + params + `]}`
+		var response rpcTestResponse
+		_ = json.Unmarshal(server.Call(context.Background(), []byte(payload)), &response)
+		responses <- response
+	}()
+	return responses
+}
+
+type rpcTestResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func TestShutdownWaitsForCalls(t *testing.T) {
+	confirmer := newBlockingConfirmer()
+	service, _ := newTestService(t, WithConfirmer(confirmer))
+	startTestService(t, service, &StartRequest{})
+	server, err := NewRPCServer(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := callAsync(server, "FactoryReset", "{}")
+	confirmer.waitRequest(t)
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- server.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown didn't wait for the call waiting for confirmation")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := service.started(); err != nil {
+		t.Fatal("service stopped before the call in progress finished")
+	}
+
+	confirmer.decision <- nil
+	response := <-responses
+	if response.Error == nil || response.Error.Code == ErrorCodeNotStarted {
+		t.Fatalf("expected the card error of a started service, got %+v", response.Error)
+	}
+
+	err = <-shutdownDone
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.started(); err == nil {
+		t.Fatal("service not stopped")
+	}
+}
+
+func TestShutdownCancelsCalls(t *testing.T) {
+	confirmer := newBlockingConfirmer()
+	service, _ := newTestService(t, WithConfirmer(confirmer))
+	startTestService(t, service, &StartRequest{})
+	server, err := NewRPCServer(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := callAsync(server, "ExportLoginKeys", "{}")
+	confirmer.waitRequest(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = server.Shutdown(ctx)
+	if err == nil {
+		t.Fatal("expected an error for the call still in progress")
+	}
+
+	select {
+	case response := <-responses:
+		if response.Error == nil || response.Error.Code != ErrorCodeTimeout {
+			t.Fatalf("expected a timeout error, got %+v", response.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call not cancelled")
+	}
+
+	response := <-callAsync(server, "GetStatus", "")
+	if response.Error == nil || response.Error.Code != ErrorCodeShuttingDown {
+		t.Fatalf("expected a shutting down error, got %+v", response.Error)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	confirmer := newBlockingConfirmer()
+	service, _ := newTestService(t, WithConfirmer(confirmer))
+	startTestService(t, service, &StartRequest{})
+	server, err := NewRPCServer(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := callAsync(server, "ExportLoginKeys", `{"timeoutMs": 50}`)
+	confirmer.waitRequest(t)
+
+	select {
+	case response := <-responses:
+		if response.Error == nil || response.Error.Code != ErrorCodeTimeout {
+			t.Fatalf("expected a timeout error, got %+v", response.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call not timed out")
+	}
+
+	// The service is still usable
+	response := <-callAsync(server, "GetStatus", "")
+	if response.Error != nil {
+		t.Fatalf("unexpected error %+v", response.Error)
+	}
+}
+
+func TestRequestTimeoutParam(t *testing.T) {
+	testCases := []struct {
+		params  string
+		timeout time.Duration
+		err     bool
+	}{
+		{params: `null`},
+		{params: `{}`},
+		{params: `[{"pin": "123456"}]`},
+		{params: `{"timeoutMs": 1500}`, timeout: 1500 * time.Millisecond},
+		{params: `[{"timeoutMs": 20}]`, timeout: 20 * time.Millisecond},
+		{params: `{"timeoutMs": 0}`, err: true},
+		{params: `{"timeoutMs": -1}`, err: true},
+		{params: `{"timeoutMs": "1000"}`, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.params, func(t *testing.T) {
+			request := &serverRequest{Params: json.RawMessage(tc.params)}
+			timeout, err := request.timeout()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if err != nil && err.Code != ErrorCodeInvalidParams {
+				t.Fatalf("expected an invalid params error, got %v", err)
+			}
+			if timeout != tc.timeout {
+				t.Fatalf("expected timeout %s, got %s", tc.timeout, timeout)
+			}
+		})
+	}
+}
+
+func TestScopeEnforcement(t *testing.T) {
+	s, _ := newTestService(t)
+	server, err := NewRPCServer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		method    string
+		forbidden bool
+	}{
+		{name: "no scope", ctx: context.Background(), method: "FactoryReset"},
+		{name: "read scope, read method", ctx: WithScope(context.Background(), ScopeRead), method: "GetMetadata"},
+		{name: "read scope, full method", ctx: WithScope(context.Background(), ScopeRead), method: "FactoryReset", forbidden: true},
+		{name: "full scope", ctx: WithScope(context.Background(), ScopeFull), method: "FactoryReset"},
+		{name: "unknown scope", ctx: WithScope(context.Background(), Scope("admin")), method: "GetStatus", forbidden: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload := `{"jsonrpc":"2.0","id":1,"method":"keycard.` + tc.method + `","params":[{}]}`
+			var response rpcTestResponse
+			if err := json.Unmarshal(server.Call(tc.ctx, []byte(payload)), &response); err != nil {
+				t.Fatal(err)
+			}
+			// The service isn't started, allowed calls fail with the `not-started` error
+			forbidden := response.Error != nil && response.Error.Code == ErrorCodeForbidden
+			if forbidden != tc.forbidden {
+				t.Fatalf("expected forbidden %v, got %+v", tc.forbidden, response.Error)
+			}
+		})
+	}
+}
+
+func TestMetricsPerService(t *testing.T) {
+	first, second := metrics.New(), metrics.New()
+	firstServer, err := NewRPCServer(startWithFakeCard(t, map[byte][]byte{keycard.InsVerifyPIN: {0x63, 0xC2}}, WithMetrics(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	startWithFakeCard(t, map[byte][]byte{}, WithMetrics(second))
+
+	payload := `{"jsonrpc":"2.0","id":1,"method":"keycard.Authorize","params":[{"pin":"000000"}]}`
+	firstServer.Call(context.Background(), []byte(payload))
+
+	expected := []struct {
+		metrics *metrics.Metrics
+		lines   []string
+	}{
+		{first, []string{
+			"keycard_pin_failures_total 1",
+			`keycard_rpc_calls_total{method="keycard.Authorize",result="wrong-pin"} 1`,
+			`keycard_state_transitions_total{state="ready"} 1`,
+		}},
+		{second, []string{
+			"keycard_pin_failures_total 0",
+			`keycard_state_transitions_total{state="ready"} 1`,
+		}},
+	}
+	for i, e := range expected {
+		var body strings.Builder
+		if err = e.metrics.WritePrometheus(&body); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range e.lines {
+			if !strings.Contains(body.String(), line+"\n") {
+				t.Errorf("service %d: missing line %q in:\n%s", i, line, body.String())
+			}
+		}
+		if i == 1 && strings.Contains(body.String(), "keycard_rpc_calls_total{") {
+			t.Errorf("service %d: unexpected RPC calls in:\n%s", i, body.String())
+		}
+	}
+}
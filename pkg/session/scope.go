@@ -0,0 +1,60 @@
+package session
+
+import (
+	"context"
+	"strings"
+)
+
+// Scope limits the methods a client is allowed to call
+type Scope string
+
+const (
+	// ScopeRead allows only reading the session status and keycard metadata
+	ScopeRead Scope = "read"
+	// ScopeFull allows all methods
+	ScopeFull Scope = "full"
+)
+
+// readMethods can be called with ScopeRead, all other methods require ScopeFull
+var readMethods = map[string]bool{
+	"GetStatus":   true,
+	"GetMetadata": true,
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context which limits the RPC methods to the given scope.
+// Calls with a context without scope are not limited, e.g. in-process calls through the C bindings.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope set with WithScope
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// ParseScope parses a scope name, returns false for unknown names
+func ParseScope(name string) (Scope, bool) {
+	switch Scope(name) {
+	case ScopeRead, ScopeFull:
+		return Scope(name), true
+	}
+	return "", false
+}
+
+// Allows reports whether the method, in "keycard.Method" or "Method" form, can be called with this scope
+func (s Scope) Allows(method string) bool {
+	switch s {
+	case ScopeFull:
+		return true
+	case ScopeRead:
+		name := method
+		if i := strings.LastIndex(method, "."); i >= 0 {
+			name = method[i+1:]
+		}
+		return readMethods[name]
+	}
+	return false
+}
@@ -1,22 +1,81 @@
 package session
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/pkg/errors"
+	"github.com/status-im/keycard-go/io"
+
+	"go.uber.org/zap"
 
 	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/audit"
+	"github.com/status-im/status-keycard-go/pkg/metrics"
 	"github.com/status-im/status-keycard-go/pkg/pairing"
+	"github.com/status-im/status-keycard-go/pkg/trace"
 	"github.com/status-im/status-keycard-go/pkg/utils"
+	"github.com/status-im/status-keycard-go/signal"
 )
 
 var (
-	errKeycardServiceNotStarted = errors.New("keycard service not started")
+	errKeycardServiceNotStarted = internal.NewError(internal.ErrorCodeNotStarted, "keycard service not started")
 )
 
+// KeycardService is the session API. Services are independent of each other, each has
+// its own PC/SC context, pairing store, logger, signal bus and metrics.
 type KeycardService struct {
+	logger    *zap.Logger
+	bus       *signal.Bus
+	metrics   *metrics.Metrics
+	transport io.Transmitter
+
+	// lifecycleLock serializes Start and Stop, so that a service is stopped completely before it's started again
+	lifecycleLock sync.Mutex
+
+	// lock protects the fields below, which are set by Start and cleared by Stop.
+	// Methods work with the keycard context returned by started, which stays valid after Stop.
+	lock           sync.RWMutex
 	keycardContext *internal.KeycardContextV2
+	tracer         *trace.Recorder
+	auditLog       *audit.Log
 	simulateError  error
+	reauthPolicy   *ReauthPolicy
+
+	// confirmer is set with WithConfirmer, signalConfirmer is enabled on Start
+	confirmer       Confirmer
+	signalConfirmer *SignalConfirmer
+}
+
+func NewKeycardService(options ...Option) *KeycardService {
+	s := &KeycardService{
+		bus:     signal.Default,
+		metrics: metrics.Default,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
 }
 
+// Bus returns the bus which the service publishes its signals to. Not exposed over RPC.
+func (s *KeycardService) Bus() *signal.Bus {
+	return s.bus
+}
+
+// Metrics returns the metrics which the service records to. Not exposed over RPC.
+func (s *KeycardService) Metrics() *metrics.Metrics {
+	return s.metrics
+}
+
+// ExportPolicy restricts the derivation paths which keys can be exported for
+type ExportPolicy = internal.ExportPolicy
+
 type StartRequest struct {
 	// StorageFilePath is the path to the file where the keycard pairings information is stored.
 	StorageFilePath string `json:"storageFilePath" validate:"required"`
@@ -26,11 +85,77 @@ type StartRequest struct {
 
 	// LogFilePath is the path to the log file. When empty, logs go to stdout.
 	LogFilePath string `json:"logFilePath,omitempty"`
+
+	// TraceFilePath is the path to the file where all APDUs exchanged with the keycard are recorded.
+	// When empty, tracing is disabled. See `pkg/trace` for the format and replay.
+	TraceFilePath string `json:"traceFilePath,omitempty"`
+
+	// TraceSecureChannel adds the decrypted secure channel traffic to the trace. Secrets are redacted.
+	TraceSecureChannel bool `json:"traceSecureChannel,omitempty"`
+
+	// Reader restricts the service to readers which name contains this string.
+	// When empty, all readers are used.
+	Reader string `json:"reader,omitempty"`
+
+	// AuthorizationIdleTimeoutMs deauthorizes the keycard when no command was executed for this long.
+	// Disabled when zero.
+	AuthorizationIdleTimeoutMs int64 `json:"authorizationIdleTimeoutMs,omitempty"`
+
+	// AuthorizationMaxDurationMs deauthorizes the keycard when it has been authorized for this long,
+	// no matter the activity. Disabled when zero.
+	AuthorizationMaxDurationMs int64 `json:"authorizationMaxDurationMs,omitempty"`
+
+	// Reauth requires a recent PIN verification for sensitive methods. Disabled when not set.
+	Reauth *ReauthPolicy `json:"reauth,omitempty"`
+
+	// ExportPolicy restricts the derivation paths which keys can be exported for. All paths allowed by the applet when not set.
+	ExportPolicy *ExportPolicy `json:"exportPolicy,omitempty"`
+
+	// AuditFilePath is the path to the hash-chained audit log of security-relevant card operations.
+	// When empty, auditing is disabled. See `pkg/audit` for the format and verification.
+	AuditFilePath string `json:"auditFilePath,omitempty"`
+
+	// AuditKey authenticates the audit log entries, at least 32 bytes. Required with AuditFilePath.
+	// Keep it apart from the log, e.g. in the OS keychain: it's needed to verify the log.
+	AuditKey utils.SecretHexString `json:"auditKey,omitempty"`
+
+	// ConfirmationTimeoutMs requires private key exports and factory reset to be approved with the Approve method,
+	// after the `confirmation-required` signal. They are rejected after this timeout. Disabled when zero.
+	ConfirmationTimeoutMs int64 `json:"confirmationTimeoutMs,omitempty"`
 }
 
-func (s *KeycardService) Start(args *StartRequest, reply *struct{}) error {
+// started returns the keycard context, or the `not-started` error
+func (s *KeycardService) started() (*internal.KeycardContextV2, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.keycardContext == nil {
+		return nil, errKeycardServiceNotStarted
+	}
+	return s.keycardContext, nil
+}
+
+func (s *KeycardService) Start(r *http.Request, args *StartRequest, reply *struct{}) (err error) {
+	// The audit log keeps its own copy of the key
+	defer utils.WipeSecrets(args)
+
+	s.lifecycleLock.Lock()
+	defer s.lifecycleLock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	if s.keycardContext != nil {
-		return errors.New("keycard service already started")
+		return internal.NewError(internal.ErrorCodeAlreadyStarted, "keycard service already started")
+	}
+
+	err = args.ExportPolicy.Validate()
+	if err != nil {
+		return internal.NewError(internal.ErrorCodeValidation, err.Error())
+	}
+
+	if args.AuditFilePath != "" && len(args.AuditKey) < audit.KeySize {
+		return internal.NewError(internal.ErrorCodeValidation, fmt.Sprintf("auditKey must be at least %d bytes", audit.KeySize))
 	}
 
 	pairingsStore, err := pairing.NewStore(args.StorageFilePath)
@@ -40,136 +165,262 @@ func (s *KeycardService) Start(args *StartRequest, reply *struct{}) error {
 
 	options := []internal.Option{
 		internal.WithStorage(pairingsStore),
-		internal.WithLogging(args.LogEnabled, args.LogFilePath),
+		internal.WithSignalBus(s.bus),
+		internal.WithMetrics(s.metrics),
+		internal.WithReader(args.Reader),
+		internal.WithExportPolicy(args.ExportPolicy),
+		internal.WithAuthorizationTimeouts(
+			time.Duration(args.AuthorizationIdleTimeoutMs)*time.Millisecond,
+			time.Duration(args.AuthorizationMaxDurationMs)*time.Millisecond,
+		),
+	}
+
+	if s.transport != nil {
+		options = append(options, internal.WithTransport(s.transport))
+	}
+
+	if s.logger != nil {
+		options = append(options, internal.WithLogger(s.logger))
+	} else {
+		options = append(options, internal.WithLogging(args.LogEnabled, args.LogFilePath))
+	}
+
+	defer func() {
+		if err != nil && s.keycardContext == nil {
+			_ = closeRecorders(s.tracer, s.auditLog)
+			s.tracer, s.auditLog = nil, nil
+		}
+	}()
+
+	if args.TraceFilePath != "" {
+		s.tracer, err = trace.NewRecorder(args.TraceFilePath, args.TraceSecureChannel)
+		if err != nil {
+			return errors.Wrap(err, "failed to create trace recorder")
+		}
+		options = append(options, internal.WithTrace(s.tracer))
 	}
 
-	s.keycardContext, err = internal.NewKeycardContextV2(options)
+	if args.AuditFilePath != "" {
+		s.auditLog, err = audit.NewLog(args.AuditFilePath, args.AuditKey)
+		if err != nil {
+			return errors.Wrap(err, "failed to open audit log")
+		}
+		options = append(options, internal.WithAuditLog(s.auditLog))
+	}
+
+	kc, err := internal.NewKeycardContextV2(options)
 	if err != nil {
 		return err
 	}
 
-	err = s.keycardContext.SimulateError(s.simulateError)
+	err = kc.SimulateError(s.simulateError)
 	if err != nil {
 		return err
 	}
 
-	return s.keycardContext.Start()
+	s.keycardContext = kc
+	s.reauthPolicy = args.Reauth
+	s.signalConfirmer = nil
+	if args.ConfirmationTimeoutMs > 0 {
+		s.signalConfirmer = NewSignalConfirmer(s.bus, time.Duration(args.ConfirmationTimeoutMs)*time.Millisecond)
+	}
+
+	// A failed start leaves the service started, with the `no-pcsc` status
+	return kc.Start()
 }
 
-func (s *KeycardService) Stop(args *struct{}, reply *struct{}) error {
-	if s.keycardContext == nil {
+func (s *KeycardService) Stop(r *http.Request, args *struct{}, reply *struct{}) error {
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown stops the service, same as Stop. A card command in progress is allowed
+// to finish until ctx is done, then it's cancelled. Not exposed over RPC.
+func (s *KeycardService) Shutdown(ctx context.Context) error {
+	s.lifecycleLock.Lock()
+	defer s.lifecycleLock.Unlock()
+
+	// The service is marked as stopped right away, without waiting for the command in progress.
+	// Calls still using the keycard context get the `not-connected` error.
+	s.lock.Lock()
+	kc, tracer, auditLog := s.keycardContext, s.tracer, s.auditLog
+	s.keycardContext, s.tracer, s.auditLog = nil, nil, nil
+	s.lock.Unlock()
+
+	if kc == nil {
 		return nil
 	}
-	s.keycardContext.Stop()
-	s.keycardContext = nil
+	err := kc.Shutdown(ctx)
+
+	recordersErr := closeRecorders(tracer, auditLog)
+	if err != nil {
+		return err
+	}
+	return recordersErr
+}
+
+// closeRecorders closes the trace recorder and the audit log, if any
+func closeRecorders(tracer *trace.Recorder, auditLog *audit.Log) error {
+	tracerErr := tracer.Close()
+	auditErr := auditLog.Close()
+
+	if tracerErr != nil {
+		return tracerErr
+	}
+	return auditErr
+}
+
+// Ready returns an error when the service is not started or PC/SC is not available. Not exposed over RPC.
+func (s *KeycardService) Ready() error {
+	kc, err := s.started()
+	if err != nil {
+		return err
+	}
+	if kc.GetStatus().State == internal.NoPCSC {
+		return internal.NewError(internal.ErrorCodeNotReady, "PC/SC not available")
+	}
 	return nil
 }
 
 // GetStatus should not be really used, as Status is pushed with `status-changed` signal.
 // But it's handy to have for debugging purposes.
-func (s *KeycardService) GetStatus(args *struct{}, reply *internal.Status) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) GetStatus(r *http.Request, args *struct{}, reply *internal.Status) error {
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	*reply = s.keycardContext.GetStatus()
+	*reply = kc.GetStatus()
 	return nil
 }
 
 type InitializeRequest struct {
-	PIN             string `json:"pin" validate:"required,len=6"`
-	PUK             string `json:"puk" validate:"required,len=12"`
-	PairingPassword string `json:"pairingPassword"`
+	PIN             utils.Secret `json:"pin" validate:"required,len=6"`
+	PUK             utils.Secret `json:"puk" validate:"required,len=12"`
+	PairingPassword utils.Secret `json:"pairingPassword"`
 }
 
-func (s *KeycardService) Initialize(args *InitializeRequest, reply *struct{}) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) Initialize(r *http.Request, args *InitializeRequest, reply *struct{}) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err := validateRequest(args)
+	err = validateRequest(args)
 	if err != nil {
 		return err
 	}
 
-	if args.PairingPassword == "" {
-		args.PairingPassword = internal.DefPairing
+	if len(args.PairingPassword) == 0 {
+		args.PairingPassword = utils.Secret(internal.DefPairing)
 	}
 
-	err = s.keycardContext.Initialize(args.PIN, args.PUK, args.PairingPassword)
+	err = kc.Initialize(r.Context(), args.PIN, args.PUK, args.PairingPassword)
 	return err
 }
 
 type AuthorizeRequest struct {
-	PIN string `json:"pin" validate:"required,len=6"`
+	PIN utils.Secret `json:"pin" validate:"required,len=6"`
 }
 
 type AuthorizeResponse struct {
 	Authorized bool `json:"authorized"`
 }
 
-func (s *KeycardService) Authorize(args *AuthorizeRequest, reply *AuthorizeResponse) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) Authorize(r *http.Request, args *AuthorizeRequest, reply *AuthorizeResponse) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err, authorized := s.keycardContext.VerifyPIN(args.PIN)
+	err, authorized := kc.VerifyPIN(r.Context(), args.PIN)
 	reply.Authorized = authorized
 	return err
 }
 
+// Deauthorize resets the secure channel, so that the PIN has to be verified again.
+// Does nothing when the keycard is not authorized.
+func (s *KeycardService) Deauthorize(r *http.Request, args *struct{}, reply *struct{}) error {
+	kc, err := s.started()
+	if err != nil {
+		return err
+	}
+
+	return kc.Deauthorize(r.Context())
+}
+
 type ChangePINRequest struct {
-	NewPIN string `json:"newPin" validate:"required,len=6"`
+	NewPIN utils.Secret `json:"newPin" validate:"required,len=6"`
 }
 
-func (s *KeycardService) ChangePIN(args *ChangePINRequest, reply *struct{}) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) ChangePIN(r *http.Request, args *ChangePINRequest, reply *struct{}) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err := validateRequest(args)
+	err = validateRequest(args)
 	if err != nil {
 		return err
 	}
 
-	err = s.keycardContext.ChangePIN(args.NewPIN)
+	err = kc.ChangePIN(r.Context(), args.NewPIN)
 	return err
 }
 
 type ChangePUKRequest struct {
-	NewPUK string `json:"newPuk" validate:"required,len=12"`
+	NewPUK utils.Secret `json:"newPuk" validate:"required,len=12"`
+
+	// PIN is verified first, when required by the re-authentication policy
+	PIN utils.Secret `json:"pin,omitempty" validate:"omitempty,len=6"`
 }
 
-func (s *KeycardService) ChangePUK(args *ChangePUKRequest, reply *struct{}) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) ChangePUK(r *http.Request, args *ChangePUKRequest, reply *struct{}) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err := validateRequest(args)
+	err = validateRequest(args)
+	if err != nil {
+		return err
+	}
+
+	err = s.reauthorize(r.Context(), kc, "ChangePUK", args.PIN)
 	if err != nil {
 		return err
 	}
 
-	err = s.keycardContext.ChangePUK(args.NewPUK)
+	err = kc.ChangePUK(r.Context(), args.NewPUK)
 	return err
 }
 
 type UnblockRequest struct {
-	PUK    string `json:"puk" validate:"required,len=12"`
-	NewPIN string `json:"newPin" validate:"required,len=6"`
+	PUK    utils.Secret `json:"puk" validate:"required,len=12"`
+	NewPIN utils.Secret `json:"newPin" validate:"required,len=6"`
 }
 
-func (s *KeycardService) Unblock(args *UnblockRequest, reply *struct{}) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) Unblock(r *http.Request, args *UnblockRequest, reply *struct{}) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err := validateRequest(args)
+	err = validateRequest(args)
 	if err != nil {
 		return err
 	}
 
-	err = s.keycardContext.UnblockPIN(args.PUK, args.NewPIN)
+	err = kc.UnblockPIN(r.Context(), args.PUK, args.NewPIN)
 	return err
 }
 
@@ -181,12 +432,13 @@ type GenerateMnemonicResponse struct {
 	Indexes []int `json:"indexes"`
 }
 
-func (s *KeycardService) GenerateMnemonic(args *GenerateMnemonicRequest, reply *GenerateMnemonicResponse) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) GenerateMnemonic(r *http.Request, args *GenerateMnemonicRequest, reply *GenerateMnemonicResponse) error {
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	indexes, err := s.keycardContext.GenerateMnemonic(args.Length)
+	indexes, err := kc.GenerateMnemonic(r.Context(), args.Length)
 	if err != nil {
 		return err
 	}
@@ -195,35 +447,69 @@ func (s *KeycardService) GenerateMnemonic(args *GenerateMnemonicRequest, reply *
 }
 
 type LoadMnemonicRequest struct {
-	Mnemonic   string `json:"mnemonic" validate:"required,mnemonic"`
-	Passphrase string `json:"passphrase"`
+	Mnemonic   utils.Secret `json:"mnemonic" validate:"required,mnemonic"`
+	Passphrase utils.Secret `json:"passphrase"`
+
+	// PIN is verified first, when required by the re-authentication policy
+	PIN utils.Secret `json:"pin,omitempty" validate:"omitempty,len=6"`
 }
 
 type LoadMnemonicResponse struct {
 	KeyUID string `json:"keyUID"` // WARNING: Is this what's returned?
 }
 
-func (s *KeycardService) LoadMnemonic(args *LoadMnemonicRequest, reply *LoadMnemonicResponse) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) LoadMnemonic(r *http.Request, args *LoadMnemonicRequest, reply *LoadMnemonicResponse) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err := validateRequest(args)
+	err = validateRequest(args)
+	if err != nil {
+		return err
+	}
+
+	err = s.reauthorize(r.Context(), kc, "LoadMnemonic", args.PIN)
 	if err != nil {
 		return err
 	}
 
-	keyUID, err := s.keycardContext.LoadMnemonic(args.Mnemonic, args.Passphrase)
+	keyUID, err := kc.LoadMnemonic(r.Context(), args.Mnemonic, args.Passphrase)
 	reply.KeyUID = utils.Btox(keyUID)
 	return err
 }
 
-func (s *KeycardService) FactoryReset(args *struct{}, reply *struct{}) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+type FactoryResetRequest struct {
+	// PIN is verified first, when required by the re-authentication policy
+	PIN utils.Secret `json:"pin,omitempty" validate:"omitempty,len=6"`
+}
+
+func (s *KeycardService) FactoryReset(r *http.Request, args *FactoryResetRequest, reply *struct{}) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
+	}
+
+	err = validateRequest(args)
+	if err != nil {
+		return err
 	}
 
-	err := s.keycardContext.FactoryReset()
+	err = s.reauthorize(r.Context(), kc, "FactoryReset", args.PIN)
+	if err != nil {
+		return err
+	}
+
+	err = s.confirm(r.Context(), kc, "FactoryReset", "Factory reset the keycard, removing its keys and pairings")
+	if err != nil {
+		return err
+	}
+
+	err = kc.FactoryReset(r.Context())
 	return err
 }
 
@@ -231,12 +517,12 @@ type GetMetadataResponse struct {
 	Metadata *internal.Metadata `json:"metadata"`
 }
 
-func (s *KeycardService) GetMetadata(args *struct{}, reply *GetMetadataResponse) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) GetMetadata(r *http.Request, args *struct{}, reply *GetMetadataResponse) error {
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
-	var err error
-	reply.Metadata, err = s.keycardContext.GetMetadata()
+	reply.Metadata, err = kc.GetMetadata(r.Context())
 	return err
 }
 
@@ -245,30 +531,53 @@ type StoreMetadataRequest struct {
 	Paths []string `json:"paths"`
 }
 
-func (s *KeycardService) StoreMetadata(args *StoreMetadataRequest, reply *struct{}) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) StoreMetadata(r *http.Request, args *StoreMetadataRequest, reply *struct{}) error {
+	kc, err := s.started()
+	if err != nil {
+		return err
 	}
 
-	err := validateRequest(args)
+	err = validateRequest(args)
 	if err != nil {
 		return err
 	}
 
-	return s.keycardContext.StoreMetadata(args.Name, args.Paths)
+	return kc.StoreMetadata(r.Context(), args.Name, args.Paths)
 }
 
 type ExportLoginKeysResponse struct {
 	Keys *internal.LoginKeys `json:"keys"`
 }
 
-func (s *KeycardService) ExportLoginKeys(args *struct{}, reply *ExportLoginKeysResponse) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+type ExportKeysRequest struct {
+	// PIN is verified first, when required by the re-authentication policy
+	PIN utils.Secret `json:"pin,omitempty" validate:"omitempty,len=6"`
+}
+
+func (s *KeycardService) ExportLoginKeys(r *http.Request, args *ExportKeysRequest, reply *ExportLoginKeysResponse) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
+	}
+
+	err = validateRequest(args)
+	if err != nil {
+		return err
+	}
+
+	err = s.reauthorize(r.Context(), kc, "ExportLoginKeys", args.PIN)
+	if err != nil {
+		return err
+	}
+
+	err = s.confirm(r.Context(), kc, "ExportLoginKeys", "Export the whisper and encryption private keys")
+	if err != nil {
+		return err
 	}
 
-	var err error
-	reply.Keys, err = s.keycardContext.ExportLoginKeys()
+	reply.Keys, err = kc.ExportLoginKeys(r.Context())
 	return err
 }
 
@@ -276,13 +585,30 @@ type ExportRecoveredKeysResponse struct {
 	Keys *internal.RecoverKeys `json:"keys"`
 }
 
-func (s *KeycardService) ExportRecoverKeys(args *struct{}, reply *ExportRecoveredKeysResponse) error {
-	if s.keycardContext == nil {
-		return errKeycardServiceNotStarted
+func (s *KeycardService) ExportRecoverKeys(r *http.Request, args *ExportKeysRequest, reply *ExportRecoveredKeysResponse) error {
+	defer utils.WipeSecrets(args)
+
+	kc, err := s.started()
+	if err != nil {
+		return err
+	}
+
+	err = validateRequest(args)
+	if err != nil {
+		return err
+	}
+
+	err = s.reauthorize(r.Context(), kc, "ExportRecoverKeys", args.PIN)
+	if err != nil {
+		return err
 	}
 
-	var err error
-	reply.Keys, err = s.keycardContext.ExportRecoverKeys()
+	err = s.confirm(r.Context(), kc, "ExportRecoverKeys", "Export the whisper and encryption private keys, and the wallet public keys")
+	if err != nil {
+		return err
+	}
+
+	reply.Keys, err = kc.ExportRecoverKeys(r.Context())
 	return err
 }
 
@@ -290,7 +616,7 @@ type SimulateErrorRequest struct {
 	Error string `json:"error"`
 }
 
-func (s *KeycardService) SimulateError(args *SimulateErrorRequest, reply *struct{}) error {
+func (s *KeycardService) SimulateError(r *http.Request, args *SimulateErrorRequest, reply *struct{}) error {
 	err := validateRequest(args)
 	if err != nil {
 		return err
@@ -298,9 +624,12 @@ func (s *KeycardService) SimulateError(args *SimulateErrorRequest, reply *struct
 
 	errToSimulate := internal.GetSimulatedError(args.Error)
 	if args.Error != "" && errToSimulate == nil {
-		return errors.New("unknown error to simulate")
+		return internal.NewError(internal.ErrorCodeValidation, "unknown error to simulate")
 	}
 
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.simulateError = errToSimulate
 
 	if s.keycardContext == nil {
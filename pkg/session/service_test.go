@@ -0,0 +1,235 @@
+package session
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/identifiers"
+
+	"github.com/status-im/status-keycard-go/internal"
+	"github.com/status-im/status-keycard-go/pkg/audit"
+	"github.com/status-im/status-keycard-go/pkg/trace"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+	"github.com/status-im/status-keycard-go/signal"
+)
+
+// newTestService returns a service publishing to its own bus, not started
+func newTestService(t *testing.T, options ...Option) (*KeycardService, *signal.Bus) {
+	t.Helper()
+
+	bus := signal.NewBus()
+	return NewKeycardService(append([]Option{WithSignalBus(bus)}, options...)...), bus
+}
+
+func hasErrorCode(err error, code ErrorCode) bool {
+	return err != nil && internal.AsError(err).Code == code
+}
+
+// startTestService starts the service, which is left in the `no-pcsc` state when PC/SC is not available
+func startTestService(t *testing.T, s *KeycardService, args *StartRequest) {
+	t.Helper()
+
+	if args.StorageFilePath == "" {
+		args.StorageFilePath = filepath.Join(t.TempDir(), "pairings.json")
+	}
+	err := s.Start(nil, args, &struct{}{})
+	if err != nil && err.Error() != internal.ErrorPCSC {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Stop(nil, &struct{}{}, &struct{}{}) })
+}
+
+// TestConcurrentLifecycle runs Start, Stop and other calls concurrently.
+// It's meant to be run with -race.
+func TestConcurrentLifecycle(t *testing.T) {
+	s, _ := newTestService(t)
+	storageFilePath := filepath.Join(t.TempDir(), "pairings.json")
+
+	const iterations = 50
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				f(i)
+			}
+		}()
+	}
+
+	run(func(int) {
+		// Without PC/SC the service is started in the `no-pcsc` state
+		err := s.Start(nil, &StartRequest{StorageFilePath: storageFilePath}, &struct{}{})
+		if err != nil && err.Error() != internal.ErrorPCSC && !hasErrorCode(err, ErrorCodeAlreadyStarted) {
+			t.Error(err)
+		}
+	})
+	run(func(int) {
+		err := s.Stop(nil, &struct{}{}, &struct{}{})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(i int) {
+		args := &SimulateErrorRequest{}
+		if i%2 == 0 {
+			args.Error = "simulated-select-applet-error"
+		}
+		err := s.SimulateError(nil, args, &struct{}{})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) {
+		err := s.GetStatus(nil, &struct{}{}, &internal.Status{})
+		if err != nil && !hasErrorCode(err, ErrorCodeNotStarted) {
+			t.Error(err)
+		}
+	})
+	run(func(int) { _ = s.Ready() })
+
+	wg.Wait()
+
+	err := s.Stop(nil, &struct{}{}, &struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.GetStatus(nil, &struct{}{}, &internal.Status{})
+	if !hasErrorCode(err, ErrorCodeNotStarted) {
+		t.Fatalf("expected not-started error, got %v", err)
+	}
+}
+
+func TestStartRequiresAuditKey(t *testing.T) {
+	s, _ := newTestService(t)
+
+	err := s.Start(nil, &StartRequest{
+		StorageFilePath: filepath.Join(t.TempDir(), "pairings.json"),
+		AuditFilePath:   filepath.Join(t.TempDir(), "audit.jsonl"),
+		AuditKey:        make([]byte, audit.KeySize-1),
+	}, &struct{}{})
+	if !hasErrorCode(err, ErrorCodeValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestStartWipesAuditKey(t *testing.T) {
+	s, _ := newTestService(t)
+
+	request := &StartRequest{
+		StorageFilePath: filepath.Join(t.TempDir(), "pairings.json"),
+		AuditFilePath:   filepath.Join(t.TempDir(), "audit.jsonl"),
+		AuditKey:        bytes.Repeat([]byte{0xa5}, audit.KeySize),
+	}
+	_ = s.Start(nil, request, &struct{}{})
+	defer s.Stop(nil, nil, nil)
+
+	if !bytes.Equal(request.AuditKey, make([]byte, audit.KeySize)) {
+		t.Fatalf("expected the audit key to be wiped, got %x", []byte(request.AuditKey))
+	}
+}
+
+func TestStartWithReplayedTrace(t *testing.T) {
+	aid, err := identifiers.KeycardInstanceAID(identifiers.KeycardDefaultInstanceIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selectCommand := globalplatform.NewCommandSelect(aid)
+	selectCommand.SetLe(0)
+	command, err := selectCommand.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A keycard which isn't initialized answers the selection with its secure channel public key
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := ethcrypto.FromECDSAPub(&key.PublicKey)
+	response := append(append([]byte{0x80, byte(len(publicKey))}, publicKey...), 0x90, 0x00)
+	replayer := trace.NewReplayer([]trace.Entry{{Reader: "reader", Command: command, Response: response}}, false)
+
+	s, _ := newTestService(t, WithTransport(replayer))
+	startTestService(t, s, &StartRequest{})
+
+	waitForState(t, s, internal.EmptyKeycard)
+
+	if divergences := replayer.Divergences(); len(divergences) > 0 {
+		t.Fatal(divergences[0])
+	}
+	if replayer.Remaining() != 0 {
+		t.Fatalf("expected the whole trace to be replayed, %d exchanges left", replayer.Remaining())
+	}
+}
+
+func TestStatusChangedEvents(t *testing.T) {
+	s := startWithFakeCard(t, map[byte][]byte{})
+
+	states := make(chan State, 10)
+	subscription := signal.On(s.Bus(), func(event StatusChangedEvent) {
+		states <- event.Status.State
+	})
+	defer subscription.Close()
+
+	err := s.Authorize(httptest.NewRequest(http.MethodPost, "/rpc", nil), &AuthorizeRequest{PIN: utils.Secret("123456")}, &AuthorizeResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The current status is delivered first, then the changes
+	for _, expected := range []State{Ready, Authorized} {
+		select {
+		case state := <-states:
+			if state != expected {
+				t.Fatalf("expected %s, got %s", expected, state)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not delivered", expected)
+		}
+	}
+}
+
+func TestServicesAreIndependent(t *testing.T) {
+	first := startWithFakeCard(t, map[byte][]byte{})
+	second := startWithFakeCard(t, map[byte][]byte{})
+
+	var secondStates []State
+	var lock sync.Mutex
+	subscription := signal.On(second.Bus(), func(event StatusChangedEvent) {
+		lock.Lock()
+		secondStates = append(secondStates, event.Status.State)
+		lock.Unlock()
+	})
+	defer subscription.Close()
+
+	err := first.Authorize(httptest.NewRequest(http.MethodPost, "/rpc", nil), &AuthorizeRequest{PIN: utils.Secret("123456")}, &AuthorizeResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, first, Authorized)
+
+	// Stopping the first service leaves the second one running
+	if err = first.Stop(nil, &struct{}{}, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = first.GetStatus(nil, &struct{}{}, &Status{}); !hasErrorCode(err, ErrorCodeNotStarted) {
+		t.Fatalf("expected the first service to be stopped, got %v", err)
+	}
+	waitForState(t, second, Ready)
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, state := range secondStates {
+		if state != Ready {
+			t.Fatalf("the second service received the status %s of the first one", state)
+		}
+	}
+}
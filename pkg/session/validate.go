@@ -1,14 +1,28 @@
 package session
 
 import (
+	"bytes"
 	goerrors "errors"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/tyler-smith/go-bip39"
+
+	"github.com/status-im/status-keycard-go/internal"
 )
 
 var (
 	validate = validator.New()
+
+	// mnemonicWords is the BIP39 word list, for lookups by []byte without string copies
+	mnemonicWords = func() map[string]struct{} {
+		words := make(map[string]struct{}, len(bip39.GetWordList()))
+		for _, word := range bip39.GetWordList() {
+			words[word] = struct{}{}
+		}
+		return words
+	}()
 )
 
 func init() {
@@ -17,19 +31,60 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	// Report fields by their JSON names, as clients know them
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 }
 
 func validateRequest(v interface{}) error {
 	err := validate.Struct(v)
-	if err != nil {
-		errs := err.(validator.ValidationErrors)
-		return goerrors.Join(errs)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !goerrors.As(err, &errs) {
+		return internal.NewError(internal.ErrorCodeValidation, err.Error())
+	}
+
+	// Field errors are listed in the error data, the values are never included
+	result := internal.NewError(internal.ErrorCodeValidation, "invalid request")
+	for _, fieldErr := range errs {
+		result.Data.Fields = append(result.Data.Fields, internal.FieldError{
+			Field: fieldErr.Field(),
+			Rule:  fieldErr.Tag(),
+		})
 	}
-	return nil
+	return result
 }
 
-// Custom validation function to check if a string is a list of space-separated words
+// Custom validation function to check if a string is a list of space-separated words.
+// Secrets are checked in place, so that no copy of the mnemonic is left in memory.
 func isMnemonic(fl validator.FieldLevel) bool {
-	mnemonic := fl.Field().String()
-	return bip39.IsMnemonicValid(mnemonic)
+	if fl.Field().Kind() != reflect.Slice {
+		return bip39.IsMnemonicValid(fl.Field().String())
+	}
+	return isMnemonicBytes(fl.Field().Bytes())
+}
+
+// isMnemonicBytes checks the same as bip39.IsMnemonicValid: 12, 15, 18, 21 or 24 words of the word list
+func isMnemonicBytes(mnemonic []byte) bool {
+	words := bytes.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return false
+	}
+
+	for _, word := range words {
+		// The conversion in a map index expression doesn't copy the word
+		if _, ok := mnemonicWords[string(word)]; !ok {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tyler-smith/go-bip39"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+func TestMnemonicValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mnemonic string
+	}{
+		{name: "12 words", mnemonic: testMnemonic},
+		{name: "extra spaces", mnemonic: "  " + strings.ReplaceAll(testMnemonic, " ", "  \t") + "\n"},
+		{name: "24 words", mnemonic: testMnemonic + " " + testMnemonic},
+		{name: "11 words", mnemonic: testMnemonic[:strings.LastIndexByte(testMnemonic, ' ')]},
+		{name: "13 words", mnemonic: testMnemonic + " abandon"},
+		{name: "27 words", mnemonic: testMnemonic + " " + testMnemonic + " abandon ability able"},
+		{name: "unknown word", mnemonic: strings.Replace(testMnemonic, "abandon", "abandonment", 1)},
+		{name: "upper case", mnemonic: strings.ToUpper(testMnemonic)},
+		{name: "empty"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected := bip39.IsMnemonicValid(tc.mnemonic)
+			if valid := isMnemonicBytes([]byte(tc.mnemonic)); valid != expected {
+				t.Fatalf("expected %v, got %v", expected, valid)
+			}
+
+			err := validateRequest(&LoadMnemonicRequest{Mnemonic: utils.Secret(tc.mnemonic)})
+			if (err == nil) != expected {
+				t.Fatalf("expected valid %v, got %v", expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package trace
+
+import (
+	"time"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// Entry is a single APDU exchange with a card.
+type Entry struct {
+	// Timestamp is the moment the command was sent to the reader.
+	Timestamp time.Time `json:"timestamp"`
+	// Duration is the time it took the reader to return the response.
+	Duration time.Duration `json:"duration"`
+	// Reader is the name of the reader the card is inserted into.
+	Reader   string          `json:"reader"`
+	Command  utils.HexString `json:"command"`
+	Response utils.HexString `json:"response,omitempty"`
+	// Error is the transport error, if the exchange failed.
+	Error string `json:"error,omitempty"`
+	// Decrypted is the plain secure channel traffic, only recorded when enabled in the Recorder.
+	Decrypted *Decrypted `json:"decrypted,omitempty"`
+}
@@ -0,0 +1,181 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LinkTypeISO7816 is LINKTYPE_ISO_7816, the link type of the exported frames.
+// Each frame is a bare ISO 7816 APDU, which Wireshark dissects with its `iso7816` dissector.
+const LinkTypeISO7816 = 264
+
+const (
+	blockTypeSectionHeader     = 0x0A0D0D0A
+	blockTypeInterface         = 0x00000001
+	blockTypeEnhancedPacket    = 0x00000006
+	byteOrderMagic             = 0x1A2B3C4D
+	optionEndOfOpt             = 0
+	optionComment              = 1
+	optionShbUserApplication   = 4
+	optionIfName               = 2
+	optionIfTsResolution       = 9
+	optionEpbFlags             = 2
+	epbFlagsDirectionInbound   = 0x1
+	epbFlagsDirectionOutbound  = 0x2
+	timestampResolutionNanosec = 9
+)
+
+// WritePcapNG exports the entries as a pcapng capture. Every reader is a separate interface.
+// Commands are outbound frames and responses are inbound frames.
+// Decrypted secure channel traffic, if recorded, and transport errors are attached to the frames as packet comments.
+func WritePcapNG(w io.Writer, entries []Entry) error {
+	err := writeBlock(w, blockTypeSectionHeader, sectionHeaderBody(), []option{
+		stringOption(optionShbUserApplication, "status-keycard-go"),
+	})
+	if err != nil {
+		return err
+	}
+
+	interfaces := make(map[string]uint32)
+
+	for _, entry := range entries {
+		interfaceID, ok := interfaces[entry.Reader]
+		if !ok {
+			interfaceID = uint32(len(interfaces))
+			interfaces[entry.Reader] = interfaceID
+
+			err = writeBlock(w, blockTypeInterface, interfaceBody(), []option{
+				stringOption(optionIfName, entry.Reader),
+				{code: optionIfTsResolution, value: []byte{timestampResolutionNanosec}},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var commandComment, responseComment string
+		if entry.Decrypted != nil {
+			commandComment = annotation("decrypted command", entry.Decrypted.Command, entry.Decrypted.Redacted)
+			responseComment = annotation("decrypted response", entry.Decrypted.Response, entry.Decrypted.Redacted)
+		}
+
+		timestamp := uint64(entry.Timestamp.UnixNano())
+		err = writePacket(w, interfaceID, timestamp, epbFlagsDirectionOutbound, entry.Command, commandComment)
+		if err != nil {
+			return err
+		}
+
+		if entry.Error != "" {
+			responseComment = "error: " + entry.Error
+		}
+		if len(entry.Response) == 0 && responseComment == "" {
+			continue
+		}
+
+		timestamp += uint64(entry.Duration.Nanoseconds())
+		err = writePacket(w, interfaceID, timestamp, epbFlagsDirectionInbound, entry.Response, responseComment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type option struct {
+	code  uint16
+	value []byte
+}
+
+func stringOption(code uint16, value string) option {
+	return option{code: code, value: []byte(value)}
+}
+
+func uint32Option(code uint16, value uint32) option {
+	return option{code: code, value: binary.LittleEndian.AppendUint32(nil, value)}
+}
+
+func annotation(title string, data []byte, redacted bool) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	b.WriteString(": ")
+	for i, v := range data {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%02X", v)
+	}
+	if redacted {
+		b.WriteString(" (secrets redacted)")
+	}
+	return b.String()
+}
+
+func sectionHeaderBody() []byte {
+	body := binary.LittleEndian.AppendUint32(nil, byteOrderMagic)
+	body = binary.LittleEndian.AppendUint16(body, 1) // major version
+	body = binary.LittleEndian.AppendUint16(body, 0) // minor version
+	body = binary.LittleEndian.AppendUint64(body, 0xFFFFFFFFFFFFFFFF)
+	return body
+}
+
+func interfaceBody() []byte {
+	body := binary.LittleEndian.AppendUint16(nil, LinkTypeISO7816)
+	body = binary.LittleEndian.AppendUint16(body, 0) // reserved
+	body = binary.LittleEndian.AppendUint32(body, 0) // no snap length limit
+	return body
+}
+
+func writePacket(w io.Writer, interfaceID uint32, timestamp uint64, direction uint32, data []byte, comment string) error {
+	body := binary.LittleEndian.AppendUint32(nil, interfaceID)
+	body = binary.LittleEndian.AppendUint32(body, uint32(timestamp>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(timestamp))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)))
+	body = append(body, pad(data)...)
+
+	options := []option{uint32Option(optionEpbFlags, direction)}
+	if comment != "" {
+		options = append(options, stringOption(optionComment, comment))
+	}
+
+	return writeBlock(w, blockTypeEnhancedPacket, body, options)
+}
+
+func writeBlock(w io.Writer, blockType uint32, body []byte, options []option) error {
+	var buf bytes.Buffer
+	buf.Write(body)
+
+	if len(options) > 0 {
+		for _, o := range options {
+			buf.Write(binary.LittleEndian.AppendUint16(nil, o.code))
+			buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(o.value))))
+			buf.Write(pad(o.value))
+		}
+		buf.Write(binary.LittleEndian.AppendUint32(nil, optionEndOfOpt))
+	}
+
+	length := uint32(buf.Len() + 12)
+	block := binary.LittleEndian.AppendUint32(nil, blockType)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	block = append(block, buf.Bytes()...)
+	block = binary.LittleEndian.AppendUint32(block, length)
+
+	_, err := w.Write(block)
+	return err
+}
+
+// pad appends zeros to align the data to 32 bits
+func pad(data []byte) []byte {
+	if len(data)%4 == 0 {
+		return data
+	}
+	return append(append([]byte{}, data...), make([]byte, 4-len(data)%4)...)
+}
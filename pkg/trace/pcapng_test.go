@@ -0,0 +1,135 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+type testBlock struct {
+	blockType uint32
+	body      []byte
+}
+
+func readBlocks(t *testing.T, data []byte) []testBlock {
+	t.Helper()
+
+	var blocks []testBlock
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("truncated block header: %x", data)
+		}
+		length := binary.LittleEndian.Uint32(data[4:8])
+		if length < 12 || int(length) > len(data) || length%4 != 0 {
+			t.Fatalf("invalid block length %d", length)
+		}
+		if trailer := binary.LittleEndian.Uint32(data[length-4 : length]); trailer != length {
+			t.Fatalf("block length mismatch: %d != %d", trailer, length)
+		}
+		blocks = append(blocks, testBlock{
+			blockType: binary.LittleEndian.Uint32(data[0:4]),
+			body:      data[8 : length-4],
+		})
+		data = data[length:]
+	}
+	return blocks
+}
+
+func TestWritePcapNG(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	entries := []Entry{
+		{
+			Timestamp: timestamp,
+			Duration:  time.Millisecond,
+			Reader:    "reader",
+			Command:   []byte{0x00, 0xA4, 0x04, 0x00},
+			Response:  []byte{0x90, 0x00},
+		},
+		{
+			Timestamp: timestamp.Add(time.Second),
+			Reader:    "reader",
+			Command:   []byte{0x80, 0xF2, 0x00, 0x00, 0x00},
+			Error:     "card removed",
+		},
+		{
+			Timestamp: timestamp.Add(2 * time.Second),
+			Reader:    "reader",
+			Command:   []byte{0x80, 0x20, 0x00, 0x00, 0x00},
+			Response:  []byte{0x90, 0x00},
+			Decrypted: &Decrypted{
+				Command:  []byte{0x80, 0x20, 0x00, 0x00, 0x06},
+				Response: []byte{0x90, 0x00},
+				Redacted: true,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePcapNG(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := readBlocks(t, buf.Bytes())
+	expectedTypes := []uint32{
+		blockTypeSectionHeader,
+		blockTypeInterface,
+		blockTypeEnhancedPacket,
+		blockTypeEnhancedPacket,
+		blockTypeEnhancedPacket,
+		blockTypeEnhancedPacket,
+		blockTypeEnhancedPacket,
+		blockTypeEnhancedPacket,
+	}
+	if len(blocks) != len(expectedTypes) {
+		t.Fatalf("expected %d blocks, got %d", len(expectedTypes), len(blocks))
+	}
+	for i, block := range blocks {
+		if block.blockType != expectedTypes[i] {
+			t.Fatalf("block %d: expected type %#x, got %#x", i, expectedTypes[i], block.blockType)
+		}
+	}
+
+	if linkType := binary.LittleEndian.Uint16(blocks[1].body); linkType != 264 {
+		t.Fatalf("expected LINKTYPE_ISO_7816 (264), got %d", linkType)
+	}
+
+	packets := []struct {
+		data      []byte
+		direction uint32
+		comment   string
+	}{
+		{entries[0].Command, epbFlagsDirectionOutbound, ""},
+		{entries[0].Response, epbFlagsDirectionInbound, ""},
+		{entries[1].Command, epbFlagsDirectionOutbound, ""},
+		{nil, epbFlagsDirectionInbound, "error: card removed"},
+		{entries[2].Command, epbFlagsDirectionOutbound, "decrypted command: 80 20 00 00 06 (secrets redacted)"},
+		{entries[2].Response, epbFlagsDirectionInbound, "decrypted response: 90 00 (secrets redacted)"},
+	}
+	for i, expected := range packets {
+		body := blocks[2+i].body
+		capturedLength := binary.LittleEndian.Uint32(body[12:16])
+		data := body[20 : 20+capturedLength]
+		if !bytes.Equal(data, expected.data) {
+			t.Fatalf("packet %d: expected %x, got %x", i, expected.data, data)
+		}
+
+		options := body[20+len(pad(data)):]
+		if code := binary.LittleEndian.Uint16(options); code != optionEpbFlags {
+			t.Fatalf("packet %d: expected flags option, got %d", i, code)
+		}
+		if direction := binary.LittleEndian.Uint32(options[4:8]); direction != expected.direction {
+			t.Fatalf("packet %d: expected direction %d, got %d", i, expected.direction, direction)
+		}
+
+		options = options[8:]
+		var comment string
+		if binary.LittleEndian.Uint16(options) == optionComment {
+			length := binary.LittleEndian.Uint16(options[2:4])
+			comment = string(options[4 : 4+length])
+		}
+		if comment != expected.comment {
+			t.Fatalf("packet %d: expected comment %q, got %q", i, expected.comment, comment)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Recorder appends APDU exchanges to a trace file, one JSON-encoded Entry per line.
+// A nil Recorder is valid and records nothing.
+type Recorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	decrypt bool
+}
+
+// NewRecorder opens the trace file for appending.
+// When decrypt is set, the plain secure channel traffic is added to each entry, with secrets redacted.
+func NewRecorder(filePath string, decrypt bool) (*Recorder, error) {
+	err := os.MkdirAll(filepath.Dir(filePath), 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		file:    file,
+		encoder: json.NewEncoder(file),
+		decrypt: decrypt,
+	}, nil
+}
+
+// DecryptsSecureChannel returns true if the plain secure channel traffic should be recorded.
+func (r *Recorder) DecryptsSecureChannel() bool {
+	return r != nil && r.decrypt
+}
+
+// Record stores a single exchange.
+func (r *Recorder) Record(entry *Entry) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+
+	return r.encoder.Encode(entry)
+}
+
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	r.encoder = nil
+	return err
+}
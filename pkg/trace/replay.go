@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+var (
+	ErrTraceExhausted = errors.New("trace exhausted")
+)
+
+// DivergenceError describes the first command that differs from the recorded one.
+type DivergenceError struct {
+	// Index is the position of the exchange in the trace, starting from 0.
+	Index    int
+	Reader   string
+	Expected utils.HexString
+	Actual   utils.HexString
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("command stream diverged at exchange %d (reader '%s'): expected %s, got %s",
+		e.Index, e.Reader, e.Expected, e.Actual)
+}
+
+// ReplayedError is returned for exchanges that failed when they were recorded.
+type ReplayedError struct {
+	Message string
+}
+
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+// Load reads all entries from a trace file written by Recorder.
+func Load(filePath string) ([]Entry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry Entry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trace line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Replayer is a transport that serves recorded responses back in order.
+// It implements the keycard-go `io.Transmitter` interface, so it can be used in place of a card:
+//
+//	cmdSet := keycard.NewCommandSet(io.NewNormalChannel(replayer))
+type Replayer struct {
+	mutex       sync.Mutex
+	entries     []Entry
+	next        int
+	lenient     bool
+	divergences []*DivergenceError
+}
+
+// NewReplayer creates a replay transport for the given entries.
+// By default, the first divergent command is returned as an error from Transmit.
+// When lenient is set, divergences are collected (see Divergences) and the recorded response is served anyway.
+// This is useful to get past commands with random data. Secure channel traffic can't be replayed though:
+// the session keys differ from the recorded ones, so the recorded responses fail the MAC verification.
+func NewReplayer(entries []Entry, lenient bool) *Replayer {
+	return &Replayer{
+		entries: entries,
+		lenient: lenient,
+	}
+}
+
+// Transmit implements the Transmitter interface
+func (r *Replayer) Transmit(command []byte) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.next >= len(r.entries) {
+		return nil, ErrTraceExhausted
+	}
+
+	index := r.next
+	entry := r.entries[index]
+
+	if !bytes.Equal(entry.Command, command) {
+		divergence := &DivergenceError{
+			Index:    index,
+			Reader:   entry.Reader,
+			Expected: entry.Command,
+			Actual:   command,
+		}
+		r.divergences = append(r.divergences, divergence)
+		if !r.lenient {
+			return nil, divergence
+		}
+	}
+
+	r.next++
+
+	if entry.Error != "" {
+		return nil, &ReplayedError{Message: entry.Error}
+	}
+
+	return entry.Response, nil
+}
+
+// Divergences returns all commands that differed from the recorded ones so far.
+func (r *Replayer) Divergences() []*DivergenceError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]*DivergenceError(nil), r.divergences...)
+}
+
+// Remaining returns the number of recorded exchanges that were not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.entries) - r.next
+}
@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func recordTestTrace(t *testing.T) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "trace.jsonl")
+	recorder, err := NewRecorder(filePath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []*Entry{
+		{Timestamp: time.Now(), Reader: "reader", Command: []byte{0x00, 0xA4}, Response: []byte{0x90, 0x00}},
+		{Timestamp: time.Now(), Reader: "reader", Command: []byte{0x80, 0xF2}, Error: "card removed"},
+		{Timestamp: time.Now(), Reader: "reader", Command: []byte{0x80, 0x20}, Response: []byte{0x63, 0xC2}},
+	}
+	for _, entry := range entries {
+		if err = recorder.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestReplay(t *testing.T) {
+	entries, err := Load(recordTestTrace(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := NewReplayer(entries, false)
+
+	response, err := replayer.Transmit([]byte{0x00, 0xA4})
+	if err != nil || !bytes.Equal(response, []byte{0x90, 0x00}) {
+		t.Fatalf("expected the recorded response, got %x, %v", response, err)
+	}
+
+	var replayedErr *ReplayedError
+	_, err = replayer.Transmit([]byte{0x80, 0xF2})
+	if !errors.As(err, &replayedErr) || replayedErr.Message != "card removed" {
+		t.Fatalf("expected the recorded error, got %v", err)
+	}
+
+	var divergence *DivergenceError
+	_, err = replayer.Transmit([]byte{0x80, 0x21})
+	if !errors.As(err, &divergence) || divergence.Index != 2 {
+		t.Fatalf("expected a divergence at exchange 2, got %v", err)
+	}
+	if replayer.Remaining() != 1 {
+		t.Fatalf("expected the divergent exchange not to be consumed, %d remaining", replayer.Remaining())
+	}
+
+	response, err = replayer.Transmit([]byte{0x80, 0x20})
+	if err != nil || !bytes.Equal(response, []byte{0x63, 0xC2}) {
+		t.Fatalf("expected the recorded response, got %x, %v", response, err)
+	}
+
+	_, err = replayer.Transmit([]byte{0x80, 0x20})
+	if !errors.Is(err, ErrTraceExhausted) {
+		t.Fatalf("expected the trace to be exhausted, got %v", err)
+	}
+}
+
+func TestLenientReplay(t *testing.T) {
+	entries, err := Load(recordTestTrace(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := NewReplayer(entries, true)
+
+	response, err := replayer.Transmit([]byte{0x00, 0xA5})
+	if err != nil || !bytes.Equal(response, []byte{0x90, 0x00}) {
+		t.Fatalf("expected the recorded response, got %x, %v", response, err)
+	}
+
+	divergences := replayer.Divergences()
+	if len(divergences) != 1 || divergences[0].Index != 0 || !bytes.Equal(divergences[0].Actual, []byte{0x00, 0xA5}) {
+		t.Fatalf("expected a divergence at exchange 0, got %v", divergences)
+	}
+	if replayer.Remaining() != 2 {
+		t.Fatalf("expected 2 remaining exchanges, got %d", replayer.Remaining())
+	}
+}
@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+const macLength = 16
+
+// Decrypted is the plain secure channel traffic of an exchange.
+type Decrypted struct {
+	Command  utils.HexString `json:"command"`
+	Response utils.HexString `json:"response,omitempty"`
+	// Redacted is set when secrets were removed from the command or the response.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// SecureChannelDecoder follows the IV chain of a keycard secure channel and decrypts the exchanged APDUs.
+// The session encryption key must be provided for every exchange, as it changes each time the channel is opened.
+// A single decoder must be used per card connection.
+type SecureChannelDecoder struct {
+	open bool
+	iv   []byte
+}
+
+// Decode returns the decrypted exchange, or nil if the exchange is not encrypted or can't be decrypted.
+// PIN, PUK, pairing secret, seed, mnemonic and private keys are redacted from the result.
+func (d *SecureChannelDecoder) Decode(command []byte, response []byte, encKey []byte) *Decrypted {
+	if len(command) < 4 || len(response) < 2 {
+		return nil
+	}
+
+	cla, ins := command[0], command[1]
+	sw := uint16(response[len(response)-2])<<8 | uint16(response[len(response)-1])
+	responseData := response[:len(response)-2]
+
+	switch {
+	case cla == globalplatform.ClaISO7816 && ins == globalplatform.InsSelect:
+		// Selecting the applet always closes the secure channel
+		d.open = false
+		return nil
+	case cla == globalplatform.ClaGp && ins == keycard.InsOpenSecureChannel:
+		d.open = sw == apdu.SwOK && len(responseData) == 32+macLength
+		if d.open {
+			d.iv = append([]byte(nil), responseData[32:]...)
+		}
+		return nil
+	case cla != globalplatform.ClaGp || ins == keycard.InsPair || ins == keycard.InsInit:
+		return nil
+	}
+
+	if !d.open || len(encKey) == 0 {
+		return nil
+	}
+
+	commandData := commandData(command)
+	if len(commandData) < macLength {
+		d.open = false
+		return nil
+	}
+
+	commandMAC := commandData[:macLength]
+	plainCommandData, err := crypto.DecryptData(commandData[macLength:], encKey, d.iv)
+	if err != nil {
+		d.open = false
+		return nil
+	}
+
+	decrypted := &Decrypted{
+		Command: append(append([]byte{}, command[:4]...), byte(len(plainCommandData))),
+	}
+	decrypted.Command = append(decrypted.Command, plainCommandData...)
+
+	if sw != apdu.SwOK || len(responseData) < macLength {
+		// The card closes the secure channel on any error
+		d.open = false
+		decrypted.Response = append([]byte{}, response...)
+		redact(decrypted)
+		return decrypted
+	}
+
+	responseMAC := responseData[:macLength]
+	decrypted.Response, err = crypto.DecryptData(responseData[macLength:], encKey, commandMAC)
+	if err != nil {
+		d.open = false
+		decrypted.Response = nil
+	}
+	d.iv = append([]byte(nil), responseMAC...)
+
+	redact(decrypted)
+	return decrypted
+}
+
+func commandData(command []byte) []byte {
+	if len(command) < 5 {
+		return nil
+	}
+	lc := int(command[4])
+	if len(command) < 5+lc {
+		return nil
+	}
+	return command[5 : 5+lc]
+}
+
+// redact removes secrets from the plain command and response, keeping the header and status word.
+func redact(d *Decrypted) {
+	ins, p2 := d.Command[1], d.Command[3]
+
+	var redactCommand, redactResponse bool
+	switch ins {
+	case keycard.InsVerifyPIN, keycard.InsChangePIN, keycard.InsUnblockPIN, keycard.InsLoadKey:
+		redactCommand = true
+	case keycard.InsGenerateMnemonic:
+		redactResponse = true
+	case keycard.InsExportKey:
+		redactResponse = p2 == keycard.P2ExportKeyPrivateAndPublic
+	}
+
+	if redactCommand && len(d.Command) > 5 {
+		d.Command = d.Command[:5]
+		d.Redacted = true
+	}
+
+	if redactResponse && len(d.Response) > 2 {
+		d.Response = d.Response[len(d.Response)-2:]
+		d.Redacted = true
+	}
+}
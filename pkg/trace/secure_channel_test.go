@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/status-im/keycard-go"
+)
+
+func TestRedact(t *testing.T) {
+	cases := []struct {
+		name     string
+		command  []byte
+		response []byte
+		expected Decrypted
+	}{
+		{
+			name:     "get status",
+			command:  []byte{0x80, keycard.InsGetStatus, 0x00, 0x00, 0x00},
+			response: []byte{0xA3, 0x00, 0x90, 0x00},
+			expected: Decrypted{
+				Command:  []byte{0x80, keycard.InsGetStatus, 0x00, 0x00, 0x00},
+				Response: []byte{0xA3, 0x00, 0x90, 0x00},
+			},
+		},
+		{
+			name:     "verify pin",
+			command:  []byte{0x80, keycard.InsVerifyPIN, 0x00, 0x00, 0x06, '1', '2', '3', '4', '5', '6'},
+			response: []byte{0x90, 0x00},
+			expected: Decrypted{
+				Command:  []byte{0x80, keycard.InsVerifyPIN, 0x00, 0x00, 0x06},
+				Response: []byte{0x90, 0x00},
+				Redacted: true,
+			},
+		},
+		{
+			name:     "load key",
+			command:  []byte{0x80, keycard.InsLoadKey, 0x03, 0x00, 0x02, 0x01, 0x02},
+			response: []byte{0x90, 0x00},
+			expected: Decrypted{
+				Command:  []byte{0x80, keycard.InsLoadKey, 0x03, 0x00, 0x02},
+				Response: []byte{0x90, 0x00},
+				Redacted: true,
+			},
+		},
+		{
+			name:     "generate mnemonic",
+			command:  []byte{0x80, keycard.InsGenerateMnemonic, 0x04, 0x00, 0x00},
+			response: []byte{0x00, 0x01, 0x00, 0x02, 0x90, 0x00},
+			expected: Decrypted{
+				Command:  []byte{0x80, keycard.InsGenerateMnemonic, 0x04, 0x00, 0x00},
+				Response: []byte{0x90, 0x00},
+				Redacted: true,
+			},
+		},
+		{
+			name:     "export private key",
+			command:  []byte{0x80, keycard.InsExportKey, 0x00, keycard.P2ExportKeyPrivateAndPublic, 0x00},
+			response: []byte{0xA1, 0x00, 0x90, 0x00},
+			expected: Decrypted{
+				Command:  []byte{0x80, keycard.InsExportKey, 0x00, keycard.P2ExportKeyPrivateAndPublic, 0x00},
+				Response: []byte{0x90, 0x00},
+				Redacted: true,
+			},
+		},
+		{
+			name:     "export public key",
+			command:  []byte{0x80, keycard.InsExportKey, 0x00, keycard.P2ExportKeyPublicOnly, 0x00},
+			response: []byte{0xA1, 0x00, 0x90, 0x00},
+			expected: Decrypted{
+				Command:  []byte{0x80, keycard.InsExportKey, 0x00, keycard.P2ExportKeyPublicOnly, 0x00},
+				Response: []byte{0xA1, 0x00, 0x90, 0x00},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			decrypted := &Decrypted{Command: c.command, Response: c.response}
+			redact(decrypted)
+			if !bytes.Equal(decrypted.Command, c.expected.Command) || !bytes.Equal(decrypted.Response, c.expected.Response) ||
+				decrypted.Redacted != c.expected.Redacted {
+				t.Fatalf("expected %+v, got %+v", c.expected, *decrypted)
+			}
+		})
+	}
+}
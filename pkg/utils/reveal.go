@@ -0,0 +1,452 @@
+package utils
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxRevealDepth bounds the recursion of MarshalRevealingSecrets, e.g. on cyclic structures
+const maxRevealDepth = 1000
+
+var errRevealTooDeep = errors.New("json: value too deep or cyclic to reveal secrets")
+
+// JSONValuer is implemented by types encoded to JSON as another value, e.g. an event encoded as its payload.
+// MarshalRevealingSecrets encodes the returned value, revealing the secrets it contains,
+// where MarshalJSON would have redacted them.
+type JSONValuer interface {
+	JSONValue() interface{}
+}
+
+var (
+	jsonValuerType    = reflect.TypeOf((*JSONValuer)(nil)).Elem()
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// MarshalRevealingSecrets encodes v to JSON like json.Marshal, with the Secret and SecretHexString values
+// it contains in plain text. Use it only for payloads sent to the client: RPC requests and responses, and signals.
+//
+// Secrets are revealed in a copy of v built for this call only, so json.Marshal of v, e.g. by a logger
+// on another goroutine, keeps redacting them. Types with their own MarshalJSON redact the secrets
+// they contain, unless they implement JSONValuer.
+func MarshalRevealingSecrets(v interface{}) ([]byte, error) {
+	revealed, err := reveal(reflect.ValueOf(v), 0)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(revealed)
+}
+
+// revealedSecret encodes a Secret in plain text
+type revealedSecret []byte
+
+func (s revealedSecret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(s))
+}
+
+// revealedHex encodes a SecretHexString in plain hex
+type revealedHex []byte
+
+func (s revealedHex) MarshalJSON() ([]byte, error) {
+	return HexString(s).MarshalJSON()
+}
+
+// revealedObject encodes the fields of a struct, in order
+type revealedObject []revealedMember
+
+type revealedMember struct {
+	name  string
+	value interface{}
+}
+
+func (o revealedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(member.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// reveal returns a value which json.Marshal encodes like v, with secrets in plain text.
+// Values which can't hold secrets are returned as is.
+func reveal(v reflect.Value, depth int) (interface{}, error) {
+	if depth > maxRevealDepth {
+		return nil, errRevealTooDeep
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	t := v.Type()
+	switch {
+	case t == secretType:
+		return revealedSecret(v.Bytes()), nil
+	case t == secretHexType:
+		return revealedHex(v.Bytes()), nil
+	case !hasSecrets(t):
+		return passThrough(v)
+	case t.Implements(jsonValuerType):
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, nil
+		}
+		return reveal(reflect.ValueOf(v.Interface().(JSONValuer).JSONValue()), depth+1)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return reveal(v.Elem(), depth+1)
+	case reflect.Struct:
+		return revealStruct(v, depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		fallthrough
+	case reflect.Array:
+		elements := make([]interface{}, v.Len())
+		for i := range elements {
+			element, err := reveal(v.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return revealMap(v, depth)
+	}
+
+	return passThrough(v)
+}
+
+// passThrough returns v for json.Marshal to encode it.
+// Addressable values are passed by pointer, so that pointer receivers of MarshalJSON are used, as by json.Marshal.
+func passThrough(v reflect.Value) (interface{}, error) {
+	if !v.CanInterface() {
+		return nil, fmt.Errorf("json: can't reveal secrets in unexported field of type %s", v.Type())
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface(), nil
+	}
+	return v.Interface(), nil
+}
+
+func revealStruct(v reflect.Value, depth int) (interface{}, error) {
+	var object revealedObject
+
+fields:
+	for _, field := range jsonFields(v.Type()) {
+		fv := v
+		for i, index := range field.index {
+			if i > 0 && fv.Kind() == reflect.Pointer {
+				// Fields of nil embedded pointers are omitted, as by json.Marshal
+				if fv.IsNil() {
+					continue fields
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(index)
+		}
+
+		if field.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		value, err := reveal(fv, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if field.quoted && value != nil {
+			value, err = quote(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		object = append(object, revealedMember{name: field.name, value: value})
+	}
+
+	return object, nil
+}
+
+func revealMap(v reflect.Value, depth int) (interface{}, error) {
+	object := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, err := reveal(iter.Value(), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+	}
+	return object, nil
+}
+
+// mapKey encodes a map key as json.Marshal does
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("json: unsupported map key type %s", k.Type())
+}
+
+// quote encodes a value of a field with the `string` option
+func quote(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	quoted, err := json.Marshal(string(data))
+	return json.RawMessage(quoted), err
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// secretsInType caches hasSecrets for each type
+var secretsInType sync.Map
+
+// hasSecrets reports whether values of type t may hold secrets which json.Marshal would redact.
+// Interfaces may hold anything. Types with their own MarshalJSON or MarshalText can't be revealed,
+// unless they implement JSONValuer.
+func hasSecrets(t reflect.Type) bool {
+	if cached, ok := secretsInType.Load(t); ok {
+		return cached.(bool)
+	}
+
+	result := typeHasSecrets(t, map[reflect.Type]bool{})
+	secretsInType.Store(t, result)
+	return result
+}
+
+func typeHasSecrets(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	switch {
+	case t == secretType || t == secretHexType || t.Implements(jsonValuerType):
+		return true
+	case encodesItself(t), visiting[t]:
+		return false
+	}
+	visiting[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasSecrets(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if (!field.IsExported() && !field.Anonymous) || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if typeHasSecrets(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func encodesItself(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(marshalerType) || pt.Implements(marshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// jsonField is a struct field encoded by json.Marshal
+type jsonField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool
+}
+
+// fieldsOfType caches jsonFields for each type
+var fieldsOfType sync.Map
+
+// jsonFields lists the fields of a struct type encoded by json.Marshal, in order,
+// with the fields of embedded structs promoted as by encoding/json
+func jsonFields(t reflect.Type) []jsonField {
+	if cached, ok := fieldsOfType.Load(t); ok {
+		return cached.([]jsonField)
+	}
+
+	var fields []jsonField
+	visited := map[reflect.Type]bool{}
+	current := []jsonField{{index: nil}}
+	currentTypes := []reflect.Type{t}
+
+	for len(currentTypes) > 0 {
+		var next []jsonField
+		var nextTypes []reflect.Type
+
+		for i, st := range currentTypes {
+			if visited[st] {
+				continue
+			}
+			visited[st] = true
+
+			for j := 0; j < st.NumField(); j++ {
+				sf := st.Field(j)
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options, _ := strings.Cut(tag, ",")
+				index := append(append([]int(nil), current[i].index...), j)
+
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, jsonField{index: index})
+					nextTypes = append(nextTypes, ft)
+					continue
+				}
+
+				field := jsonField{name: name, index: index, tagged: name != ""}
+				if name == "" {
+					field.name = sf.Name
+				}
+				for _, option := range strings.Split(options, ",") {
+					switch option {
+					case "omitempty":
+						field.omitEmpty = true
+					case "string":
+						switch ft.Kind() {
+						case reflect.Bool, reflect.String,
+							reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+							reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+							reflect.Float32, reflect.Float64:
+							field.quoted = true
+						}
+					}
+				}
+				fields = append(fields, field)
+			}
+		}
+
+		current, currentTypes = next, nextTypes
+	}
+
+	fields = dominantFields(fields)
+	fieldsOfType.Store(t, fields)
+	return fields
+}
+
+// dominantFields keeps, for each name, the shallowest field, preferring tagged ones.
+// Names without a single such field are dropped, as by encoding/json.
+func dominantFields(fields []jsonField) []jsonField {
+	byName := map[string][]jsonField{}
+	for _, field := range fields {
+		byName[field.name] = append(byName[field.name], field)
+	}
+
+	var dominant []jsonField
+	for _, candidates := range byName {
+		depth := len(candidates[0].index)
+		for _, field := range candidates {
+			if len(field.index) < depth {
+				depth = len(field.index)
+			}
+		}
+
+		var shallowest, tagged []jsonField
+		for _, field := range candidates {
+			if len(field.index) == depth {
+				shallowest = append(shallowest, field)
+				if field.tagged {
+					tagged = append(tagged, field)
+				}
+			}
+		}
+
+		switch {
+		case len(shallowest) == 1:
+			dominant = append(dominant, shallowest[0])
+		case len(tagged) == 1:
+			dominant = append(dominant, tagged[0])
+		}
+	}
+
+	sort.Slice(dominant, func(i, j int) bool {
+		a, b := dominant[i].index, dominant[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return dominant
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+const Redacted = "[REDACTED]"
+
+// Secret holds sensitive text: PIN, PUK, pairing password, mnemonic or passphrase.
+// It is redacted when formatted with `fmt`, logged with zap or marshalled with json.Marshal.
+// Only MarshalRevealingSecrets, used for RPC and signal payloads, marshals it as a plain string.
+type Secret []byte
+
+// MarshalJSON redacts the secret, see MarshalRevealingSecrets
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if len(s) > 0 {
+		return json.Marshal(Redacted)
+	}
+	return json.Marshal(string(s))
+}
+
+// UnmarshalJSON deserializes Secret from a plain string.
+// Strings without escape sequences are copied straight into the Secret buffer,
+// so that no intermediate copy is left behind in memory.
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' && bytes.IndexByte(data, '\\') < 0 {
+		*s = append(Secret(nil), data[1:len(data)-1]...)
+		return nil
+	}
+
+	var x string
+	err := json.Unmarshal(data, &x)
+	if err != nil {
+		return err
+	}
+
+	*s = Secret(x)
+	return nil
+}
+
+// Value returns the secret buffer itself, which is wiped along with the Secret. Never log it.
+// keycard-go takes secrets as strings: convert it only in that call, the string copy can't be wiped.
+func (s Secret) Value() []byte {
+	return s
+}
+
+// Wipe overwrites the secret with zeros
+func (s Secret) Wipe() {
+	Wipe(s)
+}
+
+func (s Secret) String() string {
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return Redacted
+}
+
+// Format implements fmt.Formatter, so that no formatting verb reveals the secret
+func (s Secret) Format(f fmt.State, verb rune) {
+	_, _ = io.WriteString(f, Redacted)
+}
+
+// SecretHexString holds sensitive binary data, e.g. an exported private key.
+// It is marshalled to JSON as hex, like HexString, within MarshalRevealingSecrets, and redacted otherwise.
+type SecretHexString []byte
+
+// MarshalJSON redacts the secret, see MarshalRevealingSecrets
+func (s SecretHexString) MarshalJSON() ([]byte, error) {
+	if len(s) > 0 {
+		return json.Marshal(Redacted)
+	}
+	return HexString(s).MarshalJSON()
+}
+
+// UnmarshalJSON deserializes SecretHexString from hex, without intermediate copies
+func (s *SecretHexString) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return (*HexString)(s).UnmarshalJSON(data)
+	}
+
+	buf := make([]byte, hex.DecodedLen(len(data)-2))
+	_, err := hex.Decode(buf, data[1:len(data)-1])
+	if err != nil {
+		return err
+	}
+
+	*s = buf
+	return nil
+}
+
+// Wipe overwrites the secret with zeros
+func (s SecretHexString) Wipe() {
+	Wipe(s)
+}
+
+func (s SecretHexString) String() string {
+	return Redacted
+}
+
+func (s SecretHexString) GoString() string {
+	return Redacted
+}
+
+// Format implements fmt.Formatter, so that no formatting verb reveals the secret
+func (s SecretHexString) Format(f fmt.State, verb rune) {
+	_, _ = io.WriteString(f, Redacted)
+}
+
+// Wipe overwrites the given buffer with zeros
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+var (
+	secretType    = reflect.TypeOf(Secret(nil))
+	secretHexType = reflect.TypeOf(SecretHexString(nil))
+)
+
+// WipeSecrets walks the given value and wipes every Secret and SecretHexString it finds,
+// following pointers, struct fields, slices, arrays and maps.
+func WipeSecrets(v interface{}) {
+	walkSecrets(reflect.ValueOf(v), 0, Wipe)
+}
+
+// ContainsSecrets reports whether the given value holds a non-empty Secret or SecretHexString,
+// following pointers, struct fields, slices, arrays and maps
+func ContainsSecrets(v interface{}) bool {
+	found := false
+	walkSecrets(reflect.ValueOf(v), 0, func(b []byte) {
+		found = true
+	})
+	return found
+}
+
+// walkSecrets calls fn with the buffer of every non-empty Secret and SecretHexString found in v
+func walkSecrets(v reflect.Value, depth int, fn func(b []byte)) {
+	// Guard against cyclic structures
+	if depth > 16 || !v.IsValid() {
+		return
+	}
+
+	if v.Type() == secretType || v.Type() == secretHexType {
+		if v.Len() > 0 {
+			fn(v.Bytes())
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			walkSecrets(v.Elem(), depth+1, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			walkSecrets(v.Field(i), depth+1, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			walkSecrets(v.Index(i), depth+1, fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkSecrets(iter.Value(), depth+1, fn)
+		}
+	}
+}
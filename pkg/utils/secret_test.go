@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type secretsHolder struct {
+	PIN    Secret                 `json:"pin"`
+	Key    SecretHexString        `json:"key"`
+	Nested map[string]interface{} `json:"nested"`
+	Empty  Secret                 `json:"empty"`
+}
+
+func newSecretsHolder() *secretsHolder {
+	return &secretsHolder{
+		PIN:    Secret("123456"),
+		Key:    SecretHexString{0xca, 0xfe},
+		Nested: map[string]interface{}{"mnemonic": Secret("abandon ability")},
+	}
+}
+
+func TestSecretMarshalJSONRedacts(t *testing.T) {
+	data, err := json.Marshal(newSecretsHolder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"pin":"[REDACTED]","key":"[REDACTED]","nested":{"mnemonic":"[REDACTED]"},"empty":""}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestMarshalRevealingSecrets(t *testing.T) {
+	holder := newSecretsHolder()
+
+	data, err := MarshalRevealingSecrets(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"pin":"123456","key":"cafe","nested":{"mnemonic":"abandon ability"},"empty":""}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+}
+
+// TestMarshalConcurrentlyWithReveal checks that json.Marshal of secrets being revealed
+// on another goroutine, e.g. by a logger, still redacts them
+func TestMarshalConcurrentlyWithReveal(t *testing.T) {
+	holder := newSecretsHolder()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				data, err := MarshalRevealingSecrets(holder)
+				if err != nil || !strings.Contains(string(data), "123456") {
+					t.Errorf("secret not revealed: %s %v", data, err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				data, err := json.Marshal(holder)
+				if err != nil || strings.Contains(string(data), "123456") {
+					t.Errorf("secret revealed by json.Marshal: %s %v", data, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type embeddedKeys struct {
+	Public  HexString       `json:"public"`
+	Private SecretHexString `json:"private,omitempty"`
+}
+
+type event struct {
+	payload interface{}
+}
+
+func (e event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.payload)
+}
+
+func (e event) JSONValue() interface{} {
+	return e.payload
+}
+
+type opaque struct {
+	PIN Secret `json:"pin"`
+}
+
+func (o opaque) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		PIN Secret `json:"pin"`
+	}{o.PIN})
+}
+
+// TestMarshalRevealingSecretsLikeJSON checks that values are encoded as by json.Marshal, secrets apart
+func TestMarshalRevealingSecretsLikeJSON(t *testing.T) {
+	type recovered struct {
+		embeddedKeys
+		Master   *embeddedKeys          `json:"master"`
+		Private  SecretHexString        `json:"private"`
+		Count    int                    `json:"count,string"`
+		Skipped  Secret                 `json:"-"`
+		Omitted  *embeddedKeys          `json:"omitted,omitempty"`
+		ByIndex  map[int]Secret         `json:"byIndex"`
+		Nil      []Secret               `json:"nil"`
+		Any      interface{}            `json:"any"`
+		Extra    map[string]interface{} `json:"extra,omitempty"`
+		internal Secret
+	}
+
+	value := &recovered{
+		embeddedKeys: embeddedKeys{Public: HexString{0x04}, Private: SecretHexString{0x01}},
+		Master:       &embeddedKeys{Public: HexString{0x05}, Private: SecretHexString{0x02}},
+		Private:      SecretHexString{0x03},
+		Count:        3,
+		Skipped:      Secret("skipped"),
+		ByIndex:      map[int]Secret{2: Secret("two"), 1: Secret("one")},
+		Any:          []interface{}{event{payload: Secret("event")}, opaque{PIN: Secret("opaque")}},
+		internal:     Secret("internal"),
+	}
+
+	testCases := []struct {
+		name     string
+		marshal  func(v interface{}) ([]byte, error)
+		expected string
+	}{
+		{
+			name:     "revealing",
+			marshal:  MarshalRevealingSecrets,
+			expected: `{"public":"04","master":{"public":"05","private":"02"},"private":"03","count":"3","byIndex":{"1":"one","2":"two"},"nil":null,"any":["event",{"pin":"[REDACTED]"}]}`,
+		},
+		{
+			name:     "json",
+			marshal:  json.Marshal,
+			expected: `{"public":"04","master":{"public":"05","private":"[REDACTED]"},"private":"[REDACTED]","count":"3","byIndex":{"1":"[REDACTED]","2":"[REDACTED]"},"nil":null,"any":["[REDACTED]",{"pin":"[REDACTED]"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, data)
+			}
+		})
+	}
+}
+
+func TestMarshalRevealingSecretsWithoutSecrets(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"<text>",
+		map[string]int{"b": 2, "a": 1},
+		struct {
+			Hex  HexString `json:"hex"`
+			List []int     `json:"list,omitempty"`
+		}{Hex: HexString{0xca}},
+	}
+
+	for _, value := range values {
+		expected, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := MarshalRevealingSecrets(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(expected) {
+			t.Errorf("expected %s, got %s", expected, data)
+		}
+	}
+}
+
+func TestMarshalRevealingSecretsCycle(t *testing.T) {
+	type node struct {
+		Next interface{} `json:"next"`
+	}
+	n := &node{}
+	n.Next = n
+
+	_, err := MarshalRevealingSecrets(n)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSecretFormatting(t *testing.T) {
+	secret := Secret("123456")
+	key := SecretHexString{0xca, 0xfe}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d"} {
+		output := fmt.Sprintf(format, secret) + fmt.Sprintf(format, key) + fmt.Sprintf(format, newSecretsHolder())
+		for _, value := range []string{"123456", "313233343536", "cafe", "CAFE", "202 254", "abandon"} {
+			if strings.Contains(output, value) {
+				t.Errorf("%s reveals %s: %s", format, value, output)
+			}
+		}
+	}
+}
+
+func TestWipeSecrets(t *testing.T) {
+	holder := newSecretsHolder()
+	mnemonic := holder.Nested["mnemonic"].(Secret)
+
+	WipeSecrets(holder)
+
+	for _, b := range [][]byte{holder.PIN, holder.Key, mnemonic} {
+		for _, c := range b {
+			if c != 0 {
+				t.Fatalf("secret not wiped: %v", b)
+			}
+		}
+	}
+}
+
+func TestSecretValueIsWiped(t *testing.T) {
+	secret := Secret("123456")
+	value := secret.Value()
+
+	secret.Wipe()
+
+	for _, b := range value {
+		if b != 0 {
+			t.Fatalf("expected the value to be wiped with the secret, got %v", value)
+		}
+	}
+}
@@ -1,20 +1,21 @@
 package main
 
+// #include <stdlib.h>
+// #include <string.h>
 import "C"
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"io"
-	"net/http/httptest"
+	"unsafe"
 
-	"github.com/gorilla/rpc"
 	"github.com/pkg/errors"
 
 	"github.com/status-im/status-keycard-go/pkg/session"
+	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
 var (
-	globalRPCServer *rpc.Server
+	globalRPCServer *session.RPCServer
 )
 
 func marshalError(err error) *C.char {
@@ -50,26 +51,40 @@ func KeycardCallRPC(payload *C.char) *C.char {
 		return marshalError(errors.New("RPC server not initialized"))
 	}
 
-	payloadBytes := []byte(C.GoString(payload))
+	payloadBytes := C.GoBytes(unsafe.Pointer(payload), C.int(C.strlen(payload)))
+	defer utils.Wipe(payloadBytes)
 
-	// Create a fake HTTP request
-	req := httptest.NewRequest("POST", "/rpc", bytes.NewBuffer(payloadBytes))
-	req.Header.Set("Content-Type", "application/json")
+	return callRPC(payloadBytes)
+}
 
-	// Create a fake HTTP response writer
-	rr := httptest.NewRecorder()
+// KeycardCallRPCAndWipe is the same as KeycardCallRPC, but overwrites the payload with zeros
+// once the call is finished. Use it for requests that carry secrets: PIN, PUK, mnemonic.
+// The caller still owns the payload memory.
+//
+//export KeycardCallRPCAndWipe
+func KeycardCallRPCAndWipe(payload *C.char) *C.char {
+	defer C.memset(unsafe.Pointer(payload), 0, C.strlen(payload))
 
-	// Call the server's ServeHTTP method
-	globalRPCServer.ServeHTTP(rr, req)
+	if globalRPCServer == nil {
+		return marshalError(errors.New("RPC server not initialized"))
+	}
 
-	// Read and return the response body
-	resp := rr.Result()
-	defer resp.Body.Close()
+	payloadBytes := C.GoBytes(unsafe.Pointer(payload), C.int(C.strlen(payload)))
+	defer utils.Wipe(payloadBytes)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return marshalError(errors.Wrap(err, "internal error reading response body"))
-	}
+	return callRPC(payloadBytes)
+}
+
+func callRPC(payloadBytes []byte) *C.char {
+	response := globalRPCServer.Call(context.Background(), payloadBytes)
+	// The response may contain exported private keys, wipe it once it's copied to C memory
+	defer utils.Wipe(response)
+
+	// Copy directly to C memory, avoiding a Go string copy that can't be wiped
+	cResponse := C.malloc(C.size_t(len(response) + 1))
+	cResponseBytes := unsafe.Slice((*byte)(cResponse), len(response)+1)
+	copy(cResponseBytes, response)
+	cResponseBytes[len(response)] = 0
 
-	return C.CString(string(body))
+	return (*C.char)(cResponse)
 }
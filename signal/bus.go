@@ -0,0 +1,167 @@
+package signal
+
+import (
+	"sync"
+
+	"github.com/status-im/status-keycard-go/pkg/metrics"
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+// Event is a typed signal payload, e.g. a status change or a flow result
+type Event interface {
+	// SignalType is the type of the signal carrying the event, e.g. `status-changed`
+	SignalType() string
+}
+
+// Bus delivers signals to any number of subscribers.
+// Each subscriber has its own bounded queue, so publishing never waits for subscribers.
+type Bus struct {
+	// lock serializes signals, so that subscribers receive them in sequence order
+	lock          sync.Mutex
+	lastSeq       uint64
+	lastStatus    *Envelope
+	subscriptions map[*Subscription]struct{}
+	metrics       *metrics.Metrics
+
+	// handlerLock protects the JSON handler subscription
+	handlerLock         sync.Mutex
+	handlerSubscription *Subscription
+}
+
+// Default is the bus used by the package-level functions and the C callback
+var Default = NewBus()
+
+type BusOption func(*Bus)
+
+// WithMetrics records the signals dropped for slow subscribers to the given metrics, metrics.Default by default
+func WithMetrics(m *metrics.Metrics) BusOption {
+	return func(b *Bus) {
+		b.metrics = m
+	}
+}
+
+func NewBus(options ...BusOption) *Bus {
+	b := &Bus{
+		subscriptions: map[*Subscription]struct{}{},
+		metrics:       metrics.Default,
+	}
+
+	for _, option := range options {
+		option(b)
+	}
+
+	return b
+}
+
+// Publish sends a typed event
+func (b *Bus) Publish(event Event) {
+	b.Send(event.SignalType(), event)
+}
+
+// Send sends an event of the given type. The event is encoded to JSON right away,
+// so later changes to it don't affect the signal. Signals carrying secrets, e.g. exported keys,
+// are never replayed, their JSON is wiped once every subscriber released it, see Envelope.Release.
+func (b *Bus) Send(typ string, event interface{}) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	envelope := NewEnvelope(typ, event)
+	envelope.Seq = b.lastSeq + 1
+	data, err := utils.MarshalRevealingSecrets(envelope)
+	if err != nil {
+		logger.Error("Marshalling signal envelope", "error", err)
+		return
+	}
+	envelope.data = data
+	envelope.secret = utils.ContainsSecrets(event)
+	b.lastSeq = envelope.Seq
+
+	if typ == StatusChanged && !envelope.secret {
+		b.lastStatus = envelope
+	}
+
+	// The bus holds the envelope until it's queued for every subscriber
+	envelope.Retain()
+	defer envelope.Release()
+
+	for s := range b.subscriptions {
+		s.push(envelope)
+	}
+}
+
+// Subscribe starts queueing signals, beginning with the last `status-changed` signal, if any.
+// Release each envelope read from Subscription.Events once done with it.
+func (b *Bus) Subscribe(queueSize int, policy OverflowPolicy) *Subscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	s := b.newSubscription(queueSize, 1, policy)
+	if b.lastStatus != nil {
+		s.push(b.lastStatus)
+	}
+	return s
+}
+
+// SubscribeFunc calls fn for each signal, beginning with the last `status-changed` signal, if any.
+// fn is called from a separate goroutine, so a slow fn never blocks Send. No signal is dropped:
+// signals are queued for as long as fn is busy, a warning is logged when the queue grows large.
+// Envelopes are released when fn returns, fn must retain the ones it keeps, see Envelope.Retain.
+// Close the returned subscription to unsubscribe, queued signals are then discarded.
+func (b *Bus) SubscribeFunc(fn func(*Envelope)) *Subscription {
+	b.lock.Lock()
+	s := b.newSubscription(0, 0, DropOnOverflow)
+	s.wake = make(chan struct{}, 1)
+	if b.lastStatus != nil {
+		s.push(b.lastStatus)
+	}
+	b.lock.Unlock()
+
+	go func() {
+		for range s.wake {
+			for _, envelope := range s.takePending() {
+				fn(envelope)
+				envelope.Release()
+			}
+		}
+	}()
+	return s
+}
+
+// On calls fn for each event of type T, see Bus.SubscribeFunc
+func On[T Event](b *Bus, fn func(T)) *Subscription {
+	return b.SubscribeFunc(func(envelope *Envelope) {
+		if event, ok := envelope.Event.(T); ok {
+			fn(event)
+		}
+	})
+}
+
+// SetKeycardSignalHandler replaces the JSON handler. It's a subscriber like any other,
+// see Bus.SubscribeFunc. A nil handler unsubscribes.
+func (b *Bus) SetKeycardSignalHandler(handler KeycardSignalHandler) {
+	b.handlerLock.Lock()
+	defer b.handlerLock.Unlock()
+
+	if b.handlerSubscription != nil {
+		b.handlerSubscription.Close()
+		b.handlerSubscription = nil
+	}
+
+	if handler != nil {
+		b.handlerSubscription = b.SubscribeFunc(func(envelope *Envelope) {
+			handler(envelope.JSON())
+		})
+	}
+}
+
+// newSubscription must be called with lock held.
+// The queue is extended by backlog, so that the backlog doesn't overflow it.
+func (b *Bus) newSubscription(queueSize int, backlog int, policy OverflowPolicy) *Subscription {
+	s := &Subscription{
+		bus:    b,
+		queue:  make(chan *Envelope, queueSize+backlog),
+		policy: policy,
+	}
+	b.subscriptions[s] = struct{}{}
+	return s
+}
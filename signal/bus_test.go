@@ -0,0 +1,284 @@
+package signal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
+)
+
+type keyEvent struct {
+	PrivateKey utils.SecretHexString `json:"privateKey"`
+}
+
+func (keyEvent) SignalType() string {
+	return "key"
+}
+
+func TestSignalsRevealSecrets(t *testing.T) {
+	bus := NewBus()
+	subscription := bus.Subscribe(1, DropOnOverflow)
+	defer subscription.Close()
+
+	bus.Publish(keyEvent{PrivateKey: utils.SecretHexString{0xca, 0xfe}})
+
+	envelope := <-subscription.Events()
+	if !strings.Contains(string(envelope.JSON()), `"privateKey":"cafe"`) {
+		t.Fatalf("private key not sent: %s", envelope.JSON())
+	}
+}
+
+type numberEvent struct {
+	N int `json:"n"`
+}
+
+func (numberEvent) SignalType() string {
+	return "number"
+}
+
+func TestStalledSubscriberDoesNotBlockOthers(t *testing.T) {
+	bus := NewBus()
+
+	// Never read
+	stalled := bus.Subscribe(1, DropOnOverflow)
+	defer stalled.Close()
+
+	// Blocked until released
+	release := make(chan struct{})
+	handled := make(chan int, 1000)
+	handler := bus.SubscribeFunc(func(envelope *Envelope) {
+		<-release
+		handled <- envelope.Event.(numberEvent).N
+	})
+	defer handler.Close()
+
+	active := bus.Subscribe(1000, DropOnOverflow)
+	defer active.Close()
+
+	const count = 600
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			bus.Publish(numberEvent{N: i})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked by a stalled subscriber")
+	}
+
+	for i := 0; i < count; i++ {
+		envelope := <-active.Events()
+		if envelope.Event.(numberEvent).N != i {
+			t.Fatalf("expected %d, got %+v", i, envelope.Event)
+		}
+	}
+
+	// The queue has room for one signal, and one for the replayed status
+	if stalled.Dropped() != count-2 {
+		t.Fatalf("expected %d dropped signals, got %d", count-2, stalled.Dropped())
+	}
+
+	// The handler gets every signal in order once it's unblocked, nothing is dropped
+	close(release)
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-handled:
+			if n != i {
+				t.Fatalf("expected %d, got %d", i, n)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("signal %d not handled", i)
+		}
+	}
+	if handler.Dropped() != 0 {
+		t.Fatalf("handler dropped %d signals", handler.Dropped())
+	}
+}
+
+func TestSubscribeFuncStopsOnClose(t *testing.T) {
+	bus := NewBus()
+
+	handled := make(chan int, 10)
+	handler := bus.SubscribeFunc(func(envelope *Envelope) {
+		handled <- envelope.Event.(numberEvent).N
+	})
+
+	bus.Publish(numberEvent{N: 1})
+	if n := <-handled; n != 1 {
+		t.Fatalf("expected 1, got %d", n)
+	}
+
+	handler.Close()
+	bus.Publish(numberEvent{N: 2})
+
+	select {
+	case n := <-handled:
+		t.Fatalf("signal %d handled after close", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOnDeliversTypedEvents(t *testing.T) {
+	bus := NewBus()
+
+	numbers := make(chan numberEvent, 10)
+	subscription := On(bus, func(event numberEvent) {
+		numbers <- event
+	})
+
+	// Events of other types, and untyped ones with the same signal type, are skipped
+	bus.Publish(keyEvent{PrivateKey: utils.SecretHexString{0xca, 0xfe}})
+	bus.Send("number", map[string]int{"n": 1})
+	bus.Publish(numberEvent{N: 2})
+	bus.Publish(numberEvent{N: 3})
+
+	for _, expected := range []int{2, 3} {
+		select {
+		case event := <-numbers:
+			if event.N != expected {
+				t.Fatalf("expected %d, got %d", expected, event.N)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not delivered", expected)
+		}
+	}
+
+	subscription.Close()
+	bus.Publish(numberEvent{N: 4})
+
+	select {
+	case event := <-numbers:
+		t.Fatalf("event %d delivered after close", event.N)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func isWiped(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSecretSignalsWipedOnceReleased(t *testing.T) {
+	bus := NewBus()
+	first := bus.Subscribe(1, DropOnOverflow)
+	defer first.Close()
+	second := bus.Subscribe(1, DropOnOverflow)
+	defer second.Close()
+
+	bus.Publish(keyEvent{PrivateKey: utils.SecretHexString{0xca, 0xfe}})
+
+	envelope := <-first.Events()
+	if !envelope.CarriesSecrets() {
+		t.Fatal("expected the signal to carry secrets")
+	}
+	data := envelope.JSON()
+	envelope.Release()
+	if isWiped(data) {
+		t.Fatal("signal wiped before every subscriber released it")
+	}
+
+	envelope = <-second.Events()
+	envelope.Release()
+	if !isWiped(data) {
+		t.Fatalf("signal not wiped once released: %s", data)
+	}
+	if envelope.Event != nil {
+		t.Fatal("event kept once released")
+	}
+}
+
+func TestSecretSignalsWipedOnClose(t *testing.T) {
+	bus := NewBus()
+	subscription := bus.Subscribe(1, DropOnOverflow)
+
+	handled := make(chan []byte, 1)
+	handler := bus.SubscribeFunc(func(envelope *Envelope) {
+		handled <- envelope.JSON()
+	})
+	defer handler.Close()
+
+	bus.Publish(keyEvent{PrivateKey: utils.SecretHexString{0xca, 0xfe}})
+
+	// Released when the callback returns, still queued for the subscription
+	data := <-handled
+	time.Sleep(10 * time.Millisecond)
+	if isWiped(data) {
+		t.Fatal("signal wiped while queued")
+	}
+
+	subscription.Close()
+	if !isWiped(data) {
+		t.Fatalf("signal not wiped once the subscription closed: %s", data)
+	}
+}
+
+func TestSignalsWithoutSecretsKept(t *testing.T) {
+	bus := NewBus()
+	subscription := bus.Subscribe(1, DropOnOverflow)
+	defer subscription.Close()
+
+	bus.Send(StatusChanged, numberEvent{N: 1})
+	envelope := <-subscription.Events()
+	envelope.Release()
+
+	if envelope.CarriesSecrets() || isWiped(envelope.JSON()) {
+		t.Fatalf("signal without secrets wiped: %s", envelope.JSON())
+	}
+
+	// The status is replayed to new subscribers
+	replayed := bus.Subscribe(1, DropOnOverflow)
+	defer replayed.Close()
+	if envelope := <-replayed.Events(); envelope.Seq != 1 {
+		t.Fatalf("expected the status to be replayed, got %d", envelope.Seq)
+	}
+}
+
+func TestLateSubscribersGetCurrentStatus(t *testing.T) {
+	bus := NewBus()
+	bus.Send(StatusChanged, numberEvent{N: 1})
+	bus.Send("number", numberEvent{N: 2})
+	bus.Send(StatusChanged, numberEvent{N: 3})
+	bus.Send("number", numberEvent{N: 4})
+
+	handled := make(chan []byte, 2)
+	bus.SetKeycardSignalHandler(func(data []byte) {
+		handled <- append([]byte(nil), data...)
+	})
+	defer bus.SetKeycardSignalHandler(nil)
+
+	subscription := bus.Subscribe(1, DropOnOverflow)
+	defer subscription.Close()
+
+	// Only the last status is replayed, with its original sequence number
+	status := <-subscription.Events()
+	if status.Type != StatusChanged || status.Seq != 3 || status.Event.(numberEvent).N != 3 {
+		t.Fatalf("expected the last status, got %+v", status)
+	}
+	status.Release()
+
+	select {
+	case data := <-handled:
+		if !strings.Contains(string(data), `"seq":3`) || !strings.Contains(string(data), `"n":3`) {
+			t.Fatalf("expected the last status, got %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("status not replayed to the handler")
+	}
+
+	// New signals continue the sequence, so subscribers can tell that signal 4 was missed
+	bus.Send("number", numberEvent{N: 5})
+	next := <-subscription.Events()
+	if next.Seq != 5 {
+		t.Fatalf("expected seq 5, got %d", next.Seq)
+	}
+	next.Release()
+}
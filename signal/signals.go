@@ -9,25 +9,41 @@ extern void KeycardSetEventCallback(void *cb);
 */
 import "C"
 import (
-	"encoding/json"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-keycard-go/pkg/utils"
 )
 
-// KeycardSignalHandler is a simple callback function that gets called when any signal is received
+// KeycardSignalHandler is a simple callback function that gets called when any signal is received.
+// Signals carrying secrets are wiped when it returns, copy the JSON to keep it.
 type KeycardSignalHandler func([]byte)
 
-// storing the current signal handler here
-var keycardSignalHandler KeycardSignalHandler
+// StatusChanged is the type of signals carrying the session status
+const StatusChanged = "status-changed"
+
+// handlerQueueWarning is the number of signals queued for a slow callback before a warning is logged
+const handlerQueueWarning = 256
 
 // All general log messages in this package should be routed through this logger.
 var logger = log.New("package", "keycard-go/signal")
 
-// Envelope is a general signal sent upward from node to RN app
+// Envelope is a general signal sent upward from node to RN app.
+// Seq increases by one with each signal, so that a gap means missed signals.
+// A replayed signal keeps its original Seq.
 type Envelope struct {
 	Type  string      `json:"type"`
+	Seq   uint64      `json:"seq"`
 	Event interface{} `json:"event"`
+
+	// data is the JSON encoding, set when the signal is sent
+	data []byte
+
+	// secret is set when data reveals secrets. data is then wiped once refs drops to zero.
+	secret bool
+	refs   atomic.Int32
 }
 
 // NewEnvelope creates new envlope of given type and event payload.
@@ -38,33 +54,85 @@ func NewEnvelope(typ string, event interface{}) *Envelope {
 	}
 }
 
-// send sends application signal (in JSON) upwards to application (via default notification handler)
-func Send(typ string, event interface{}) {
-	signal := NewEnvelope(typ, event)
-	data, err := json.Marshal(&signal)
-	if err != nil {
-		logger.Error("Marshalling signal envelope", "error", err)
-		return
+// JSON returns the envelope encoded when it was sent. It must not be modified,
+// nor used after the envelope is released: signals carrying secrets are wiped then.
+func (e *Envelope) JSON() []byte {
+	return e.data
+}
+
+// CarriesSecrets reports whether the JSON reveals secrets, e.g. exported keys.
+// Such signals must not be kept beyond their delivery.
+func (e *Envelope) CarriesSecrets() bool {
+	return e.secret
+}
+
+// Retain keeps the envelope from being wiped until Release is called,
+// e.g. by a SubscribeFunc callback queueing it for later
+func (e *Envelope) Retain() {
+	if e.secret {
+		e.refs.Add(1)
 	}
-	// If a Go implementation of signal handler is set, let's use it.
-	if keycardSignalHandler != nil {
-		keycardSignalHandler(data)
-	} else {
-		// ...and fallback to C implementation otherwise.
-		str := C.CString(string(data))
-		C.KeycardServiceSignalEvent(str)
-		C.free(unsafe.Pointer(str))
+}
+
+// Release tells that the subscriber is done with the envelope. The JSON of signals carrying
+// secrets is wiped once every subscriber they were queued for released them.
+// Subscribers which never release an envelope only keep it from being wiped.
+func (e *Envelope) Release() {
+	if e.secret && e.refs.Add(-1) == 0 {
+		utils.Wipe(e.data)
+		e.Event = nil
 	}
 }
 
+// send sends application signal (in JSON) upwards to application (via default notification handler).
+// Signals are queued for each subscriber, Send never waits for subscribers to process them.
+func Send(typ string, event interface{}) {
+	Default.Send(typ, event)
+}
+
+// Publish sends a typed event to the default bus
+func Publish(event Event) {
+	Default.Publish(event)
+}
+
+// Subscribe subscribes to the default bus, see Bus.Subscribe
+func Subscribe(queueSize int, policy OverflowPolicy) *Subscription {
+	return Default.Subscribe(queueSize, policy)
+}
+
+// SubscribeFunc subscribes to the default bus, see Bus.SubscribeFunc
+func SubscribeFunc(fn func(*Envelope)) *Subscription {
+	return Default.SubscribeFunc(fn)
+}
+
 // SetKeycardSignalHandler sets new handler for geth events
-// this function uses pure go implementation
+// this function uses pure go implementation.
+// The handler immediately receives the last `status-changed` signal, if any.
+// It replaces the C callback, only one of them receives signals.
 func SetKeycardSignalHandler(handler KeycardSignalHandler) {
-	keycardSignalHandler = handler
+	Default.SetKeycardSignalHandler(handler)
 }
 
 // KeycardSetSignalEventCallback set callback
-// this function uses C implementation (see `signals.c` file)
+// this function uses C implementation (see `signals.c` file).
+// The callback immediately receives the last `status-changed` signal, if any.
+// It replaces the Go handler, only one of them receives signals.
 func KeycardSetSignalEventCallback(cb unsafe.Pointer) {
 	C.KeycardSetEventCallback(cb)
+	if cb == nil {
+		Default.SetKeycardSignalHandler(nil)
+		return
+	}
+
+	Default.SetKeycardSignalHandler(func(data []byte) {
+		// The C string is wiped like the envelope, as signals may carry secrets
+		str := (*C.char)(C.malloc(C.size_t(len(data) + 1)))
+		buffer := unsafe.Slice((*byte)(unsafe.Pointer(str)), len(data)+1)
+		copy(buffer, data)
+		buffer[len(data)] = 0
+
+		C.KeycardServiceSignalEvent(str)
+		utils.Wipe(buffer)
+		C.free(unsafe.Pointer(str))
+	})
 }
@@ -0,0 +1,119 @@
+package signal
+
+import (
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what happens when a subscriber doesn't keep up with signals
+type OverflowPolicy int
+
+const (
+	// DropOnOverflow drops new signals while the queue is full.
+	// The subscriber notices missed signals by a gap in Envelope.Seq.
+	DropOnOverflow OverflowPolicy = iota
+
+	// CloseOnOverflow closes the subscription when the queue is full.
+	// Suits clients which can reconnect and resume, e.g. network connections.
+	CloseOnOverflow
+)
+
+// Subscription receives signals through a bounded queue, so that a slow
+// subscriber never blocks Send, nor delays other subscribers.
+type Subscription struct {
+	bus     *Bus
+	queue   chan *Envelope
+	policy  OverflowPolicy
+	closed  bool
+	dropped atomic.Uint64
+
+	// pending and wake replace the queue for SubscribeFunc, which never drops signals
+	pending []*Envelope
+	wake    chan struct{}
+}
+
+// Events returns the queue of signals. It's closed when the subscription is closed.
+func (s *Subscription) Events() <-chan *Envelope {
+	return s.queue
+}
+
+// Dropped returns the number of signals dropped with DropOnOverflow
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes. Signals still queued are released and discarded.
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	s.close()
+	s.bus.lock.Unlock()
+
+	for envelope := range s.queue {
+		envelope.Release()
+	}
+}
+
+// close must be called with the bus lock held.
+// Queued signals can still be read, e.g. after an overflow.
+func (s *Subscription) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.bus.subscriptions, s)
+	close(s.queue)
+	if s.wake != nil {
+		close(s.wake)
+		for _, envelope := range s.pending {
+			envelope.Release()
+		}
+		s.pending = nil
+	}
+}
+
+// takePending returns the signals queued for SubscribeFunc since the last call
+func (s *Subscription) takePending() []*Envelope {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	pending := s.pending
+	s.pending = nil
+	return pending
+}
+
+// push must be called with the bus lock held
+func (s *Subscription) push(envelope *Envelope) {
+	if s.closed {
+		return
+	}
+
+	if s.wake != nil {
+		envelope.Retain()
+		s.pending = append(s.pending, envelope)
+		if len(s.pending) == handlerQueueWarning {
+			logger.Warn("signal handler is too slow, signals are piling up", "queued", len(s.pending))
+		}
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	envelope.Retain()
+	select {
+	case s.queue <- envelope:
+		return
+	default:
+		envelope.Release()
+	}
+
+	switch s.policy {
+	case DropOnOverflow:
+		s.dropped.Add(1)
+		logger.Warn("signal subscriber is too slow, dropping signal")
+	case CloseOnOverflow:
+		logger.Warn("signal subscriber is too slow, closing subscription")
+		s.close()
+	}
+	s.bus.metrics.SignalsDropped.Inc()
+}